@@ -0,0 +1,67 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/conf"
+	"jutkey-server/packages/storage/sql"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+)
+
+var backfillOpts sql.BackfillOptions
+
+// backfillCmd catches utxo_history up with tx_data,the sync of the running servers pauses while it runs
+var backfillCmd = &cobra.Command{
+	Use:    "backfill",
+	Short:  "Backfill utxo_history from tx_data with parallel workers",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		if err := conf.InitDatabase(); err != nil {
+			log.WithError(err).Fatal("postgres sql database connect failed")
+		}
+		opts := sql.GetBackfillOptions()
+		flags := cmd.Flags()
+		if flags.Changed("workers") {
+			opts.Workers = backfillOpts.Workers
+		}
+		if flags.Changed("chunk-blocks") {
+			opts.ChunkBlocks = backfillOpts.ChunkBlocks
+		}
+		if flags.Changed("batch-rows") {
+			opts.BatchRows = backfillOpts.BatchRows
+		}
+		opts.DryRun = dryRun
+
+		ctx, cancel := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+		defer cancel()
+		p, err := sql.BackfillUtxoHistory(ctx, opts, func(p sql.BackfillProgress) {
+			fmt.Printf("block %d/%d %d rows %.0f blocks/s %.0f rows/s\n",
+				p.Block, p.ToBlock, p.Rows, p.BlocksPerSecond(), p.RowsPerSecond())
+		})
+		if err != nil {
+			log.WithError(err).Error("backfill")
+			os.Exit(1)
+		}
+		if p.FromBlock >= p.ToBlock {
+			fmt.Println("utxo_history is up to date")
+			return
+		}
+		if dryRun {
+			fmt.Print("dry run,nothing loaded. ")
+		}
+		fmt.Printf("backfilled blocks %d-%d,%d rows in %s\n", p.FromBlock, p.ToBlock, p.Rows, p.Elapsed.Round(time.Second))
+	},
+}
+
+func init() {
+	flags := backfillCmd.Flags()
+	flags.IntVar(&backfillOpts.Workers, "workers", 0, "decode workers,default backfill.workers or the cpu count")
+	flags.Int64Var(&backfillOpts.ChunkBlocks, "chunk-blocks", 0, "blocks decoded by a worker at a time,default backfill.chunk_blocks")
+	flags.IntVar(&backfillOpts.BatchRows, "batch-rows", 0, "rows loaded by a COPY,default backfill.batch_rows")
+	flags.BoolVar(&dryRun, "dry-run", false, "decode and compute the balances without loading the rows")
+}
@@ -0,0 +1,70 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/conf"
+	"jutkey-server/packages/storage/kv"
+	"jutkey-server/packages/storage/sql"
+)
+
+// cacheCmd manages the redis cache,the flushed keys are rebuilt by the crontab tasks of the server.
+// The memory cache lives in the server process and is dropped by a restart
+var cacheCmd = &cobra.Command{
+	Use:              "cache",
+	Short:            "Redis cache management",
+	PersistentPreRun: loadConfigWKey,
+}
+
+var cacheFlushCmd = &cobra.Command{
+	Use:       "flush [key...]",
+	Short:     "Delete the cached statistics and honor nodes,or the given keys",
+	ValidArgs: sql.CacheKeys,
+	Args:      cobra.OnlyValidArgs,
+	Run: func(cmd *cobra.Command, args []string) {
+		r := conf.GetRedisDbConn()
+		if r == nil || !r.Enable {
+			log.Fatal("redis is disabled")
+		}
+		if err := r.Initer(); err != nil {
+			log.WithError(err).WithField("address", r.Str()).Fatal("redis database connect failed")
+		}
+		if err := kv.InitStore(); err != nil {
+			log.WithError(err).Fatal("cache store init failed")
+		}
+		if _, ok := kv.Default.(*kv.RedisStore); !ok {
+			log.Fatal("the cache backend is not redis,restart the server to drop its cache")
+		}
+		defer kv.Default.Close()
+
+		keys := args
+		if len(keys) == 0 {
+			keys = sql.CacheKeys
+		}
+		ctx := context.Background()
+		for i, key := range keys {
+			f, err := kv.Default.Exists(ctx, key)
+			if err != nil {
+				log.WithError(err).WithField("key", key).Fatal("cache flush")
+			}
+			switch {
+			case !f:
+				fmt.Printf("(%d/%d) %s not cached\n", i+1, len(keys), key)
+			case dryRun:
+				fmt.Printf("(%d/%d) %s would be deleted\n", i+1, len(keys), key)
+			default:
+				if err = kv.Default.Del(ctx, key); err != nil {
+					log.WithError(err).WithField("key", key).Fatal("cache flush")
+				}
+				fmt.Printf("(%d/%d) %s deleted\n", i+1, len(keys), key)
+			}
+		}
+	},
+}
+
+func init() {
+	cacheFlushCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the keys to be deleted")
+	cacheCmd.AddCommand(cacheFlushCmd)
+}
@@ -0,0 +1,89 @@
+package cmd
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/conf"
+	"jutkey-server/packages/storage/sql"
+	"time"
+)
+
+var (
+	migrateUpSteps   int
+	migrateDownSteps int
+)
+
+// migrateCmd manages the schema of the tables owned by the server
+var migrateCmd = &cobra.Command{
+	Use:              "migrate",
+	Short:            "Database schema migrations",
+	PersistentPreRun: loadConfigWKey,
+}
+
+var migrateUpCmd = &cobra.Command{
+	Use:   "up",
+	Short: "Apply the pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		connectDatabase()
+		done, err := sql.MigrateUp(migrateUpSteps)
+		for _, m := range done {
+			fmt.Printf("applied  %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("migrate up")
+		}
+		if len(done) == 0 {
+			fmt.Println("no pending migrations")
+		}
+	},
+}
+
+var migrateDownCmd = &cobra.Command{
+	Use:   "down",
+	Short: "Revert the latest applied migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		connectDatabase()
+		done, err := sql.MigrateDown(migrateDownSteps)
+		for _, m := range done {
+			fmt.Printf("reverted %04d_%s\n", m.Version, m.Name)
+		}
+		if err != nil {
+			log.WithError(err).Fatal("migrate down")
+		}
+		if len(done) == 0 {
+			fmt.Println("no applied migrations")
+		}
+	},
+}
+
+var migrateStatusCmd = &cobra.Command{
+	Use:   "status",
+	Short: "Show the applied and pending migrations",
+	Run: func(cmd *cobra.Command, args []string) {
+		connectDatabase()
+		list, err := sql.MigrateStatus()
+		if err != nil {
+			log.WithError(err).Fatal("migrate status")
+		}
+		for _, m := range list {
+			status := "pending"
+			if m.Applied {
+				status = "applied " + time.Unix(m.AppliedAt, 0).UTC().Format(time.RFC3339)
+			}
+			fmt.Printf("%04d_%-30s %s\n", m.Version, m.Name, status)
+		}
+	},
+}
+
+func init() {
+	migrateUpCmd.Flags().IntVar(&migrateUpSteps, "steps", 0, "number of migrations to apply,0 applies all")
+	migrateDownCmd.Flags().IntVar(&migrateDownSteps, "steps", 1, "number of migrations to revert")
+	migrateCmd.AddCommand(migrateUpCmd, migrateDownCmd, migrateStatusCmd)
+}
+
+func connectDatabase() {
+	if err := conf.ConnectDatabase(); err != nil {
+		log.WithError(err).Fatal("postgres sql database connect failed")
+	}
+}
@@ -0,0 +1,66 @@
+package cmd
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/conf"
+	"jutkey-server/packages/storage/sql"
+	"time"
+)
+
+var (
+	reindexFromBlock int64
+	dryRun           bool
+)
+
+// reindexCmd rebuilds tx_data and utxo_history,the sync of the running servers pauses while it runs
+var reindexCmd = &cobra.Command{
+	Use:    "reindex",
+	Short:  "Truncate and rebuild tx_data and utxo_history from block_chain,the running servers pause their sync",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		if reindexFromBlock < 1 {
+			log.Fatal("--from-block must be greater than 0")
+		}
+		if err := conf.InitDatabase(); err != nil {
+			log.WithError(err).Fatal("postgres sql database connect failed")
+		}
+		plan, err := sql.GetReindexPlan(reindexFromBlock)
+		if err != nil {
+			log.WithError(err).Fatal("reindex plan")
+		}
+		fmt.Printf("rebuild blocks %d-%d,delete %d tx_data rows and %d utxo_history rows\n",
+			plan.FromBlock, plan.ChainBlock, plan.TxData, plan.UtxoHistory)
+		if dryRun {
+			fmt.Println("dry run,nothing changed")
+			return
+		}
+
+		start := time.Now()
+		phase, phaseStart := "", start
+		err = sql.Reindex(reindexFromBlock, 2*time.Second, func(table string, block int64) {
+			if phase != "" && table != phase {
+				phaseStart = time.Now()
+			}
+			phase = table
+			done := block - plan.FromBlock + 1
+			if done < 0 {
+				done = 0
+			}
+			total := plan.ChainBlock - plan.FromBlock + 1
+			fmt.Printf("%-12s block %d/%d %5.1f%% %.0f blocks/s\n", table, block, plan.ChainBlock,
+				float64(done)*100/float64(total), float64(done)/time.Since(phaseStart).Seconds())
+		})
+		if err != nil {
+			log.WithError(err).Fatal("reindex")
+		}
+		fmt.Printf("reindex completed in %s\n", time.Since(start).Round(time.Second))
+	},
+}
+
+func init() {
+	reindexCmd.Flags().Int64Var(&reindexFromBlock, "from-block", 0, "first block to rebuild")
+	reindexCmd.Flags().BoolVar(&dryRun, "dry-run", false, "only print the rows to be deleted")
+	_ = reindexCmd.MarkFlagRequired("from-block")
+}
@@ -12,10 +12,12 @@ import (
 	"jutkey-server/packages/daemons"
 	"jutkey-server/packages/storage/geoip"
 	"jutkey-server/packages/storage/sql"
+	"jutkey-server/packages/tracing"
 	"os"
 	"os/signal"
 	"path/filepath"
 	"syscall"
+	"time"
 )
 
 // rootCmd represents the base command when called without any subcommands
@@ -27,6 +29,12 @@ var rootCmd = &cobra.Command{
 func init() {
 	rootCmd.AddCommand(
 		initDatabaseCmd,
+		migrateCmd,
+		reindexCmd,
+		backfillCmd,
+		verifyCmd,
+		statsCmd,
+		cacheCmd,
 		startCmd,
 		versionCmd,
 	)
@@ -59,6 +67,9 @@ func loadStartRun() error {
 	}()
 	daemons.ExitCh = make(chan error)
 	conf.Initer()
+	if err := tracing.InitTracing(); err != nil {
+		log.WithError(err).Fatal("init tracing failed")
+	}
 
 	exitErr := func() {
 		api.SeverShutdown()
@@ -73,10 +84,19 @@ func loadStartRun() error {
 
 		geoip.CloseGeoIp()
 
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		if err = tracing.Shutdown(ctx); err != nil {
+			log.WithFields(log.Fields{"error": err}).Error("tracing shutdown failed")
+		}
+
 		os.Exit(1)
 	}
 
-	daemons.StartDaemons(context.Background())
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	daemons.StartDaemons(ctx)
+	go conf.WatchConfig(ctx)
 
 	go func() {
 		err := api.Run(conf.GetEnvConf().ServerInfo.Str())
@@ -85,7 +105,7 @@ func loadStartRun() error {
 		}
 	}()
 
-	sigChan := make(chan os.Signal)
+	sigChan := make(chan os.Signal, 1)
 	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGQUIT)
 	select {
 	case err := <-daemons.ExitCh:
@@ -99,7 +119,11 @@ func loadStartRun() error {
 }
 
 func loadInitDatabase() error {
-	return conf.InitDatabase()
+	if err := conf.InitDatabase(); err != nil {
+		return err
+	}
+	_, err := sql.MigrateUp(0)
+	return err
 }
 
 func loadConfigWKey(cmd *cobra.Command, args []string) {
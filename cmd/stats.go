@@ -0,0 +1,27 @@
+package cmd
+
+import (
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/packages/storage/sql"
+	"time"
+)
+
+// statsCmd prints how far the synced tables are behind the chain
+var statsCmd = &cobra.Command{
+	Use:    "stats",
+	Short:  "Print the indexer lag of tx_data and utxo_history",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		connectDatabase()
+		list, err := sql.GetIndexerLag()
+		if err != nil {
+			log.WithError(err).Fatal("stats")
+		}
+		fmt.Printf("%-12s %10s %10s %8s %s\n", "TABLE", "BLOCK", "TARGET", "LAG", "DELAY")
+		for _, l := range list {
+			fmt.Printf("%-12s %10d %10d %8d %s\n", l.Table, l.Block, l.Target, l.Lag, time.Duration(l.Delay)*time.Second)
+		}
+	},
+}
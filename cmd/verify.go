@@ -0,0 +1,60 @@
+package cmd
+
+import (
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	log "github.com/sirupsen/logrus"
+	"github.com/spf13/cobra"
+	"jutkey-server/packages/storage/sql"
+	"os"
+)
+
+var verifyEcosystem int64
+
+// verifyCmd checks the utxo_history balances,it only reads the database
+var verifyCmd = &cobra.Command{
+	Use:    "verify",
+	Short:  "Recompute the utxo balances from spent_info and compare them with utxo_history",
+	PreRun: loadConfigWKey,
+	Run: func(cmd *cobra.Command, args []string) {
+		connectDatabase()
+		var uh sql.UtxoHistory
+		f, err := uh.GetLast()
+		if err != nil {
+			log.WithError(err).Fatal("verify")
+		}
+		if !f {
+			fmt.Println("utxo_history is empty")
+			return
+		}
+		ecosystems := []int64{verifyEcosystem}
+		if verifyEcosystem == 0 {
+			if ecosystems, err = sql.GetUtxoEcosystems(); err != nil {
+				log.WithError(err).Fatal("verify")
+			}
+		}
+
+		var checked, mismatched int64
+		for i, eco := range ecosystems {
+			count, list, err := sql.VerifyUtxoBalances(eco, uh.Block)
+			if err != nil {
+				log.WithError(err).WithField("ecosystem", eco).Fatal("verify")
+			}
+			for _, m := range list {
+				fmt.Printf("  ecosystem %d %s expected %s stored %s\n",
+					m.Ecosystem, converter.AddressToString(m.KeyId), m.Expected, m.Stored)
+			}
+			checked += count
+			mismatched += int64(len(list))
+			fmt.Printf("ecosystem %d (%d/%d) %d keys %d mismatched\n", eco, i+1, len(ecosystems), count, len(list))
+		}
+		fmt.Printf("verified at block %d,%d keys %d mismatched\n", uh.Block, checked, mismatched)
+		if mismatched > 0 {
+			os.Exit(1)
+		}
+	},
+}
+
+func init() {
+	verifyCmd.Flags().Int64Var(&verifyEcosystem, "ecosystem", 0, "ecosystem to verify,0 verifies all")
+}
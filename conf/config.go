@@ -1,40 +1,68 @@
 package conf
 
 import (
+	"database/sql"
 	"encoding/json"
 	"fmt"
 	"github.com/IBAX-io/go-ibax/packages/common/crypto"
 	"github.com/IBAX-io/go-ibax/packages/conf/syspar"
 	"github.com/IBAX-io/go-ibax/packages/smart"
 	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
+	"gopkg.in/natefinch/lumberjack.v2"
 	"gorm.io/driver/postgres"
 	"gorm.io/gorm"
-	"gorm.io/gorm/logger"
+	"gorm.io/plugin/dbresolver"
+	"jutkey-server/packages/logs"
 	"os"
 	"path"
+	"path/filepath"
+	"reflect"
+	"sync/atomic"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gopkg.in/yaml.v2"
 )
 
+const (
+	defaultMaxIdle = 5
+	defaultMaxOpen = 20
+
+	defaultLogFile    = "logrus.log"
+	defaultLogMaxSize = 100 //MB
+)
+
 var (
-	configInfo EnvConf // all server config information
+	configInfo atomic.Value // *EnvConf,all server config information,replaced as a whole by a reload
 	pgdb       *gorm.DB
+	replicaDBs []*sql.DB
 )
 
+func init() {
+	configInfo.Store(&EnvConf{})
+}
+
 type EnvConf struct {
 	ConfigPath     string
 	ServerInfo     *serverModel      `yaml:"server"`
 	Centrifugo     *centrifugoConfig `yaml:"centrifugo"`
 	DatabaseInfo   *databaseModel    `yaml:"database"`
 	RedisInfo      *redisModel       `yaml:"redis"`
+	Cache          *cacheConfig      `yaml:"cache"`
 	Crontab        *crontab          `yaml:"crontab"`
+	Alert          *alertConfig      `yaml:"alert"`
+	Backfill       *backfillConfig   `yaml:"backfill"`
+	Reconcile      *reconcileConfig  `yaml:"reconcile"`
+	Content        *contentConfig    `yaml:"content"`
+	Log            *logConfig        `yaml:"log"`
+	Cors           *corsConfig       `yaml:"cors"`
+	Tracing        *tracingConfig    `yaml:"tracing"`
 	CryptoSettings cryptoSettings    `yaml:"crypto_settings"`
 }
 
+// GetEnvConf returns the current config,a reload stores a new one so the returned config is never changed by it
 func GetEnvConf() *EnvConf {
-	return &configInfo
+	return configInfo.Load().(*EnvConf)
 }
 
 func GetDbConn() *databaseModel {
@@ -45,24 +73,72 @@ func GetRedisDbConn() *redisModel {
 	return GetEnvConf().RedisInfo
 }
 
+func GetCacheConf() *cacheConfig {
+	return GetEnvConf().Cache
+}
+
 func GetCentrifugoConn() *centrifugoConfig {
 	return GetEnvConf().Centrifugo
 }
 
+func GetAlertConf() *alertConfig {
+	return GetEnvConf().Alert
+}
+
+func GetBackfillConf() *backfillConfig {
+	return GetEnvConf().Backfill
+}
+
+func GetReconcileConf() *reconcileConfig {
+	return GetEnvConf().Reconcile
+}
+
+func GetContentConf() *contentConfig {
+	return GetEnvConf().Content
+}
+
+func GetLogConf() *logConfig {
+	return GetEnvConf().Log
+}
+
+func GetCorsConf() *corsConfig {
+	return GetEnvConf().Cors
+}
+
+func GetTracingConf() *tracingConfig {
+	return GetEnvConf().Tracing
+}
+
 func LoadConfig(configPath string) {
+	cfg, err := readConfig(configPath)
+	if err != nil {
+		logrus.WithError(err).Fatal("config load failed")
+	}
+	configInfo.Store(cfg)
+	data, _ := json.Marshal(cfg.Redacted())
+	fmt.Printf("config: %v\n", string(data))
+	registerCrypto(GetEnvConf().CryptoSettings)
+}
+
+// readConfig parses config.yml of configPath,applies the JUTKEY_* environment overrides and validates the result
+func readConfig(configPath string) (*EnvConf, error) {
 	filePath := path.Join(configPath, "config.yml")
 	configData, err := os.ReadFile(filePath)
 	if err != nil {
-		logrus.WithError(err).Fatal("config file read failed")
+		return nil, err
 	}
 	configData = []byte(os.ExpandEnv(string(configData)))
-	err = yaml.Unmarshal(configData, &configInfo)
-	data, _ := json.Marshal(&configInfo)
-	fmt.Printf("config: %v\n", string(data))
-	if err != nil {
-		logrus.WithError(err).Fatal("config parse failed")
+	cfg := &EnvConf{ConfigPath: configPath}
+	if err = yaml.Unmarshal(configData, cfg); err != nil {
+		return nil, fmt.Errorf("%s: %s", filePath, err.Error())
 	}
-	registerCrypto(GetEnvConf().CryptoSettings)
+	if err = applyEnv(reflect.ValueOf(cfg).Elem(), EnvPrefix); err != nil {
+		return nil, err
+	}
+	if err = cfg.Validate(); err != nil {
+		return nil, err
+	}
+	return cfg, nil
 }
 
 func Initer() {
@@ -72,11 +148,15 @@ func Initer() {
 	if err != nil {
 		logrus.WithError(err).Fatal("init log file")
 	}
-	if err := redis.Initer(); err != nil {
-		logrus.WithError(err).Fatal("redis database config information: %v", redis)
+	if redis != nil {
+		if err := redis.Initer(); err != nil {
+			logrus.WithError(err).WithField("address", redis.Str()).Fatal("redis database connect failed")
+		}
 	}
-	if err := centrifugo.Initer(); err != nil {
-		logrus.WithError(err).Fatal("centrifugo config information: %v", centrifugo)
+	if centrifugo != nil {
+		if err := centrifugo.Initer(); err != nil {
+			logrus.WithError(err).WithField("url", centrifugo.URL).Fatal("centrifugo init failed")
+		}
 	}
 	err = InitDatabase()
 	if err != nil {
@@ -90,34 +170,92 @@ func InitTimeLocal() {
 
 func initLogs() error {
 	InitTimeLocal()
-	fileName := path.Join(GetEnvConf().ConfigPath, "logrus.log")
-	openMode := os.O_APPEND
-	if _, err := os.Stat(fileName); os.IsNotExist(err) {
-		openMode = os.O_CREATE
+	l := GetLogConf()
+	if l == nil {
+		l = &logConfig{}
+	}
+	if l.Format == "text" {
+		logrus.SetFormatter(&logrus.TextFormatter{FullTimestamp: true})
+	} else {
+		logrus.SetFormatter(&logrus.JSONFormatter{})
+	}
+	setLogLevel(l)
+	if l.Output == "stdout" {
+		logrus.SetOutput(os.Stdout)
+		return nil
 	}
-	f, err := os.OpenFile(fileName, os.O_WRONLY|openMode, 0755)
+	fileName := l.File
+	if fileName == "" {
+		fileName = defaultLogFile
+	}
+	if !filepath.IsAbs(fileName) {
+		fileName = filepath.Join(GetEnvConf().ConfigPath, fileName)
+	}
+	maxSize := l.MaxSize
+	if maxSize <= 0 {
+		maxSize = defaultLogMaxSize
+	}
+	f, err := os.OpenFile(fileName, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
 	if err != nil {
 		fmt.Fprintln(os.Stderr, "Can't open log file: ", fileName)
 		return err
 	}
-	logrus.SetOutput(f)
+	f.Close()
+	logrus.SetOutput(&lumberjack.Logger{
+		Filename:   fileName,
+		MaxSize:    maxSize,
+		MaxAge:     l.MaxAge,
+		MaxBackups: l.MaxBackups,
+		LocalTime:  true,
+		Compress:   l.Compress,
+	})
 	return nil
 }
 
+func setLogLevel(l *logConfig) {
+	level := logrus.InfoLevel
+	if l != nil && l.Level != "" {
+		level, _ = logrus.ParseLevel(l.Level) //checked by Validate
+	}
+	logrus.SetLevel(level)
+}
+
 func registerCrypto(c cryptoSettings) {
 	crypto.InitAsymAlgo(c.Cryptoer)
 	crypto.InitHashAlgo(c.Hasher)
 }
 
+func slowSqlThreshold() time.Duration {
+	if l := GetLogConf(); l != nil && l.SlowSql > 0 {
+		return time.Duration(l.SlowSql) * time.Millisecond
+	}
+	return 0
+}
+
 func InitDatabase() (err error) {
-	dbn := configInfo.DatabaseInfo
+	if err = ConnectDatabase(); err != nil {
+		return err
+	}
+	if err = syspar.SysUpdate(nil); err != nil {
+		return err
+	}
+	smart.InitVM()
+	if err := smart.LoadContracts(); err != nil {
+		return err
+	}
+	return nil
+}
+
+// ConnectDatabase opens the postgres connection pool without loading the chain parameters and contracts
+func ConnectDatabase() (err error) {
+	dbn := GetDbConn()
 
 	dsn := fmt.Sprintf("%s TimeZone=UTC", dbn.Connect)
 	pgdb, err = gorm.Open(postgres.New(postgres.Config{
 		DSN: dsn,
 	}), &gorm.Config{
 		//AllowGlobalUpdate: true,                      //allow global update
-		Logger: logger.Default.LogMode(logger.Silent), // start Logger,show detail log
+		Logger: logs.NewGormLogger(slowSqlThreshold()),
 	})
 
 	if err != nil {
@@ -127,17 +265,54 @@ func InitDatabase() (err error) {
 	if err != nil {
 		return err
 	}
-	dbSql.SetConnMaxLifetime(time.Minute * 10)
-	dbSql.SetMaxIdleConns(5)
-	dbSql.SetMaxOpenConns(20)
-	sqldb.DBConn = pgdb
-
-	if err = syspar.SysUpdate(nil); err != nil {
+	setPool(dbSql, dbn)
+	if err = connectReplicas(dbn); err != nil {
 		return err
 	}
-	smart.InitVM()
-	if err := smart.LoadContracts(); err != nil {
+	if err = pgdb.Use(statementTimeout{}); err != nil {
 		return err
 	}
+	//the chain queries of go-ibax stay on the primary,they are read and written by the sync
+	sqldb.DBConn = dbn.Primary()
 	return nil
 }
+
+func setPool(db *sql.DB, dbn *databaseModel) {
+	db.SetConnMaxLifetime(time.Minute * 10)
+	maxIdle, maxOpen := defaultMaxIdle, defaultMaxOpen
+	if dbn.MaxIdle > 0 {
+		maxIdle = dbn.MaxIdle
+	}
+	if dbn.MaxOpen > 0 {
+		maxOpen = dbn.MaxOpen
+	}
+	db.SetMaxIdleConns(maxIdle)
+	db.SetMaxOpenConns(maxOpen)
+}
+
+// connectReplicas registers the read replicas on pgdb,the reads are balanced over them and
+// the writes,raw non select statements and transactions stay on the primary
+func connectReplicas(dbn *databaseModel) error {
+	if len(dbn.Replicas) == 0 {
+		return nil
+	}
+	dialectors := make([]gorm.Dialector, 0, len(dbn.Replicas))
+	for i, r := range dbn.Replicas {
+		dsn := fmt.Sprintf("%s TimeZone=UTC", r)
+		db, err := sql.Open("pgx", dsn)
+		if err != nil {
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+		if err = db.Ping(); err != nil {
+			db.Close()
+			return fmt.Errorf("replica %d: %w", i, err)
+		}
+		setPool(db, dbn)
+		replicaDBs = append(replicaDBs, db)
+		dialectors = append(dialectors, postgres.New(postgres.Config{Conn: db}))
+	}
+	return pgdb.Use(dbresolver.Register(dbresolver.Config{
+		Replicas: dialectors,
+		Policy:   dbresolver.RandomPolicy{},
+	}))
+}
@@ -0,0 +1,174 @@
+package conf
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func writeConfig(t *testing.T, data string) string {
+	dir := t.TempDir()
+	if err := os.WriteFile(filepath.Join(dir, "config.yml"), []byte(data), 0644); err != nil {
+		t.Fatal(err)
+	}
+	return dir
+}
+
+func TestReadConfigEnv(t *testing.T) {
+	data, err := os.ReadFile("config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeConfig(t, string(data))
+	t.Setenv("JUTKEY_DATABASE_MAX_OPEN", "50")
+	t.Setenv("JUTKEY_REDIS_ENABLE", "false")
+	t.Setenv("JUTKEY_CORS_ALLOW_ORIGINS", "https://a.com, https://b.com")
+	t.Setenv("JUTKEY_CONTENT_S3_BUCKET", "files")
+
+	cfg, err := readConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cfg.DatabaseInfo.MaxOpen != 50 || cfg.RedisInfo.Enable || cfg.Content.S3.Bucket != "files" {
+		t.Errorf("env not applied: %+v %+v %+v", cfg.DatabaseInfo, cfg.RedisInfo, cfg.Content.S3)
+	}
+	if got := strings.Join(cfg.Cors.AllowOrigins, ","); got != "https://a.com,https://b.com" {
+		t.Errorf("cors origins %q", got)
+	}
+
+	t.Setenv("JUTKEY_SERVER_PORT", "abc")
+	if _, err = readConfig(dir); err == nil || !strings.Contains(err.Error(), "JUTKEY_SERVER_PORT") {
+		t.Errorf("expected JUTKEY_SERVER_PORT error,got %v", err)
+	}
+}
+
+func TestValidate(t *testing.T) {
+	dir := writeConfig(t, `
+server:
+  mode: prod
+  host: 0.0.0.0
+  port: 70000
+  public_url: ftp://a.com
+database:
+  enable: true
+  max_idle: 30
+  max_open: 10
+crontab:
+  real_time: "every second"
+  delay: "0/20 * * * * ?"
+log:
+  level: verbose
+cors:
+  allow_origins: ["*", "https://*.ibax.io", "ftp://a.com"]
+  allow_credentials: true
+  groups:
+    - paths: [logo]
+      allow_methods: [get]
+cache:
+  backend: memcached
+reconcile:
+  enable: true
+  sample_size: 500
+  full_interval: -1
+`)
+	_, err := readConfig(dir)
+	if err == nil {
+		t.Fatal("expected invalid config")
+	}
+	for _, field := range []string{"server.mode", "server.port", "server.public_url", "database.connect", "database.max_idle",
+		"crontab.real_time", "log.level", "crypto_settings.cryptoer", "cors.allow_origins",
+		"cors.groups[0].paths", "cors.groups[0].allow_methods", "cache.backend", "reconcile.full_interval"} {
+		if !strings.Contains(err.Error(), field+":") {
+			t.Errorf("%s not reported in %s", field, err)
+		}
+	}
+	if strings.Contains(err.Error(), "crontab.delay") || strings.Contains(err.Error(), "ibax.io") ||
+		strings.Contains(err.Error(), "reconcile.sample_size") {
+		t.Errorf("valid crontab.delay reported: %s", err)
+	}
+}
+
+func TestRedacted(t *testing.T) {
+	cfg := &EnvConf{
+		DatabaseInfo: &databaseModel{
+			Connect:  "host=127.0.0.1 user=postgres password=123456 sslmode=disable",
+			Replicas: []string{"host=10.0.0.2 user=reader password='a b' sslmode=disable"},
+		},
+		RedisInfo:  &redisModel{Address: "127.0.0.1"},
+		Centrifugo: &centrifugoConfig{Secret: "s", Key: "k", URL: "http://127.0.0.1:8001"},
+		ServerInfo: &serverModel{Port: 7023, AdminToken: "t"},
+	}
+	m := cfg.Redacted()
+	if got := m["database"].(map[string]any)["connect"]; got != "host=127.0.0.1 user=postgres password=****** sslmode=disable" {
+		t.Errorf("connect %v", got)
+	}
+	if got := m["database"].(map[string]any)["replicas"].([]string); got[0] != "host=10.0.0.2 user=reader password=****** sslmode=disable" {
+		t.Errorf("replicas %v", got)
+	}
+	ce := m["centrifugo"].(map[string]any)
+	if ce["secret"] != redactedValue || ce["key"] != redactedValue || ce["url"] != "http://127.0.0.1:8001" {
+		t.Errorf("centrifugo %v", ce)
+	}
+	if got := m["server"].(map[string]any)["admin_token"]; got != redactedValue {
+		t.Errorf("admin_token %v", got)
+	}
+	if got := m["redis"].(map[string]any)["password"]; got != "" {
+		t.Errorf("empty password %v", got)
+	}
+}
+
+func TestRemainingTimeout(t *testing.T) {
+	if _, ok := remainingTimeout(context.Background()); ok {
+		t.Error("timeout without a deadline")
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), time.Minute)
+	defer cancel()
+	if ms, ok := remainingTimeout(ctx); !ok || ms <= 59000 || ms > 60000 {
+		t.Errorf("remaining %d,%v", ms, ok)
+	}
+}
+
+func TestReloadSnapshot(t *testing.T) {
+	data, err := os.ReadFile("config.yml")
+	if err != nil {
+		t.Fatal(err)
+	}
+	dir := writeConfig(t, string(data))
+	cfg, err := readConfig(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev := GetEnvConf()
+	configInfo.Store(cfg)
+	defer configInfo.Store(prev)
+
+	//the readers run while the config is reloaded,go test -race reports a shared write
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		for ctx.Err() == nil {
+			if c := GetCorsConf(); c != nil {
+				_ = len(c.AllowOrigins)
+			}
+			_ = GetLogConf()
+		}
+	}()
+	t.Setenv("JUTKEY_CORS_ALLOW_ORIGINS", "https://a.com")
+	for i := 0; i < 5; i++ {
+		if err = Reload(); err != nil {
+			t.Fatal(err)
+		}
+	}
+	cancel()
+	<-done
+	if got := strings.Join(GetCorsConf().AllowOrigins, ","); got != "https://a.com" {
+		t.Errorf("cors origins %q after reload", got)
+	}
+	if cfg.Cors.AllowOrigins[0] == "https://a.com" {
+		t.Error("reload changed the previous config")
+	}
+}
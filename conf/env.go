@@ -0,0 +1,153 @@
+package conf
+
+import (
+	"fmt"
+	"os"
+	"reflect"
+	"regexp"
+	"strconv"
+	"strings"
+)
+
+// EnvPrefix is the prefix of the environment variables overriding config.yml,
+// the name is made of the yaml keys,e.g. JUTKEY_DATABASE_MAX_OPEN,JUTKEY_CONTENT_S3_BUCKET
+const EnvPrefix = "JUTKEY"
+
+const redactedValue = "******"
+
+var dsnPassword = regexp.MustCompile(`(?i)(password=)('[^']*'|\S+)`)
+
+// applyEnv overrides the fields of v with the environment variables named after prefix
+func applyEnv(v reflect.Value, prefix string) error {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+		name := prefix + "_" + strings.ToUpper(key)
+		switch {
+		case fv.Kind() == reflect.Ptr && fv.Type().Elem().Kind() == reflect.Struct:
+			if fv.IsNil() {
+				if !hasEnvPrefix(name + "_") {
+					continue
+				}
+				fv.Set(reflect.New(fv.Type().Elem()))
+			}
+			if err := applyEnv(fv.Elem(), name); err != nil {
+				return err
+			}
+		case fv.Kind() == reflect.Struct:
+			if err := applyEnv(fv, name); err != nil {
+				return err
+			}
+		default:
+			val, ok := os.LookupEnv(name)
+			if !ok {
+				continue
+			}
+			if err := setValue(fv, val); err != nil {
+				return fmt.Errorf("%s: %s", name, err.Error())
+			}
+		}
+	}
+	return nil
+}
+
+func setValue(fv reflect.Value, val string) error {
+	switch fv.Kind() {
+	case reflect.String:
+		fv.SetString(val)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(val)
+		if err != nil {
+			return err
+		}
+		fv.SetBool(b)
+	case reflect.Int, reflect.Int64:
+		n, err := strconv.ParseInt(val, 10, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetInt(n)
+	case reflect.Float64:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return err
+		}
+		fv.SetFloat(f)
+	case reflect.Slice:
+		if fv.Type().Elem().Kind() != reflect.String {
+			return fmt.Errorf("unsupported type %s", fv.Type())
+		}
+		var list []string
+		for _, s := range strings.Split(val, ",") {
+			if s = strings.TrimSpace(s); s != "" {
+				list = append(list, s)
+			}
+		}
+		fv.Set(reflect.ValueOf(list))
+	default:
+		return fmt.Errorf("unsupported type %s", fv.Type())
+	}
+	return nil
+}
+
+func yamlKey(field reflect.StructField) string {
+	tag := strings.Split(field.Tag.Get("yaml"), ",")[0]
+	if tag == "-" || !field.IsExported() {
+		return ""
+	}
+	return tag
+}
+
+func hasEnvPrefix(prefix string) bool {
+	for _, kv := range os.Environ() {
+		if strings.HasPrefix(kv, prefix) {
+			return true
+		}
+	}
+	return false
+}
+
+// Redacted returns the config as yaml keyed maps with the secrets masked,it is safe to be logged
+func (c *EnvConf) Redacted() map[string]any {
+	return redact(reflect.ValueOf(c).Elem())
+}
+
+func redact(v reflect.Value) map[string]any {
+	t := v.Type()
+	m := make(map[string]any, t.NumField())
+	for i := 0; i < t.NumField(); i++ {
+		field, fv := t.Field(i), v.Field(i)
+		key := yamlKey(field)
+		if key == "" {
+			continue
+		}
+		if fv.Kind() == reflect.Ptr {
+			if fv.IsNil() {
+				m[key] = nil
+				continue
+			}
+			fv = fv.Elem()
+		}
+		switch {
+		case fv.Kind() == reflect.Struct:
+			m[key] = redact(fv)
+		case field.Tag.Get("redact") == "dsn" && fv.Kind() == reflect.Slice:
+			list := make([]string, fv.Len())
+			for j := range list {
+				list[j] = dsnPassword.ReplaceAllString(fv.Index(j).String(), "${1}"+redactedValue)
+			}
+			m[key] = list
+		case field.Tag.Get("redact") == "dsn":
+			m[key] = dsnPassword.ReplaceAllString(fv.String(), "${1}"+redactedValue)
+		case field.Tag.Get("redact") != "" && !fv.IsZero():
+			m[key] = redactedValue
+		default:
+			m[key] = fv.Interface()
+		}
+	}
+	return m
+}
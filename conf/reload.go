@@ -0,0 +1,131 @@
+package conf
+
+import (
+	"context"
+	"github.com/fsnotify/fsnotify"
+	"github.com/sirupsen/logrus"
+	"os"
+	"os/signal"
+	"path/filepath"
+	"reflect"
+	"sync"
+	"syscall"
+	"time"
+)
+
+const reloadDebounce = 500 * time.Millisecond
+
+var (
+	reloadMu    sync.Mutex
+	reloadHooks []func(c *EnvConf)
+)
+
+// OnReload registers fn to be called with the new config after it has been reloaded,
+// fn must only apply the settings that are safe to change at runtime:
+// crontab schedules,server.rate_limit,log.level and the cors section
+func OnReload(fn func(c *EnvConf)) {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	reloadHooks = append(reloadHooks, fn)
+}
+
+// Reload reads config.yml again,an invalid config is rejected and the running one is kept
+func Reload() error {
+	reloadMu.Lock()
+	defer reloadMu.Unlock()
+	old := GetEnvConf()
+	cfg, err := readConfig(old.ConfigPath)
+	if err != nil {
+		return err
+	}
+	warnRestartRequired(old, cfg)
+
+	//the readers keep the config they got,the reloaded sections are swapped in a copy
+	next := *old
+	next.Crontab = cfg.Crontab
+	next.Log = cfg.Log
+	next.Cors = cfg.Cors
+	configInfo.Store(&next)
+	setLogLevel(cfg.Log)
+	for _, fn := range reloadHooks {
+		fn(cfg)
+	}
+	logrus.Info("config reloaded")
+	return nil
+}
+
+// warnRestartRequired logs the changed sections that are only applied after a restart
+func warnRestartRequired(old, cfg *EnvConf) {
+	oldServer, newServer := serverModel{}, serverModel{}
+	if old.ServerInfo != nil {
+		oldServer = *old.ServerInfo
+	}
+	if cfg.ServerInfo != nil {
+		newServer = *cfg.ServerInfo
+	}
+	oldServer.RateLimit, newServer.RateLimit = 0, 0
+	changed := map[string]bool{
+		"server":          !reflect.DeepEqual(oldServer, newServer),
+		"centrifugo":      !reflect.DeepEqual(old.Centrifugo, cfg.Centrifugo),
+		"database":        !reflect.DeepEqual(old.DatabaseInfo, cfg.DatabaseInfo),
+		"redis":           !reflect.DeepEqual(old.RedisInfo, cfg.RedisInfo),
+		"cache":           !reflect.DeepEqual(old.Cache, cfg.Cache),
+		"alert":           !reflect.DeepEqual(old.Alert, cfg.Alert),
+		"backfill":        !reflect.DeepEqual(old.Backfill, cfg.Backfill),
+		"reconcile":       !reflect.DeepEqual(old.Reconcile, cfg.Reconcile),
+		"content":         !reflect.DeepEqual(old.Content, cfg.Content),
+		"crypto_settings": old.CryptoSettings != cfg.CryptoSettings,
+	}
+	for section, ok := range changed {
+		if ok {
+			logrus.WithField("section", section).Warn("config changed,restart the server to apply it")
+		}
+	}
+}
+
+// WatchConfig reloads the config on SIGHUP and when config.yml is written,until ctx is done
+func WatchConfig(ctx context.Context) {
+	hup := make(chan os.Signal, 1)
+	signal.Notify(hup, syscall.SIGHUP)
+	defer signal.Stop(hup)
+
+	var events chan fsnotify.Event
+	var errs chan error
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		logrus.WithError(err).Error("config watcher create failed,only SIGHUP reloads the config")
+	} else {
+		defer watcher.Close()
+		//watch the directory,editors replace the file instead of writing it
+		if err = watcher.Add(GetEnvConf().ConfigPath); err != nil {
+			logrus.WithError(err).Error("config watcher add failed,only SIGHUP reloads the config")
+		} else {
+			events, errs = watcher.Events, watcher.Errors
+		}
+	}
+
+	file := filepath.Clean(filepath.Join(GetEnvConf().ConfigPath, "config.yml"))
+	debounce := time.NewTimer(time.Hour)
+	debounce.Stop()
+	reload := func(reason string) {
+		if err := Reload(); err != nil {
+			logrus.WithError(err).WithField("reason", reason).Error("config reload failed")
+		}
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-hup:
+			reload("SIGHUP")
+		case ev := <-events:
+			if filepath.Clean(ev.Name) == file && ev.Op&(fsnotify.Write|fsnotify.Create|fsnotify.Rename) != 0 {
+				debounce.Reset(reloadDebounce)
+			}
+		case err := <-errs:
+			logrus.WithError(err).Error("config watcher")
+		case <-debounce.C:
+			reload("file changed")
+		}
+	}
+}
@@ -0,0 +1,102 @@
+package conf
+
+import (
+	"context"
+	"database/sql"
+	"gorm.io/gorm"
+	"strconv"
+	"time"
+)
+
+// statementTimeoutKey keeps the transaction of a statement in its settings,
+// the statement is part of the key as the settings are copied to the statements cloned from it
+type statementTimeoutKey struct {
+	stmt *gorm.Statement
+}
+
+type statementTimeoutTx struct {
+	tx   *sql.Tx
+	pool gorm.ConnPool
+}
+
+// statementTimeout runs the queries with a context deadline,the api requests bounded by
+// database.statement_timeout,in a transaction that sets the remaining time as its statement_timeout.
+// It applies to the primary and the replicas alike,so the server stops a query the client gave up on.
+// Only the Query callbacks are wrapped,Find,First,Take,Count and Pluck included. The Row callbacks,
+// Row,Rows and Scan,return the rows after the callbacks are done so they can't run in the transaction,
+// their queries are only stopped by the cancel request pgx sends when the deadline is exceeded
+type statementTimeout struct{}
+
+func (statementTimeout) Name() string {
+	return "statement_timeout"
+}
+
+func (p statementTimeout) Initialize(db *gorm.DB) error {
+	//after the resolver has chosen the primary or a replica
+	err := db.Callback().Query().Before("gorm:query").After("gorm:db_resolver").Register("statement_timeout:begin", p.begin)
+	if err != nil {
+		return err
+	}
+	return db.Callback().Query().After("gorm:query").Register("statement_timeout:end", p.end)
+}
+
+// remainingTimeout returns the milliseconds left before the deadline of ctx,false without a deadline
+func remainingTimeout(ctx context.Context) (int64, bool) {
+	if ctx == nil {
+		return 0, false
+	}
+	deadline, ok := ctx.Deadline()
+	if !ok {
+		return 0, false
+	}
+	return time.Until(deadline).Milliseconds(), true
+}
+
+func (statementTimeout) begin(db *gorm.DB) {
+	if db.Error != nil {
+		return
+	}
+	ms, ok := remainingTimeout(db.Statement.Context)
+	if !ok {
+		return
+	}
+	//not in a transaction already
+	beginner, ok := db.Statement.ConnPool.(gorm.TxBeginner)
+	if !ok {
+		return
+	}
+	if ms <= 0 {
+		db.AddError(context.DeadlineExceeded)
+		return
+	}
+	tx, err := beginner.BeginTx(db.Statement.Context, nil)
+	if err != nil {
+		db.AddError(err)
+		return
+	}
+	if _, err = tx.ExecContext(db.Statement.Context, "SET LOCAL statement_timeout = "+strconv.FormatInt(ms, 10)); err != nil {
+		tx.Rollback()
+		db.AddError(err)
+		return
+	}
+	db.Statement.Settings.Store(statementTimeoutKey{db.Statement}, statementTimeoutTx{tx: tx, pool: db.Statement.ConnPool})
+	db.Statement.ConnPool = tx
+}
+
+func (statementTimeout) end(db *gorm.DB) {
+	//deleted,so a later run of the same statement doesn't find a finished transaction
+	v, ok := db.Statement.Settings.LoadAndDelete(statementTimeoutKey{db.Statement})
+	if !ok {
+		return
+	}
+	st := v.(statementTimeoutTx)
+	//the statement may be run again,e.g. a count then a find on the same query
+	db.Statement.ConnPool = st.pool
+	if db.Error != nil {
+		st.tx.Rollback()
+		return
+	}
+	if err := st.tx.Commit(); err != nil {
+		db.AddError(err)
+	}
+}
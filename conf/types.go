@@ -6,6 +6,8 @@ import (
 	"github.com/centrifugal/gocent"
 	"github.com/go-redis/redis/v8"
 	"gorm.io/gorm"
+	"gorm.io/plugin/dbresolver"
+	"time"
 )
 
 var (
@@ -15,14 +17,18 @@ var (
 )
 
 type serverModel struct {
-	Mode        string `yaml:"mode"`         // run mode
-	Host        string `yaml:"host"`         // server host
-	Port        int    `yaml:"port"`         // server port
-	EnableHttps bool   `yaml:"enable_https"` // enable https
-	CertFile    string `yaml:"cert_file"`    // cert file path
-	KeyFile     string `yaml:"key_file"`     // key file path
-	DocsApi     string `yaml:"docs_api"`     // api docs request address
-	BaseUrl     string `yaml:"base_url"`
+	Mode        string  `yaml:"mode"`         // run mode
+	Host        string  `yaml:"host"`         // server host
+	Port        int     `yaml:"port"`         // server port
+	EnableHttps bool    `yaml:"enable_https"` // enable https
+	CertFile    string  `yaml:"cert_file"`    // cert file path
+	KeyFile     string  `yaml:"key_file"`     // key file path
+	DocsApi     string  `yaml:"docs_api"`     // api docs request address
+	BaseUrl     string  `yaml:"base_url"`
+	PublicUrl   string  `yaml:"public_url"`                // scheme and host the clients reach the api at,used for the absolute urls in responses
+	RateLimit   float64 `yaml:"rate_limit"`                // requests per second of every client,default 10
+	AdminToken  string  `yaml:"admin_token" redact:"true"` // bearer token of the admin api and /metrics,empty disables them
+	AuthSecret  string  `yaml:"auth_secret" redact:"true"` // signing key of the wallet tokens,empty disables the alert api
 }
 
 type crontab struct {
@@ -33,11 +39,15 @@ type crontab struct {
 type databaseModel struct {
 	Enable  bool   `yaml:"enable"`
 	DBType  string `yaml:"type"`
-	Connect string `yaml:"connect"`
+	Connect string `yaml:"connect" redact:"dsn"`
 	Name    string `yaml:"name"`
 	Ver     string `yaml:"ver"`
 	MaxIdle int    `yaml:"max_idle"`
 	MaxOpen int    `yaml:"max_open"`
+	//Replicas are the read only connections,the queries bound to a request context are sent to them
+	Replicas []string `yaml:"replicas" redact:"dsn"`
+	//StatementTimeout is the max duration of the request queries in milliseconds,0 means no limit
+	StatementTimeout int `yaml:"statement_timeout"`
 }
 
 type cryptoSettings struct {
@@ -47,24 +57,125 @@ type cryptoSettings struct {
 
 type centrifugoConfig struct {
 	Enable bool   `yaml:"enable"`
-	Secret string `yaml:"secret"`
+	Secret string `yaml:"secret" redact:"true"`
 	URL    string `yaml:"url"`
 	Socket string `yaml:"socket"`
-	Key    string `yaml:"key"`
+	Key    string `yaml:"key" redact:"true"`
+}
+
+type alertConfig struct {
+	Enable         bool       `yaml:"enable"`
+	Workers        int        `yaml:"workers"`         // concurrent delivery workers
+	MaxRetries     int        `yaml:"max_retries"`     // delivery attempts before dead-letter
+	RetryBackoff   int        `yaml:"retry_backoff"`   // first retry delay(seconds),doubles every attempt
+	WebhookTimeout int        `yaml:"webhook_timeout"` // webhook request timeout(seconds)
+	Smtp           smtpConfig `yaml:"smtp"`
+}
+
+type backfillConfig struct {
+	Enable      bool  `yaml:"enable"`
+	MinBlocks   int64 `yaml:"min_blocks"`   // utxo_history lag(blocks) that starts a backfill at start
+	Workers     int   `yaml:"workers"`      // decode workers,0 uses the cpu count
+	ChunkBlocks int64 `yaml:"chunk_blocks"` // blocks decoded by a worker at a time
+	BatchRows   int   `yaml:"batch_rows"`   // rows loaded by a COPY
+}
+
+type reconcileConfig struct {
+	Enable         bool `yaml:"enable"`
+	SampleSize     int  `yaml:"sample_size"`     // wallets checked by a sample run
+	SampleInterval int  `yaml:"sample_interval"` // minutes between the sample runs
+	FullInterval   int  `yaml:"full_interval"`   // hours between the runs checking every wallet,0 disables them
+}
+
+type smtpConfig struct {
+	Host     string `yaml:"host"`
+	Port     int    `yaml:"port"`
+	Username string `yaml:"username"`
+	Password string `yaml:"password" redact:"true"`
+	From     string `yaml:"from"`
+}
+
+type contentConfig struct {
+	Backend   string   `yaml:"backend"`    // local,s3
+	CacheDir  string   `yaml:"cache_dir"`  // local cache directory
+	CacheSize int64    `yaml:"cache_size"` // local cache size(MB),least recently used files are evicted
+	S3        s3Config `yaml:"s3"`
+}
+
+type s3Config struct {
+	Endpoint  string `yaml:"endpoint"`
+	AccessKey string `yaml:"access_key" redact:"true"`
+	SecretKey string `yaml:"secret_key" redact:"true"`
+	Bucket    string `yaml:"bucket"`
+	Region    string `yaml:"region"`
+	UseSSL    bool   `yaml:"use_ssl"`
 }
 
 type redisModel struct {
+	Enable   bool   `yaml:"enable"`
 	Address  string `yaml:"address"`
 	Port     int    `yaml:"port"`
-	Password string `yaml:"password"`
+	Password string `yaml:"password" redact:"true"`
 	Db       int    `yaml:"db"`
 }
 
+type cacheConfig struct {
+	Backend string `yaml:"backend"`  // redis,memory,empty uses redis when it's enabled
+	MaxKeys int    `yaml:"max_keys"` // keys of the memory backend,the least recently used ones are evicted
+}
+
+type logConfig struct {
+	Level      string `yaml:"level"`       // panic,fatal,error,warn,info,debug,trace
+	Format     string `yaml:"format"`      // json,text
+	Output     string `yaml:"output"`      // stdout,file
+	File       string `yaml:"file"`        // log file,relative to the config directory
+	MaxSize    int    `yaml:"max_size"`    // size(MB) of the file before it's rotated
+	MaxAge     int    `yaml:"max_age"`     // days to keep the rotated files,0 keeps them all
+	MaxBackups int    `yaml:"max_backups"` // rotated files to keep,0 keeps them all
+	Compress   bool   `yaml:"compress"`    // gzip the rotated files
+	SlowSql    int    `yaml:"slow_sql"`    // queries slower than it(ms) are logged as warnings,0 disables
+}
+
+type tracingConfig struct {
+	Enable      bool    `yaml:"enable"`
+	ServiceName string  `yaml:"service_name"`
+	Exporter    string  `yaml:"exporter"` // otlp,stdout,file
+	Protocol    string  `yaml:"protocol"` // otlp protocol:grpc,http
+	Endpoint    string  `yaml:"endpoint"` // otlp collector address,e.g. 127.0.0.1:4317
+	Insecure    bool    `yaml:"insecure"` // otlp without tls
+	File        string  `yaml:"file"`     // spans file of the file exporter,relative to the config directory
+	SampleRatio float64 `yaml:"sample_ratio"`
+}
+
+type corsConfig struct {
+	AllowOrigins     []string    `yaml:"allow_origins"`     // exact origins,"*" or wildcard subdomains like https://*.example.com
+	AllowMethods     []string    `yaml:"allow_methods"`     // preflight allowed methods
+	AllowHeaders     []string    `yaml:"allow_headers"`     // preflight allowed request headers
+	ExposeHeaders    []string    `yaml:"expose_headers"`    // response headers readable by the browser
+	MaxAge           int         `yaml:"max_age"`           // preflight cache(seconds),0 lets the browser decide
+	AllowCredentials bool        `yaml:"allow_credentials"` // can't be used with "*"
+	Groups           []corsGroup `yaml:"groups"`            // overrides of the route groups
+}
+
+// corsGroup overrides the set fields of the cors policy for the request paths under Paths
+type corsGroup struct {
+	Paths            []string `yaml:"paths"` // path prefixes,e.g. /api/v1/logo
+	AllowOrigins     []string `yaml:"allow_origins"`
+	AllowMethods     []string `yaml:"allow_methods"`
+	AllowHeaders     []string `yaml:"allow_headers"`
+	ExposeHeaders    []string `yaml:"expose_headers"`
+	MaxAge           *int     `yaml:"max_age"`
+	AllowCredentials *bool    `yaml:"allow_credentials"`
+}
+
 func (r *redisModel) Str() string {
 	return fmt.Sprintf("%s:%d", r.Address, r.Port)
 }
 
 func (r *redisModel) Initer() error {
+	if !r.Enable {
+		return nil
+	}
 	rc = redis.NewClient(&redis.Options{
 		Addr:     r.Str(),
 		Password: r.Password,
@@ -77,14 +188,22 @@ func (r *redisModel) Initer() error {
 	return nil
 }
 
+// Conn returns nil when redis is disabled
 func (r *redisModel) Conn() *redis.Client {
 	return rc
 }
 
 func (l *redisModel) Close() error {
+	if rc == nil {
+		return nil
+	}
 	return rc.Close()
 }
 
+func (s *smtpConfig) Str() string {
+	return fmt.Sprintf("%s:%d", s.Host, s.Port)
+}
+
 func (r *serverModel) Str() string {
 	return fmt.Sprintf("%s:%d", r.Host, r.Port)
 }
@@ -100,13 +219,34 @@ func (d *databaseModel) Close() error {
 		}
 		pgdb = nil
 	}
+	for _, r := range replicaDBs {
+		if err := r.Close(); err != nil {
+			return err
+		}
+	}
+	replicaDBs = nil
 	return nil
 }
 
+// GetStatementTimeout returns the max duration of the request queries,0 means no limit
+func (d *databaseModel) GetStatementTimeout() time.Duration {
+	return time.Duration(d.StatementTimeout) * time.Millisecond
+}
+
+// Conn returns the connection that sends the reads to the replicas when there are some
 func (d *databaseModel) Conn() *gorm.DB {
 	return pgdb
 }
 
+// Primary returns the connection pinned to the primary,it is used by the sync writers and every
+// query that must see the latest writes
+func (d *databaseModel) Primary() *gorm.DB {
+	if pgdb == nil || len(replicaDBs) == 0 {
+		return pgdb
+	}
+	return pgdb.Clauses(dbresolver.Write).Session(&gorm.Session{})
+}
+
 func (c *centrifugoConfig) Initer() error {
 	if c.Enable {
 		publisher = gocent.New(gocent.Config{
@@ -0,0 +1,199 @@
+package conf
+
+import (
+	"fmt"
+	"github.com/robfig/cron/v3"
+	"github.com/sirupsen/logrus"
+	"net/url"
+	"os"
+	"strings"
+)
+
+// CronParser parses the crontab schedules,the first field is the second
+var CronParser = cron.NewParser(cron.Second | cron.Minute |
+	cron.Hour | cron.Dom | cron.Month | cron.DowOptional | cron.Descriptor)
+
+type validator struct {
+	errs []string
+}
+
+func (v *validator) check(ok bool, field, format string, args ...any) {
+	if !ok {
+		v.errs = append(v.errs, field+": "+fmt.Sprintf(format, args...))
+	}
+}
+
+func (v *validator) port(field string, port int) {
+	v.check(port > 0 && port <= 65535, field, "must be between 1 and 65535,got %d", port)
+}
+
+func (v *validator) err() error {
+	if len(v.errs) == 0 {
+		return nil
+	}
+	return fmt.Errorf("invalid config:\n  %s", strings.Join(v.errs, "\n  "))
+}
+
+// Validate reports every invalid field of the config at once
+func (c *EnvConf) Validate() error {
+	v := &validator{}
+	if c.ServerInfo == nil {
+		v.check(false, "server", "section is required")
+	} else {
+		s := c.ServerInfo
+		v.check(s.Mode == "" || s.Mode == "debug" || s.Mode == "release" || s.Mode == "test", "server.mode", "must be debug,release or test,got %q", s.Mode)
+		v.check(s.Host != "", "server.host", "is required")
+		v.port("server.port", s.Port)
+		v.check(s.RateLimit >= 0, "server.rate_limit", "must not be negative")
+		if s.PublicUrl != "" {
+			u, err := url.Parse(s.PublicUrl)
+			v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.RawQuery == "",
+				"server.public_url", "must be an http(s) url like https://api.example.com,got %q", s.PublicUrl)
+		}
+		if s.EnableHttps {
+			v.check(fileExists(s.CertFile), "server.cert_file", "file %q not found", s.CertFile)
+			v.check(fileExists(s.KeyFile), "server.key_file", "file %q not found", s.KeyFile)
+		}
+	}
+
+	if c.DatabaseInfo == nil {
+		v.check(false, "database", "section is required")
+	} else {
+		d := c.DatabaseInfo
+		v.check(d.Enable, "database.enable", "must be true,the server can't run without postgres")
+		v.check(d.DBType == "" || d.DBType == "postgres", "database.type", "only postgres is supported,got %q", d.DBType)
+		v.check(d.Connect != "", "database.connect", "is required")
+		v.check(d.MaxIdle >= 0, "database.max_idle", "must not be negative")
+		v.check(d.MaxOpen >= 0, "database.max_open", "must not be negative")
+		v.check(d.MaxOpen == 0 || d.MaxIdle <= d.MaxOpen, "database.max_idle", "must not be greater than max_open")
+		for i, r := range d.Replicas {
+			v.check(strings.TrimSpace(r) != "", fmt.Sprintf("database.replicas[%d]", i), "must not be empty")
+			v.check(r != d.Connect, fmt.Sprintf("database.replicas[%d]", i), "must not be the primary connect")
+		}
+		v.check(d.StatementTimeout >= 0, "database.statement_timeout", "must not be negative")
+	}
+
+	if r := c.RedisInfo; r != nil && r.Enable {
+		v.check(r.Address != "", "redis.address", "is required")
+		v.port("redis.port", r.Port)
+		v.check(r.Db >= 0, "redis.db", "must not be negative")
+	}
+
+	if ch := c.Cache; ch != nil {
+		v.check(ch.Backend == "" || ch.Backend == "redis" || ch.Backend == "memory", "cache.backend", "must be redis or memory,got %q", ch.Backend)
+		v.check(ch.Backend != "redis" || (c.RedisInfo != nil && c.RedisInfo.Enable), "cache.backend", "redis requires redis.enable")
+		v.check(ch.MaxKeys >= 0, "cache.max_keys", "must not be negative")
+	}
+
+	if ce := c.Centrifugo; ce != nil && ce.Enable {
+		v.check(ce.URL != "", "centrifugo.url", "is required")
+		v.check(ce.Secret != "", "centrifugo.secret", "is required")
+		v.check(ce.Key != "", "centrifugo.key", "is required")
+	}
+
+	if ct := c.Crontab; ct != nil {
+		_, err := CronParser.Parse(ct.RealTime)
+		v.check(err == nil, "crontab.real_time", "%v", err)
+		_, err = CronParser.Parse(ct.Delay)
+		v.check(err == nil, "crontab.delay", "%v", err)
+	}
+
+	if b := c.Backfill; b != nil {
+		v.check(b.MinBlocks >= 0, "backfill.min_blocks", "must not be negative")
+		v.check(b.Workers >= 0, "backfill.workers", "must not be negative")
+		v.check(b.ChunkBlocks >= 0, "backfill.chunk_blocks", "must not be negative")
+		v.check(b.BatchRows >= 0, "backfill.batch_rows", "must not be negative")
+	}
+
+	if r := c.Reconcile; r != nil && r.Enable {
+		v.check(r.SampleSize >= 0, "reconcile.sample_size", "must not be negative")
+		v.check(r.SampleInterval >= 0, "reconcile.sample_interval", "must not be negative")
+		v.check(r.FullInterval >= 0, "reconcile.full_interval", "must not be negative")
+	}
+
+	if a := c.Alert; a != nil && a.Enable {
+		v.check(a.Workers >= 0, "alert.workers", "must not be negative")
+		v.check(a.MaxRetries >= 0, "alert.max_retries", "must not be negative")
+		v.check(a.RetryBackoff >= 0, "alert.retry_backoff", "must not be negative")
+		v.check(a.WebhookTimeout >= 0, "alert.webhook_timeout", "must not be negative")
+		if a.Smtp.Host != "" {
+			v.port("alert.smtp.port", a.Smtp.Port)
+		}
+	}
+
+	if ct := c.Content; ct != nil {
+		v.check(ct.Backend == "" || ct.Backend == "local" || ct.Backend == "s3", "content.backend", "must be local or s3,got %q", ct.Backend)
+		v.check(ct.CacheSize >= 0, "content.cache_size", "must not be negative")
+		if ct.Backend == "s3" {
+			v.check(ct.S3.Endpoint != "", "content.s3.endpoint", "is required")
+			v.check(ct.S3.Bucket != "", "content.s3.bucket", "is required")
+		}
+	}
+
+	if cr := c.Cors; cr != nil {
+		v.cors("cors", cr.AllowOrigins, cr.AllowMethods, cr.AllowCredentials)
+		v.check(cr.MaxAge >= 0, "cors.max_age", "must not be negative")
+		for i, g := range cr.Groups {
+			field := fmt.Sprintf("cors.groups[%d]", i)
+			v.check(len(g.Paths) > 0, field+".paths", "is required")
+			for _, p := range g.Paths {
+				v.check(strings.HasPrefix(p, "/"), field+".paths", "%q must start with /", p)
+			}
+			origins, credentials := g.AllowOrigins, cr.AllowCredentials
+			if len(origins) == 0 {
+				origins = cr.AllowOrigins
+			}
+			if g.AllowCredentials != nil {
+				credentials = *g.AllowCredentials
+			}
+			v.cors(field, origins, g.AllowMethods, credentials)
+			v.check(g.MaxAge == nil || *g.MaxAge >= 0, field+".max_age", "must not be negative")
+		}
+	}
+
+	if t := c.Tracing; t != nil && t.Enable {
+		v.check(t.Exporter == "otlp" || t.Exporter == "stdout" || t.Exporter == "file", "tracing.exporter", "must be otlp,stdout or file,got %q", t.Exporter)
+		if t.Exporter == "otlp" {
+			v.check(t.Protocol == "" || t.Protocol == "grpc" || t.Protocol == "http", "tracing.protocol", "must be grpc or http,got %q", t.Protocol)
+			v.check(t.Endpoint != "", "tracing.endpoint", "is required")
+		}
+		v.check(t.SampleRatio >= 0 && t.SampleRatio <= 1, "tracing.sample_ratio", "must be between 0 and 1")
+	}
+
+	if l := c.Log; l != nil {
+		if l.Level != "" {
+			_, err := logrus.ParseLevel(l.Level)
+			v.check(err == nil, "log.level", "%v", err)
+		}
+		v.check(l.Format == "" || l.Format == "json" || l.Format == "text", "log.format", "must be json or text,got %q", l.Format)
+		v.check(l.Output == "" || l.Output == "stdout" || l.Output == "file", "log.output", "must be stdout or file,got %q", l.Output)
+		v.check(l.MaxSize >= 0, "log.max_size", "must not be negative")
+		v.check(l.MaxAge >= 0, "log.max_age", "must not be negative")
+		v.check(l.MaxBackups >= 0, "log.max_backups", "must not be negative")
+		v.check(l.SlowSql >= 0, "log.slow_sql", "must not be negative")
+	}
+
+	v.check(c.CryptoSettings.Cryptoer != "", "crypto_settings.cryptoer", "is required")
+	v.check(c.CryptoSettings.Hasher != "", "crypto_settings.hasher", "is required")
+	return v.err()
+}
+
+func (v *validator) cors(field string, origins, methods []string, credentials bool) {
+	for _, o := range origins {
+		if o == "*" {
+			v.check(!credentials, field+".allow_origins", `"*" can't be used with allow_credentials`)
+			continue
+		}
+		u, err := url.Parse(strings.Replace(o, "://*.", "://wildcard.", 1))
+		v.check(err == nil && (u.Scheme == "http" || u.Scheme == "https") && u.Host != "" && u.Path == "" &&
+			!strings.Contains(u.Host, "*"), field+".allow_origins", "%q must be like https://example.com or https://*.example.com", o)
+	}
+	for _, m := range methods {
+		v.check(m != "" && strings.ToUpper(m) == m && !strings.ContainsAny(m, " ,"), field+".allow_methods", "%q must be an upper case method", m)
+	}
+}
+
+func fileExists(name string) bool {
+	info, err := os.Stat(name)
+	return err == nil && !info.IsDir()
+}
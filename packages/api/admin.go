@@ -0,0 +1,46 @@
+package api
+
+import (
+	"crypto/subtle"
+	"github.com/gin-gonic/gin"
+	"jutkey-server/conf"
+	"jutkey-server/packages/params"
+	"jutkey-server/packages/storage/sql"
+	"net/http"
+	"strings"
+)
+
+// AdminAuth requires server.admin_token as a bearer token,every request is denied when it's empty
+func AdminAuth() gin.HandlerFunc {
+	token := []byte(conf.GetEnvConf().ServerInfo.AdminToken)
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		bearer := strings.TrimPrefix(auth, "Bearer ")
+		if len(token) == 0 || bearer == auth || subtle.ConstantTimeCompare([]byte(bearer), token) != 1 {
+			ret := &Response{}
+			ret.Return(nil, CodePermissionDenied)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ret)
+			return
+		}
+		c.Next()
+	}
+}
+
+func getBalanceDiscrepanciesHandler(c *gin.Context) {
+	req := &params.BalanceDiscrepancyRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetBalanceDiscrepancies(c.Request.Context(), req.Kind, req.Resolved, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
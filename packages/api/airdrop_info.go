@@ -29,7 +29,7 @@ func GetAirdropInfoHandler(c *gin.Context) {
 		return
 	}
 	info.Account = wallet
-	rets, err = info.GetAirdropInfo()
+	rets, err = info.GetAirdropInfo(c.Request.Context())
 	if err != nil {
 		ret.ReturnFailureString(fmt.Sprintf("get airdrop info failed:%s", err.Error()))
 		JsonResponse(c, ret)
@@ -60,7 +60,7 @@ func GetAirdropBalanceHandler(c *gin.Context) {
 	}
 	var info = &sql.AirdropInfo{}
 	info.Account = wallet
-	rets, err = info.GetAirdropBalance()
+	rets, err = info.GetAirdropBalance(c.Request.Context())
 	if err != nil {
 		ret.ReturnFailureString(fmt.Sprintf("get airdrop balance failed:%s", err.Error()))
 		JsonResponse(c, ret)
@@ -70,3 +70,35 @@ func GetAirdropBalanceHandler(c *gin.Context) {
 	JsonResponse(c, ret)
 
 }
+
+func GetAirdropScheduleHandler(c *gin.Context) {
+	wallet := c.Param("wallet")
+
+	ret := &Response{}
+	keyId := converter.StringToAddress(wallet)
+	if keyId == 0 {
+		ret.Return(nil, CodeRequestformat.Errorf(errors.New("request params wallet invalid:"+wallet)))
+		JsonResponse(c, ret)
+		return
+	}
+	if !sql.AirdropReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	var info = &sql.AirdropInfo{}
+	info.Account = wallet
+	rets, err := info.GetAirdropSchedule(c.Request.Context())
+	if err != nil {
+		ret.ReturnFailureString(fmt.Sprintf("get airdrop schedule failed:%s", err.Error()))
+		JsonResponse(c, ret)
+		return
+	}
+	if rets == nil {
+		ret.Return(nil, CodeRecordNotExists)
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -0,0 +1,155 @@
+package api
+
+import (
+	"github.com/IBAX-io/go-ibax/packages/consts"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/params"
+	"jutkey-server/packages/storage/sql"
+)
+
+type alertRuleCreated struct {
+	Id     int64  `json:"id"`
+	Secret string `json:"secret,omitempty"`
+}
+
+func createAlertRuleHandler(c *gin.Context) {
+	req := &params.AlertRuleRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !authorizedWallet(c, req.Wallet) {
+		ret.Return(nil, CodePermissionDenied)
+		JsonResponse(c, ret)
+		return
+	}
+	rule := &sql.AlertRule{
+		Wallet:    req.Wallet,
+		Ecosystem: req.Ecosystem,
+		EventType: req.EventType,
+		Threshold: req.Threshold,
+		Channel:   req.Channel,
+		Target:    req.Target,
+		Secret:    req.Secret,
+	}
+	if err = rule.Create(); err != nil {
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err, "wallet": req.Wallet}).Error("create alert rule failed")
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(alertRuleCreated{Id: rule.ID, Secret: rule.Secret}, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getAlertRulesHandler(c *gin.Context) {
+	req := &params.WalletPageRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !authorizedWallet(c, req.Wallet) {
+		ret.Return(nil, CodePermissionDenied)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetAlertRules(c.Request.Context(), req.Wallet, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func deleteAlertRuleHandler(c *gin.Context) {
+	req := &params.AlertRuleIdRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !authorizedWallet(c, req.Wallet) {
+		ret.Return(nil, CodePermissionDenied)
+		JsonResponse(c, ret)
+		return
+	}
+	var rule sql.AlertRule
+	f, err := rule.Delete(req.Id, req.Wallet)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	if !f {
+		ret.Return(nil, CodeRecordNotExists)
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(nil, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func enableAlertRuleHandler(c *gin.Context) {
+	req := &params.AlertRuleIdRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !authorizedWallet(c, req.Wallet) {
+		ret.Return(nil, CodePermissionDenied)
+		JsonResponse(c, ret)
+		return
+	}
+	var rule sql.AlertRule
+	f, err := rule.SetEnabled(req.Id, req.Wallet, req.Enabled)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	if !f {
+		ret.Return(nil, CodeRecordNotExists)
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(nil, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getAlertDeadLettersHandler(c *gin.Context) {
+	req := &params.WalletPageRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !authorizedWallet(c, req.Wallet) {
+		ret.Return(nil, CodePermissionDenied)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetAlertDeadLetters(c.Request.Context(), req.Wallet, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -22,7 +22,7 @@ func getMyAssignBalanceHandler(c *gin.Context) {
 	ret := &Response{}
 	keyId := converter.StringToAddress(wallet)
 	if keyId == 0 {
-		log.WithFields(log.Fields{"type": consts.ConversionError, "value": wallet}).Error("assign balance converting wallet to address")
+		logger(c).WithFields(log.Fields{"type": consts.ConversionError, "value": wallet}).Error("assign balance converting wallet to address")
 		ret.Return(nil, CodeRequestformat.Errorf(errors.New("request params wallet invalid:"+wallet)))
 		JsonResponse(c, ret)
 		return
@@ -34,9 +34,9 @@ func getMyAssignBalanceHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	show, balance, totalBalance, err := assign.GetBalance(nil, wallet)
+	show, balance, totalBalance, err := assign.GetBalance(c.Request.Context(), wallet)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("assign get balance")
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("assign get balance")
 		if err == gorm.ErrRecordNotFound {
 			ret.Return(nil, CodeSuccess)
 		} else {
@@ -57,6 +57,28 @@ func getMyAssignBalanceHandler(c *gin.Context) {
 	JsonResponse(c, ret)
 }
 
+func getAssignScheduleHandler(c *gin.Context) {
+	wallet := c.Param("wallet")
+
+	ret := &Response{}
+	keyId := converter.StringToAddress(wallet)
+	if keyId == 0 {
+		logger(c).WithFields(log.Fields{"type": consts.ConversionError, "value": wallet}).Error("assign schedule converting wallet to address")
+		ret.Return(nil, CodeRequestformat.Errorf(errors.New("request params wallet invalid:"+wallet)))
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetAssignSchedule(c.Request.Context(), wallet)
+	if err != nil {
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("get assign schedule")
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
 func getKeyInfoHandler(c *gin.Context) {
 	var found bool
 	keysList := make([]*sql.KeyEcosystemInfo, 0)
@@ -65,7 +87,7 @@ func getKeyInfoHandler(c *gin.Context) {
 
 	keyid := converter.StringToAddress(c.Param("account"))
 	if keyid == 0 {
-		log.WithFields(log.Fields{"type": consts.ConversionError, "value": c.Param("account")}).Error("converting wallet to address")
+		logger(c).WithFields(log.Fields{"type": consts.ConversionError, "value": c.Param("account")}).Error("converting wallet to address")
 		ret.Return(nil, CodeRequestformat.Errorf(errors.New("account params invalid:"+c.Param("account"))))
 		JsonResponse(c, ret)
 		return
@@ -107,7 +129,7 @@ func getKeyInfoHandler(c *gin.Context) {
 		for _, r := range roles {
 			var role sql.RoleInfo
 			if err := json.Unmarshal([]byte(r.Role), &role); err != nil {
-				log.WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling role")
+				logger(c).WithFields(log.Fields{"type": consts.JSONUnmarshallError, "error": err}).Error("unmarshalling role")
 				ret.Return(nil, CodeJsonformaterr.Errorf(err))
 				JsonResponse(c, ret)
 				return
@@ -116,7 +138,7 @@ func getKeyInfoHandler(c *gin.Context) {
 		}
 		keyRes.Notifications, err = sql.GetNotifications(ecosystemID, key)
 		if err != nil {
-			log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting notifications")
+			logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting notifications")
 			ret.Return(nil, CodeJsonformaterr.Errorf(err))
 			JsonResponse(c, ret)
 			return
@@ -165,12 +187,12 @@ func getUtxoInputHandler(c *gin.Context) {
 	}
 	keyId := converter.StringToAddress(req.Wallet)
 	if keyId == 0 {
-		log.WithFields(log.Fields{"type": consts.ConversionError, "wallet": req.Wallet}).Error("converting wallet to address")
+		logger(c).WithFields(log.Fields{"type": consts.ConversionError, "wallet": req.Wallet}).Error("converting wallet to address")
 		ret.Return(nil, CodeRequestformat.Errorf(errors.New("wallet params invalid:"+req.Wallet)))
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := sql.GetUtxoInput(keyId, req.Search)
+	rets, err := sql.GetUtxoInput(c.Request.Context(), keyId, req.Search)
 	if err != nil {
 		ret.Return(nil, CodeDBfinderr.Errorf(err))
 		JsonResponse(c, ret)
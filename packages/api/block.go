@@ -0,0 +1,70 @@
+package api
+
+import (
+	"errors"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/gin-gonic/gin"
+	"jutkey-server/packages/storage/sql"
+)
+
+type blockTimeResult struct {
+	Block     int64   `json:"block"`
+	Time      int64   `json:"time"`
+	Estimated bool    `json:"estimated"`
+	BlockTime float64 `json:"block_time"` //average seconds per block
+}
+
+func getBlockTimeHandler(c *gin.Context) {
+	ret := &Response{}
+	blockId := converter.StrToInt64(c.Query("block"))
+	if blockId <= 0 {
+		ret.ReturnFailureString("request params block invalid:" + c.Query("block"))
+		JsonResponse(c, ret)
+		return
+	}
+	estimator := sql.GetBlockTimeEstimator()
+	t, estimated, err := estimator.TimeOf(c.Request.Context(), blockId)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	_, avg, err := estimator.Latest(c.Request.Context())
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(blockTimeResult{Block: blockId, Time: t, Estimated: estimated, BlockTime: avg}, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getBlockAtHandler(c *gin.Context) {
+	ret := &Response{}
+	t := converter.StrToInt64(c.Query("time"))
+	if t <= 0 {
+		ret.ReturnFailureString("request params time invalid:" + c.Query("time"))
+		JsonResponse(c, ret)
+		return
+	}
+	estimator := sql.GetBlockTimeEstimator()
+	blockId, estimated, err := estimator.BlockAt(c.Request.Context(), t)
+	if errors.Is(err, sql.ErrBeforeFirstBlock) {
+		ret.ReturnFailureString("request params time before the first block:" + c.Query("time"))
+		JsonResponse(c, ret)
+		return
+	}
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	_, avg, err := estimator.Latest(c.Request.Context())
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(blockTimeResult{Block: blockId, Time: t, Estimated: estimated, BlockTime: avg}, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -2,7 +2,10 @@ package api
 
 import (
 	"github.com/gin-gonic/gin"
+	"jutkey-server/conf"
+	"jutkey-server/packages/consts"
 	"net/http"
+	"strings"
 )
 
 func JsonResponse(c *gin.Context, body *Response) {
@@ -17,3 +20,13 @@ func PureJsonResponse(c *gin.Context, body *Response) {
 func IndentedJsonResponse(c *gin.Context, body any) {
 	c.IndentedJSON(http.StatusOK, body)
 }
+
+// apiUrl returns the url of an api path,absolute when server.public_url is set.
+// The request host and X-Forwarded-Proto are not used,they're chosen by the client
+func apiUrl(path string) string {
+	base := ""
+	if s := conf.GetEnvConf().ServerInfo; s != nil {
+		base = strings.TrimSuffix(s.PublicUrl, "/")
+	}
+	return base + consts.ApiPath + path
+}
@@ -0,0 +1,190 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	"jutkey-server/conf"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"sync/atomic"
+)
+
+var (
+	defaultCorsMethods = []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"}
+	defaultCorsHeaders = []string{"Authorization", "Content-Length", "X-CSRF-Token", "Accept", "Origin", "Host", "Connection",
+		"Accept-Encoding", "Accept-Language", "DNT", "X-CustomHeader", "Keep-Alive", "User-Agent", "X-Requested-With",
+		"If-Modified-Since", "If-None-Match", "Range", "Cache-Control", "Content-Type", "Pragma"}
+	defaultCorsExpose = []string{"Content-Length", "Content-Type", "Content-Range", "ETag"}
+
+	corsRules atomic.Value //*corsRuleSet
+)
+
+type corsPolicy struct {
+	anyOrigin   bool
+	origins     map[string]bool
+	wildcards   [][2]string //scheme,host suffix
+	methods     string
+	headers     string
+	expose      string
+	maxAge      string
+	credentials bool
+}
+
+type corsRule struct {
+	prefix string
+	policy *corsPolicy
+}
+
+type corsRuleSet struct {
+	def   *corsPolicy
+	rules []corsRule //the longest prefix first
+}
+
+func newCorsPolicy(origins, methods, headers, expose []string, maxAge int, credentials bool) *corsPolicy {
+	if len(origins) == 0 {
+		origins = []string{"*"}
+	}
+	if len(methods) == 0 {
+		methods = defaultCorsMethods
+	}
+	if len(headers) == 0 {
+		headers = defaultCorsHeaders
+	}
+	if len(expose) == 0 {
+		expose = defaultCorsExpose
+	}
+	p := &corsPolicy{
+		origins:     make(map[string]bool),
+		methods:     strings.Join(methods, ", "),
+		headers:     strings.Join(headers, ", "),
+		expose:      strings.Join(expose, ", "),
+		credentials: credentials,
+	}
+	if maxAge > 0 {
+		p.maxAge = strconv.Itoa(maxAge)
+	}
+	for _, o := range origins {
+		o = strings.ToLower(strings.TrimSuffix(o, "/"))
+		if o == "*" {
+			p.anyOrigin = true
+		} else if i := strings.Index(o, "://*."); i > 0 {
+			p.wildcards = append(p.wildcards, [2]string{o[:i+3], o[i+4:]})
+		} else {
+			p.origins[o] = true
+		}
+	}
+	return p
+}
+
+// allowOrigin returns the Access-Control-Allow-Origin value of origin,empty if it's not allowed
+func (p *corsPolicy) allowOrigin(origin string) string {
+	if p.anyOrigin {
+		return "*"
+	}
+	o := strings.ToLower(origin)
+	if p.origins[o] {
+		return origin
+	}
+	for _, w := range p.wildcards {
+		//https://*.example.com matches https://a.example.com but not https://example.com
+		if strings.HasPrefix(o, w[0]) && strings.HasSuffix(o, w[1]) && len(o) > len(w[0])+len(w[1]) &&
+			!strings.Contains(o[len(w[0]):], "/") {
+			return origin
+		}
+	}
+	return ""
+}
+
+func newCorsRuleSet(c *conf.EnvConf) *corsRuleSet {
+	cr := c.Cors
+	if cr == nil {
+		return &corsRuleSet{def: newCorsPolicy(nil, nil, nil, nil, 0, false)}
+	}
+	set := &corsRuleSet{def: newCorsPolicy(cr.AllowOrigins, cr.AllowMethods, cr.AllowHeaders, cr.ExposeHeaders, cr.MaxAge, cr.AllowCredentials)}
+	for _, g := range cr.Groups {
+		origins, methods, headers, expose := cr.AllowOrigins, cr.AllowMethods, cr.AllowHeaders, cr.ExposeHeaders
+		maxAge, credentials := cr.MaxAge, cr.AllowCredentials
+		if len(g.AllowOrigins) > 0 {
+			origins = g.AllowOrigins
+		}
+		if len(g.AllowMethods) > 0 {
+			methods = g.AllowMethods
+		}
+		if len(g.AllowHeaders) > 0 {
+			headers = g.AllowHeaders
+		}
+		if len(g.ExposeHeaders) > 0 {
+			expose = g.ExposeHeaders
+		}
+		if g.MaxAge != nil {
+			maxAge = *g.MaxAge
+		}
+		if g.AllowCredentials != nil {
+			credentials = *g.AllowCredentials
+		}
+		policy := newCorsPolicy(origins, methods, headers, expose, maxAge, credentials)
+		for _, p := range g.Paths {
+			set.rules = append(set.rules, corsRule{prefix: strings.TrimSuffix(p, "/"), policy: policy})
+		}
+	}
+	sort.SliceStable(set.rules, func(i, j int) bool { return len(set.rules[i].prefix) > len(set.rules[j].prefix) })
+	return set
+}
+
+func (s *corsRuleSet) match(path string) *corsPolicy {
+	for _, r := range s.rules {
+		if path == r.prefix || strings.HasPrefix(path, r.prefix+"/") {
+			return r.policy
+		}
+	}
+	return s.def
+}
+
+func setCorsRules(c *conf.EnvConf) {
+	corsRules.Store(newCorsRuleSet(c))
+}
+
+// Cors applies the cors policy of the request path,preflight requests are answered here
+// and don't reach the handlers
+func Cors() gin.HandlerFunc {
+	setCorsRules(conf.GetEnvConf())
+	conf.OnReload(setCorsRules)
+	return func(c *gin.Context) {
+		origin := c.Request.Header.Get("Origin")
+		if origin == "" {
+			c.Next()
+			return
+		}
+		p := corsRules.Load().(*corsRuleSet).match(c.Request.URL.Path)
+		preflight := c.Request.Method == http.MethodOptions && c.Request.Header.Get("Access-Control-Request-Method") != ""
+		h := c.Writer.Header()
+		h.Add("Vary", "Origin")
+		allowOrigin := p.allowOrigin(origin)
+		if allowOrigin == "" {
+			if preflight {
+				c.AbortWithStatus(http.StatusForbidden)
+				return
+			}
+			c.Next()
+			return
+		}
+		h.Set("Access-Control-Allow-Origin", allowOrigin)
+		if p.credentials {
+			h.Set("Access-Control-Allow-Credentials", "true")
+		}
+		if preflight {
+			h.Add("Vary", "Access-Control-Request-Method")
+			h.Add("Vary", "Access-Control-Request-Headers")
+			h.Set("Access-Control-Allow-Methods", p.methods)
+			h.Set("Access-Control-Allow-Headers", p.headers)
+			if p.maxAge != "" {
+				h.Set("Access-Control-Max-Age", p.maxAge)
+			}
+			c.AbortWithStatus(http.StatusNoContent)
+			return
+		}
+		h.Set("Access-Control-Expose-Headers", p.expose)
+		c.Next()
+	}
+}
@@ -0,0 +1,138 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"gopkg.in/yaml.v2"
+	"jutkey-server/conf"
+	"jutkey-server/packages/storage/sql"
+)
+
+const testCorsConfig = `
+cors:
+  allow_origins: [https://wallet.example.com, "https://*.example.org"]
+  allow_methods: [GET, POST, OPTIONS]
+  max_age: 600
+  allow_credentials: true
+  groups:
+    - paths: [/api/v1/logo]
+      allow_origins: ["*"]
+      allow_credentials: false
+    - paths: [/api/v1/nft_miner_file]
+      allow_origins: [https://market.example.com]
+      allow_credentials: false
+`
+
+func corsTestRouter(t *testing.T) *gin.Engine {
+	var cfg conf.EnvConf
+	if err := yaml.Unmarshal([]byte(testCorsConfig), &cfg); err != nil {
+		t.Fatal(err)
+	}
+	gin.SetMode(gin.TestMode)
+	r := gin.New()
+	r.Use(Cors())
+	setCorsRules(&cfg)
+	ok := func(c *gin.Context) { c.String(http.StatusOK, "ok") }
+	r.GET("/api/v1/logo/:id", ok)
+	r.GET("/api/v1/logos", ok)
+	r.GET("/api/v1/block", ok)
+	r.GET("/api/v1/nft_miner_file/:id", func(c *gin.Context) {
+		writeNftMinerImage(c, &sql.NftMinerImage{Data: []byte("<svg/>"), ContentType: "image/svg+xml;utf8", ETag: `"1"`})
+	})
+	return r
+}
+
+func corsRequest(r *gin.Engine, method, path, origin string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, path, nil)
+	req.Header.Set("Origin", origin)
+	if method == http.MethodOptions {
+		req.Header.Set("Access-Control-Request-Method", http.MethodGet)
+	}
+	w := httptest.NewRecorder()
+	r.ServeHTTP(w, req)
+	return w
+}
+
+func TestCorsExactOrigin(t *testing.T) {
+	r := corsTestRouter(t)
+	w := corsRequest(r, http.MethodGet, "/api/v1/block", "https://wallet.example.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "https://wallet.example.com" {
+		t.Errorf("allowed origin: %d %v", w.Code, w.Header())
+	}
+	w = corsRequest(r, http.MethodGet, "/api/v1/block", "https://evil.com")
+	if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("other origin: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCorsWildcard(t *testing.T) {
+	r := corsTestRouter(t)
+	for origin, allowed := range map[string]bool{
+		"https://a.example.org":     true,
+		"https://a.b.example.org":   true,
+		"https://example.org":       false,
+		"http://a.example.org":      false,
+		"https://a.example.org.com": false,
+	} {
+		w := corsRequest(r, http.MethodGet, "/api/v1/block", origin)
+		if got := w.Header().Get("Access-Control-Allow-Origin") == origin; got != allowed {
+			t.Errorf("%s allowed %v, want %v", origin, got, allowed)
+		}
+	}
+}
+
+func TestCorsPreflight(t *testing.T) {
+	r := corsTestRouter(t)
+	w := corsRequest(r, http.MethodOptions, "/api/v1/block", "https://wallet.example.com")
+	if w.Code != http.StatusNoContent || w.Header().Get("Access-Control-Allow-Methods") != "GET, POST, OPTIONS" ||
+		w.Header().Get("Access-Control-Max-Age") != "600" {
+		t.Errorf("allowed preflight: %d %v", w.Code, w.Header())
+	}
+	w = corsRequest(r, http.MethodOptions, "/api/v1/block", "https://evil.com")
+	if w.Code != http.StatusForbidden || w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("rejected preflight: %d %v", w.Code, w.Header())
+	}
+}
+
+func TestCorsGroupPrefix(t *testing.T) {
+	r := corsTestRouter(t)
+	w := corsRequest(r, http.MethodGet, "/api/v1/logo/1", "https://evil.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "*" || w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("group path: %v", w.Header())
+	}
+	//a prefix only matches whole path segments
+	w = corsRequest(r, http.MethodGet, "/api/v1/logos", "https://evil.com")
+	if w.Header().Get("Access-Control-Allow-Origin") != "" {
+		t.Errorf("path outside the group: %v", w.Header())
+	}
+}
+
+func TestCorsCredentials(t *testing.T) {
+	r := corsTestRouter(t)
+	w := corsRequest(r, http.MethodGet, "/api/v1/block", "https://wallet.example.com")
+	if w.Header().Get("Access-Control-Allow-Credentials") != "true" {
+		t.Errorf("credentials not allowed: %v", w.Header())
+	}
+	w = corsRequest(r, http.MethodGet, "/api/v1/block", "https://evil.com")
+	if w.Header().Get("Access-Control-Allow-Credentials") != "" {
+		t.Errorf("credentials allowed for a rejected origin: %v", w.Header())
+	}
+}
+
+func TestCorsNftMinerFile(t *testing.T) {
+	r := corsTestRouter(t)
+	//the image handler doesn't open the route to every origin itself
+	for origin, want := range map[string]string{
+		"https://market.example.com": "https://market.example.com",
+		"https://wallet.example.com": "",
+		"https://evil.com":           "",
+	} {
+		w := corsRequest(r, http.MethodGet, "/api/v1/nft_miner_file/1", origin)
+		if w.Code != http.StatusOK || w.Header().Get("Access-Control-Allow-Origin") != want {
+			t.Errorf("%s: %d %v, want origin %q", origin, w.Code, w.Header(), want)
+		}
+	}
+}
@@ -0,0 +1,107 @@
+package api
+
+import (
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/gin-gonic/gin"
+	"jutkey-server/packages/params"
+	"jutkey-server/packages/storage/sql"
+)
+
+func getDaoProposalsHandler(c *gin.Context) {
+	ret := &Response{}
+	req := &params.DaoProposalsRequest{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetDaoProposals(c.Request.Context(), req.Status, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getDaoProposalHandler(c *gin.Context) {
+	ret := &Response{}
+	id := converter.StrToInt64(c.Param("id"))
+	if id <= 0 {
+		ret.ReturnFailureString("request params proposal id invalid")
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetDaoProposal(c.Request.Context(), id)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getDaoProposalVotersHandler(c *gin.Context) {
+	ret := &Response{}
+	req := &params.DaoProposalVotersRequest{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetDaoProposalVoters(c.Request.Context(), req.Id, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getDaoWalletVotesHandler(c *gin.Context) {
+	ret := &Response{}
+	req := &params.WalletPageRequest{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetDaoWalletVotes(c.Request.Context(), req.Wallet, req.Page, req.Limit)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getDaoMembershipChangesHandler(c *gin.Context) {
+	ret := &Response{}
+	req := &params.GeneralRequest{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !sql.NodeReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetDaoMembershipChanges(c.Request.Context(), req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -6,10 +6,13 @@ import (
 	"github.com/IBAX-io/go-ibax/packages/converter"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/consts"
 	"jutkey-server/packages/params"
+	"jutkey-server/packages/storage/content"
 	"jutkey-server/packages/storage/sql"
 	"net/http"
-	"os"
+	"time"
 	"unicode/utf8"
 )
 
@@ -57,7 +60,7 @@ func getAllEcosystemList(c *gin.Context) {
 	list.Limit = req.Limit
 	list.Page = req.Page
 	var eco sql.Ecosystem
-	ecosystems, total, err := eco.GetFind(req.Limit, req.Page, req.Order, req.Where)
+	ecosystems, total, err := eco.GetFind(c.Request.Context(), req.Limit, req.Page, req.Order, req.Where)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -77,7 +80,7 @@ func getAllEcosystemList(c *gin.Context) {
 			}
 
 			var key sql.Key
-			count, err := key.GetEcosystemsKeysCount(ecosystems[i].ID)
+			count, err := key.GetEcosystemsKeysCount(c.Request.Context(), ecosystems[i].ID)
 			if err != nil {
 				ret.ReturnFailureString(err.Error())
 				JsonResponse(c, ret)
@@ -119,7 +122,7 @@ func getEcosystemThroughKey(c *gin.Context) {
 		return
 	}
 	keyid := converter.StringToAddress(req.Wallet)
-	mines, err := data.GetEcosystemsKeyAmount(keyid, req.Page, req.Limit, req.Search, req.Ids)
+	mines, err := data.GetEcosystemsKeyAmount(c.Request.Context(), keyid, req.Page, req.Limit, req.Search, req.Ids)
 	if err != nil {
 		if err.Error() == "record not found" {
 			ret.Return(nil, CodeSuccess)
@@ -152,7 +155,7 @@ func ecosystemSearchHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := sql.EcosystemSearch(req.Search, req.Order, req.Wallet)
+	rets, err := sql.EcosystemSearch(c.Request.Context(), req.Search, req.Order, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -188,57 +191,72 @@ func getAttachmentHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
+	var size int
+	if s := c.Query("size"); s != "" {
+		size = int(converter.StrToInt64(s))
+		if size < content.ThumbnailMinSize || size > content.ThumbnailMaxSize {
+			ret.ReturnFailureString(fmt.Sprintf("Request params size must be between %d and %d", content.ThumbnailMinSize, content.ThumbnailMaxSize))
+			JsonResponse(c, ret)
+			return
+		}
+	}
 
-	fileName, id, err := sql.GetFileNameByHash(hash)
+	bin, err := sql.GetAttachment(hash)
 	if err != nil {
 		ret.ReturnFailureString("Get attachment failed:" + err.Error())
 		JsonResponse(c, ret)
 		return
 	}
-	if fileName == "" {
+	if bin == nil {
 		ret.ReturnFailureString("Get attachment failed:File doesn't not exist")
 		JsonResponse(c, ret)
 		return
 	}
-	//Save the file to the local. If the file does not exist, search for the file from the database
-	if !sql.IsExist(sql.UploadDir + fileName) {
-		fileName, err = sql.LoadFile(id)
-		if err != nil {
-			ret.ReturnFailureString("loadFile failed:" + err.Error())
-			JsonResponse(c, ret)
-			return
-		}
-		if fileName == "" {
-			ret.ReturnFailureString("hash doesn't not exist")
-			JsonResponse(c, ret)
-			return
-		}
-
+	fileName := bin.GetByJpeg()
+	key, contentType := fileName, bin.MimeType
+	if size > 0 {
+		key, contentType = content.ThumbnailKey(hash, size), "image/png"
 	}
 
-	data, err := os.ReadFile(sql.UploadDir + fileName)
+	//serve from the content store,load the binary from the database when it is not stored yet
+	obj, err := content.Default.Open(c, key)
+	if errors.Is(err, content.ErrNotExist) {
+		obj, err = loadAttachment(c, bin, hash, key, contentType, size)
+	}
 	if err != nil {
-		ret.ReturnFailureString("Get attachment read file failed:" + err.Error())
+		ret.ReturnFailureString("Get attachment failed:" + err.Error())
 		JsonResponse(c, ret)
 		return
 	}
+	defer obj.Close()
 
-	if !sql.CompareHash(data, hash) {
-		ret.ReturnFailureString("Hash is incorrect")
-		JsonResponse(c, ret)
-		return
+	if contentType != "" {
+		c.Header("Content-Type", contentType)
 	}
-
-	//c.Header("Content-Type", bin.MimeType)
-	c.Header("Content-Type", http.DetectContentType(data))
 	c.Header("Content-Disposition", fmt.Sprintf(`attachment; filename="%s"`, fileName))
-	c.Header("Access-Control-Allow-Origin", "*")
-	_, err = c.Writer.Write(data)
-	if err != nil {
-		ret.ReturnFailureString("Attachment File Write Error:" + err.Error())
-		JsonResponse(c, ret)
-		return
+	c.Header("Cache-Control", "public, max-age=86400")
+	http.ServeContent(c.Writer, c.Request, fileName, obj.ModTime(), obj)
+}
+
+func loadAttachment(c *gin.Context, bin *sql.Binary, hash, key, contentType string, size int) (content.Object, error) {
+	if err := bin.LoadData(); err != nil {
+		return nil, err
 	}
+	if !sql.CompareHash(bin.Data, hash) {
+		return nil, errors.New("Hash is incorrect")
+	}
+	data := bin.Data
+	if size > 0 {
+		var err error
+		data, err = content.Thumbnail(data, size)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if err := content.Default.Put(c, key, data, contentType); err != nil {
+		logger(c).WithFields(log.Fields{"type": consts.IOError, "error": err, "key": key}).Warn("attachment store failed")
+	}
+	return content.NewBytesObject(data, time.Now()), nil
 }
 
 func getKeyAmountHandler(c *gin.Context) {
@@ -260,7 +278,7 @@ func getKeyAmountHandler(c *gin.Context) {
 		return
 	}
 
-	rets, err := sql.GetKeyAmountByEcosystem(req.Ecosystem, req.Wallet)
+	rets, err := sql.GetKeyAmountByEcosystem(c.Request.Context(), req.Ecosystem, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -269,3 +287,53 @@ func getKeyAmountHandler(c *gin.Context) {
 	ret.Return(&rets, CodeSuccess)
 	JsonResponse(c, ret)
 }
+
+func getMemberProfileHandler(c *gin.Context) {
+	ret := &Response{}
+	ecosystem := converter.StrToInt64(c.Param("ecosystem"))
+	account := c.Param("account")
+	if ecosystem <= 0 {
+		ret.ReturnFailureString("request params ecosystem invalid:" + c.Param("ecosystem"))
+		JsonResponse(c, ret)
+		return
+	}
+	if converter.StringToAddress(account) == 0 {
+		ret.Return(nil, CodeRequestformat.Errorf(errors.New("request params account invalid:"+account)))
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetMemberProfile(c.Request.Context(), ecosystem, account)
+	if err != nil {
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("get member profile")
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	if rets == nil {
+		ret.Return(nil, CodeRecordNotExists)
+		JsonResponse(c, ret)
+		return
+	}
+	rets.SetAvatar(apiUrl("get_attachment/"))
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getMemberDirectoryHandler(c *gin.Context) {
+	req := &params.MemberDirectoryRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.GetMemberDirectory(c.Request.Context(), req.Ecosystem, req.Name, req.Page, req.Limit)
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -21,7 +21,7 @@ func monthHistoryDetailHandler(c *gin.Context) {
 		return
 	}
 	var data sql.History
-	mines, err := data.GetMonthFind(req)
+	mines, err := data.GetMonthFind(c.Request.Context(), req)
 	if err != nil {
 		if err.Error() == "record not found" {
 			ret.Return(nil, CodeSuccess)
@@ -62,7 +62,7 @@ func monthHistoryTotalHandler(c *gin.Context) {
 	}
 	data := sql.History{}
 	var rets sql.WalletMonthHistoryResponse
-	list, err := data.GetWalletMonthHistoryTotals(req.Ecosystem, converter.StringToAddress(req.Wallet), 3)
+	list, err := data.GetWalletMonthHistoryTotals(c.Request.Context(), req.Ecosystem, converter.StringToAddress(req.Wallet), 3)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -85,14 +85,14 @@ func getHistoryHandler(c *gin.Context) {
 		return
 	}
 	var h sql.History
-	rlt, err := h.GetList(req)
+	rlt, err := h.GetList(c.Request.Context(), req)
 	if err != nil {
 		if err.Error() == "opt params invalid" {
 			ret.Return(nil, CodeParam.Errorf(err))
 			JsonResponse(c, ret)
 			return
 		}
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Warn("find history list")
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Warn("find history list")
 		if err == gorm.ErrRecordNotFound {
 			ret.Return(nil, CodeSuccess)
 		} else {
@@ -124,7 +124,7 @@ func getKeyTotalHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rlt, err := sql.GetAccountHistoryTotal(req.Wallet, req.Ecosystem)
+	rlt, err := sql.GetAccountHistoryTotal(c.Request.Context(), req.Wallet, req.Ecosystem)
 	if err != nil {
 		ret.Return(nil, CodeDBfinderr.Errorf(err))
 		JsonResponse(c, ret)
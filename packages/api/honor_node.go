@@ -1,8 +1,10 @@
 package api
 
 import (
+	"github.com/IBAX-io/go-ibax/packages/converter"
 	"github.com/gin-gonic/gin"
 	"github.com/gin-gonic/gin/binding"
+	"github.com/shopspring/decimal"
 	"jutkey-server/packages/params"
 	"jutkey-server/packages/storage/sql"
 )
@@ -14,7 +16,7 @@ func getNodeStatisticsHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := sql.GetNodeStatistics()
+	rets, err := sql.GetNodeStatistics(c.Request.Context())
 	if err != nil {
 		ret.ReturnFailureString("Get Node Statistics Failed")
 		JsonResponse(c, ret)
@@ -49,7 +51,7 @@ func getHonorNodeListHandler(c *gin.Context) {
 		return
 	}
 
-	rets, err := sql.NodeListSearch(req.Page, req.Limit, req.Wallet)
+	rets, err := sql.NodeListSearch(c.Request.Context(), req.Page, req.Limit, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -84,7 +86,7 @@ func nodeDetailHandler(c *gin.Context) {
 		return
 	}
 
-	rets, err := sql.NodeDetail(req.Search, req.Wallet)
+	rets, err := sql.NodeDetail(c.Request.Context(), req.Search, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString("Get Node Detail Failed")
 		JsonResponse(c, ret)
@@ -110,7 +112,7 @@ func getNodeDaoVoteListHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := sql.GetDaoVoteList(req.Search, req.Page, req.Limit)
+	rets, err := sql.GetDaoVoteList(c.Request.Context(), req.Search, req.Page, req.Limit)
 	if err != nil {
 		ret.ReturnFailureString("Get Node Dao Vote List Failed")
 		JsonResponse(c, ret)
@@ -134,7 +136,7 @@ func getNodeBlockListHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := sql.GetNodeBlockList(req.Search, req.Page, req.Limit, req.Order)
+	rets, err := sql.GetNodeBlockList(c.Request.Context(), req.Search, req.Page, req.Limit, req.Order)
 	if err != nil {
 		ret.ReturnFailureString("Get Node Block List Failed")
 		JsonResponse(c, ret)
@@ -159,7 +161,7 @@ func getNodeVoteHistoryHandler(c *gin.Context) {
 		return
 	}
 
-	rets, err := sql.GetNodeVoteHistory(req, 1)
+	rets, err := sql.GetNodeVoteHistory(c.Request.Context(), req, 1)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -184,7 +186,60 @@ func getNodeSubstituteHistoryHandler(c *gin.Context) {
 		return
 	}
 
-	rets, err := sql.GetNodeVoteHistory(req, 2)
+	rets, err := sql.GetNodeVoteHistory(c.Request.Context(), req, 2)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getNodePerformanceHandler(c *gin.Context) {
+	ret := &Response{}
+	id := converter.StrToInt64(c.Param("id"))
+	if id <= 0 {
+		ret.ReturnFailureString("request params node id invalid")
+		JsonResponse(c, ret)
+		return
+	}
+	consensusMode := converter.StrToInt64(c.DefaultQuery("consensus_mode", "2"))
+	if consensusMode != 1 && consensusMode != 2 {
+		ret.ReturnFailureString("request params consensus_mode invalid")
+		JsonResponse(c, ret)
+		return
+	}
+	from := converter.StrToInt64(c.Query("from"))
+	to := converter.StrToInt64(c.Query("to"))
+
+	rets, err := sql.GetNodePerformance(c.Request.Context(), id, int32(consensusMode), from, to)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getNodeRewardEstimateHandler(c *gin.Context) {
+	ret := &Response{}
+	req := &params.NodeRewardEstimateRequest{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	amount, err := decimal.NewFromString(req.Amount)
+	if err != nil {
+		ret.ReturnFailureString("request params amount invalid")
+		JsonResponse(c, ret)
+		return
+	}
+
+	rets, err := sql.NodeRewardEstimate(c.Request.Context(), req.Id, amount, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -0,0 +1,59 @@
+package api
+
+import (
+	"context"
+	"github.com/didip/tollbooth"
+	"github.com/didip/tollbooth/limiter"
+	"github.com/gin-gonic/gin"
+	"jutkey-server/conf"
+	"sync/atomic"
+)
+
+const defaultRateLimit = 10 //requests per second
+
+var rateLimiter atomic.Value //*limiter.Limiter
+
+func setRateLimit(max float64) {
+	if max <= 0 {
+		max = defaultRateLimit
+	}
+	if lmt, ok := rateLimiter.Load().(*limiter.Limiter); ok && lmt.GetMax() == max {
+		return
+	}
+	//a new limiter,the token buckets of the old one keep the old rate until they expire
+	rateLimiter.Store(tollbooth.NewLimiter(max, nil))
+}
+
+// RateLimit limits the requests of every client,the limit is reloaded with the config
+func RateLimit() gin.HandlerFunc {
+	setRateLimit(conf.GetEnvConf().ServerInfo.RateLimit)
+	conf.OnReload(func(c *conf.EnvConf) {
+		setRateLimit(c.ServerInfo.RateLimit)
+	})
+	return func(c *gin.Context) {
+		lmt := rateLimiter.Load().(*limiter.Limiter)
+		httpError := tollbooth.LimitByRequest(lmt, c.Writer, c.Request)
+		if httpError != nil {
+			c.Data(httpError.StatusCode, lmt.GetMessageContentType(), []byte(httpError.Message))
+			c.Abort()
+			return
+		}
+		c.Next()
+	}
+}
+
+// StatementTimeout bounds the request context with database.statement_timeout,the queries run
+// with the request context are cancelled when it expires or when the client goes away
+func StatementTimeout() gin.HandlerFunc {
+	timeout := conf.GetDbConn().GetStatementTimeout()
+	return func(c *gin.Context) {
+		if timeout <= 0 {
+			c.Next()
+			return
+		}
+		ctx, cancel := context.WithTimeout(c.Request.Context(), timeout)
+		defer cancel()
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	}
+}
@@ -0,0 +1,61 @@
+package api
+
+import (
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
+	"time"
+)
+
+const requestIDHeader = "X-Request-Id"
+
+// RequestID takes the request id from the X-Request-Id header or generates one,
+// it's returned in the response header and carried by the request context
+func RequestID() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(requestIDHeader)
+		if id == "" || len(id) > 64 {
+			id = logs.NewRequestID()
+		}
+		c.Set(logs.RequestIDField, id)
+		c.Request = c.Request.WithContext(logs.ContextWithRequestID(c.Request.Context(), id))
+		c.Header(requestIDHeader, id)
+		c.Next()
+	}
+}
+
+// AccessLog writes the access logs to logrus with the application logs
+func AccessLog() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		start := time.Now()
+		path := c.Request.URL.Path
+		c.Next()
+		fields := log.Fields{
+			"status":     c.Writer.Status(),
+			"method":     c.Request.Method,
+			"path":       path,
+			"query":      c.Request.URL.RawQuery,
+			"ip":         c.ClientIP(),
+			"latency_ms": time.Since(start).Milliseconds(),
+			"size":       c.Writer.Size(),
+			"user_agent": c.Request.UserAgent(),
+		}
+		entry := logger(c).WithFields(fields)
+		if len(c.Errors) > 0 {
+			entry = entry.WithField("error", c.Errors.String())
+		}
+		switch {
+		case c.Writer.Status() >= 500:
+			entry.Error("access")
+		case c.Writer.Status() >= 400:
+			entry.Warn("access")
+		default:
+			entry.Info("access")
+		}
+	}
+}
+
+// logger returns the log entry of the request
+func logger(c *gin.Context) *log.Entry {
+	return logs.WithContext(c.Request.Context())
+}
@@ -9,6 +9,7 @@ import (
 	log "github.com/sirupsen/logrus"
 	"jutkey-server/packages/params"
 	"jutkey-server/packages/storage/sql"
+	"net/http"
 )
 
 func userNftMinerSummaryHandler(c *gin.Context) {
@@ -22,9 +23,9 @@ func userNftMinerSummaryHandler(c *gin.Context) {
 	}
 
 	data := sql.NftMinerItems{}
-	mines, err := data.GetUserNftMinerSummary(req.Wallet)
+	mines, err := data.GetUserNftMinerSummary(c.Request.Context(), req.Wallet)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting Get Nft Miner Summary")
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting Get Nft Miner Summary")
 		if err.Error() == "record not found" {
 			ret.Return(nil, CodeSuccess)
 		} else {
@@ -47,9 +48,9 @@ func nftMinerDayRewardHandler(c *gin.Context) {
 		return
 	}
 	var rets sql.NftMinerItems
-	list, err := rets.GetUserNftFifteenDayOverview(15, req.Wallet)
+	list, err := rets.GetUserNftFifteenDayOverview(c.Request.Context(), 15, req.Wallet)
 	if err != nil {
-		log.WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting GetNftFifteendayOverView")
+		logger(c).WithFields(log.Fields{"type": consts.DBError, "error": err}).Error("getting GetNftFifteendayOverView")
 		if err.Error() == "record not found" {
 			ret.Return(nil, CodeSuccess)
 		} else {
@@ -73,7 +74,7 @@ func getNftMinerKeyInfosHandler(c *gin.Context) {
 		return
 	}
 	items := &sql.NftMinerItems{}
-	res, err := items.GetNftMinerKeyInfo(req.Wallet)
+	res, err := items.GetNftMinerKeyInfo(c.Request.Context(), req.Wallet)
 	if err != nil {
 		ret.Return(nil, CodeDBfinderr.Errorf(err))
 		JsonResponse(c, ret)
@@ -104,7 +105,7 @@ func getNftMinerRewardHistoryHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	res, err := items.GetNftMinerRewardHistory(req.Search, req.Page, req.Limit)
+	res, err := items.GetNftMinerRewardHistory(c.Request.Context(), req.Search, req.Page, req.Limit)
 	if err != nil {
 		ret.Return(nil, CodeDBfinderr.Errorf(err))
 		JsonResponse(c, ret)
@@ -147,7 +148,7 @@ func getNftMinerDetailHandler(c *gin.Context) {
 	}
 
 	items := &sql.NftMinerItems{}
-	rets, err := items.GetNftMinerDetailBySearch(req.Search, req.Wallet)
+	rets, err := items.GetNftMinerDetailBySearch(c.Request.Context(), req.Search, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -189,7 +190,7 @@ func getNftMinerStakingHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	rets, err := stak.GetNftMinerStakeInfo(req.Search, req.Page, req.Limit, req.Order, req.Wallet)
+	rets, err := stak.GetNftMinerStakeInfo(c.Request.Context(), req.Search, req.Page, req.Limit, req.Order, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -230,7 +231,7 @@ func getNftMinerRewardHandler(c *gin.Context) {
 		return
 	}
 	items := &sql.NftMinerItems{}
-	rets, err := items.GetNftMinerTxInfo(req.Search, req.Page, req.Limit, req.Order, req.Wallet)
+	rets, err := items.GetNftMinerTxInfo(c.Request.Context(), req.Search, req.Page, req.Limit, req.Order, req.Wallet)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -261,21 +262,32 @@ func getNftMinerFileHandler(c *gin.Context) {
 		JsonResponse(c, ret)
 		return
 	}
-	data, err := items.ParseSvgParams()
-	if err != nil {
-		ret.ReturnFailureString("Get Nft Miner File Failed")
+	img, err := items.RenderImage(c.Request.Context(), c.Query("format"), c.Query("size"))
+	if err != nil && img == nil {
+		ret.ReturnFailureString("Get Nft Miner File Failed:" + err.Error())
 		JsonResponse(c, ret)
 		return
 	}
-	c.Header("Content-Type", "image/svg+xml;utf8")
-	c.Header("Access-Control-Allow-Origin", "*")
-	_, err = c.Writer.Write([]byte(data))
 	if err != nil {
+		logger(c).WithFields(log.Fields{"error": err, "id": id}).Warn("Get Nft Miner File cache failed")
+	}
+	writeNftMinerImage(c, img)
+}
+
+// writeNftMinerImage writes the image with its cache headers,the cors headers are set by Cors
+func writeNftMinerImage(c *gin.Context, img *sql.NftMinerImage) {
+	c.Header("ETag", img.ETag)
+	c.Header("Cache-Control", "public, max-age=86400")
+	if c.GetHeader("If-None-Match") == img.ETag {
+		c.Status(http.StatusNotModified)
+		return
+	}
+	c.Header("Content-Type", img.ContentType)
+	if _, err := c.Writer.Write(img.Data); err != nil {
+		ret := &Response{}
 		ret.ReturnFailureString("Get Nft Miner File Handler Write Error:" + err.Error())
 		JsonResponse(c, ret)
-		return
 	}
-
 }
 
 func getNftMinerSynthesizableHandler(c *gin.Context) {
@@ -288,7 +300,7 @@ func getNftMinerSynthesizableHandler(c *gin.Context) {
 		return
 	}
 	items := &sql.NftMinerItems{}
-	res, err := items.NftMinerSynthesizable(req.Wallet, req.Search)
+	res, err := items.NftMinerSynthesizable(c.Request.Context(), req.Wallet, req.Search)
 	if err != nil {
 		ret.ReturnFailureString(err.Error())
 		JsonResponse(c, ret)
@@ -347,3 +359,126 @@ func getNftMinerTransferInfoHandler(c *gin.Context) {
 	ret.Return(res, CodeSuccess)
 	JsonResponse(c, ret)
 }
+
+func getNftMinerProvenanceHandler(c *gin.Context) {
+	ret := &Response{}
+	id := converter.StrToInt64(c.Param("id"))
+	if id <= 0 {
+		ret.ReturnFailureString("request params invalid")
+		JsonResponse(c, ret)
+		return
+	}
+	if !sql.NftMinerReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.NftMinerProvenance(c.Request.Context(), id)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if c.Query("format") == "dot" {
+		c.Data(http.StatusOK, "text/vnd.graphviz; charset=utf-8", []byte(rets.Dot()))
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getNftMinerMetadataHandler(c *gin.Context) {
+	ret := &Response{}
+	idStr := c.Param("id")
+	id := converter.StrToInt64(idStr)
+	if id <= 0 {
+		ret.ReturnFailureString("request params invalid")
+		JsonResponse(c, ret)
+		return
+	}
+	if !sql.NftMinerReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.NftMinerMetadata(c.Request.Context(), id, apiUrl("nft_miner_file/"))
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if rets == nil {
+		ret.ReturnFailureString("unknown nft Miner id:" + idStr)
+		JsonResponse(c, ret)
+		return
+	}
+	c.JSON(http.StatusOK, rets)
+}
+
+func getNftMinerCollectionHandler(c *gin.Context) {
+	ret := &Response{}
+	if !sql.NftMinerReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.NftMinerCollection(c.Request.Context())
+	if err != nil {
+		ret.Return(nil, CodeDBfinderr.Errorf(err))
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getNftMinerCalendarHandler(c *gin.Context) {
+	ret := &Response{}
+	wallet := c.Param("wallet")
+	days := 30
+	if d := c.Query("days"); d != "" {
+		days = int(converter.StrToInt64(d))
+	}
+	if !sql.NftMinerReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.NftMinerCalendar(c.Request.Context(), wallet, days)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if c.Query("format") == "ics" {
+		c.Header("Content-Disposition", fmt.Sprintf("inline; filename=%q", "nft_miner_"+wallet+".ics"))
+		c.Data(http.StatusOK, "text/calendar; charset=utf-8", []byte(rets.ICS()))
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func getNftMinerSynthesisPlanHandler(c *gin.Context) {
+	req := &params.NftSynthesisPlanRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if !sql.NftMinerReady {
+		ret.Return(nil, CodeSuccess)
+		JsonResponse(c, ret)
+		return
+	}
+	rets, err := sql.NftMinerSynthesisPlan(c.Request.Context(), req.Wallet, req.Target, req.Plans)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(rets, CodeSuccess)
+	JsonResponse(c, ret)
+}
@@ -2,19 +2,18 @@ package api
 
 import (
 	"context"
-	"fmt"
 	"github.com/IBAX-io/go-ibax/packages/utils"
 	"github.com/gin-gonic/gin/binding"
 	swaggerFiles "github.com/swaggo/files"
 	ginSwagger "github.com/swaggo/gin-swagger"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
 	"golang.org/x/net/http2"
 	"jutkey-server/packages/consts"
+	"jutkey-server/packages/metrics"
+	"jutkey-server/packages/tracing"
 	"net/http"
 	_ "net/http/pprof"
-	"strings"
 
-	"github.com/didip/tollbooth"
-	"github.com/didip/tollbooth_gin"
 	"github.com/gin-gonic/gin"
 	log "github.com/sirupsen/logrus"
 	"jutkey-server/conf"
@@ -35,49 +34,22 @@ func init() {
 
 var server *http.Server
 
-func Cors() gin.HandlerFunc {
-	return func(c *gin.Context) {
-		method := c.Request.Method
-
-		origin := c.Request.Header.Get("Origin")
-		var headerKeys []string
-		for k := range c.Request.Header {
-			headerKeys = append(headerKeys, k)
-		}
-		headerStr := strings.Join(headerKeys, ", ")
-		if headerStr != "" {
-			headerStr = fmt.Sprintf("access-control-allow-origin, access-control-allow-headers, %s", headerStr)
-		} else {
-			headerStr = "access-control-allow-origin, access-control-allow-headers"
-		}
-		if origin != "" {
-			//c.Writer.Header().Set("Access-Control-Allow-Origin", "*")
-			c.Header("Access-Control-Allow-Origin", "*")
-			//c.Header("Access-Control-Allow-Headers", headerStr)
-			c.Header("Access-Control-Allow-Methods", "POST, GET, OPTIONS, PUT, DELETE")
-			c.Header("Access-Control-Allow-Headers", "Authorization, Content-Length, X-CSRF-Token, Accept, Origin, Host, Connection, Accept-Encoding, Accept-Language,DNT, X-CustomHeader, Keep-Alive, User-Agent, X-Requested-With, If-Modified-Since, Cache-Control, Content-Type, Pragma")
-			c.Header("Access-Control-Expose-Headers", "Content-Length, Access-Control-Allow-Origin, Access-Control-Allow-Headers, Content-Type")
-			// c.Header("Access-Control-Max-Age", "172800")
-			c.Header("Access-Control-Allow-Credentials", "true")
-			c.Set("content-type", "application/json")
-		}
-
-		//OPTIONS
-		if method == "OPTIONS" {
-			c.JSON(http.StatusOK, "Options Request!")
-		}
-		c.Next()
-	}
-}
 func prefix(s string) string {
 	return "/api/v2/" + s
 }
 
 func Run(host string) (err error) {
-	r := gin.Default()
-	//Ten requests per second
-	limiter := tollbooth.NewLimiter(10, nil)
-	r.Use(Cors(), tollbooth_gin.LimitHandler(limiter))
+	if mode := conf.GetEnvConf().ServerInfo.Mode; mode != "" {
+		gin.SetMode(mode)
+	}
+	gin.DefaultWriter = log.StandardLogger().WriterLevel(log.DebugLevel)
+	gin.DefaultErrorWriter = log.StandardLogger().WriterLevel(log.ErrorLevel)
+	r := gin.New()
+	r.Use(RequestID())
+	if tracing.Enabled() {
+		r.Use(otelgin.Middleware(tracing.ServiceName()))
+	}
+	r.Use(AccessLog(), gin.Recovery(), Cors(), RateLimit())
 	r.GET("/", func(c *gin.Context) {
 		c.JSON(200, gin.H{
 			"message": consts.Version(),
@@ -89,7 +61,9 @@ func Run(host string) (err error) {
 			"message": "pong",
 		})
 	})
+	r.GET("/metrics", AdminAuth(), gin.WrapH(metrics.Handler()))
 	rte := r.Group(consts.ApiPath)
+	rte.Use(StatementTimeout())
 
 	// programatically set swagger info
 	docs.SwaggerInfo.Title = "jutkey API"
@@ -124,14 +98,22 @@ func Run(host string) (err error) {
 	rte.POST("/nft_miner_reward", getNftMinerRewardHandler)
 	rte.GET("/nft_miner_file/:id", getNftMinerFileHandler)
 	rte.POST("/nft_miner_synthesizable", getNftMinerSynthesizableHandler)
+	rte.POST("/nft_miner_synthesis_plan", getNftMinerSynthesisPlanHandler)
 	rte.GET("/nft_miner_synthesis_info/:txHash", getNftMinerSynthesisInfoHandler)
 	rte.GET("/nft_miner_transfer_info/:id/:source/:target", getNftMinerTransferInfoHandler)
+	rte.GET("/nft_miner_provenance/:id", getNftMinerProvenanceHandler)
+	rte.GET("/nft_miner_metadata/:id", getNftMinerMetadataHandler)
+	rte.GET("/nft_miner_collection", getNftMinerCollectionHandler)
+	rte.GET("/nft_miner_calendar/:wallet", getNftMinerCalendarHandler)
 
 	//user-center
 	rte.POST("/history", getHistoryHandler)
 	rte.POST("/key_total", getKeyTotalHandler)
 	rte.GET("/assign_balance/:wallet", getMyAssignBalanceHandler)
+	rte.GET("/assign_schedule/:wallet", getAssignScheduleHandler)
 	rte.GET("/key_info/:account", getKeyInfoHandler)
+	rte.GET("/member/:ecosystem/:account", getMemberProfileHandler)
+	rte.POST("/member_directory", getMemberDirectoryHandler)
 	rte.POST("/get_utxo_input", getUtxoInputHandler)
 
 	//honor-node
@@ -142,12 +124,40 @@ func Run(host string) (err error) {
 	rte.POST("/node_block_list", getNodeBlockListHandler)
 	rte.POST("/node_vote_history", getNodeVoteHistoryHandler)
 	rte.POST("/node_substitute_history", getNodeSubstituteHistoryHandler)
+	rte.GET("/node_performance/:id", getNodePerformanceHandler)
+	rte.POST("/node_reward_estimate", getNodeRewardEstimateHandler)
+
+	//dao
+	rte.POST("/dao_proposals", getDaoProposalsHandler)
+	rte.GET("/dao_proposal/:id", getDaoProposalHandler)
+	rte.POST("/dao_proposal_voters", getDaoProposalVotersHandler)
+	rte.POST("/dao_wallet_votes", getDaoWalletVotesHandler)
+	rte.POST("/dao_membership_changes", getDaoMembershipChangesHandler)
 
 	//airdrop
 	rte.GET("/airdrop_info/:wallet", GetAirdropInfoHandler)
 	rte.GET("/airdrop_balance/:wallet", GetAirdropBalanceHandler)
+	rte.GET("/airdrop_schedule/:wallet", GetAirdropScheduleHandler)
+
+	//wallet auth
+	rte.GET("/wallet_nonce/:wallet", getWalletNonceHandler)
+	rte.POST("/wallet_token", walletTokenHandler)
+
+	//alert,the rules are managed by the wallet token owner
+	alert := rte.Group("", WalletAuth())
+	alert.POST("/alert_rule_create", createAlertRuleHandler)
+	alert.POST("/alert_rules", getAlertRulesHandler)
+	alert.POST("/alert_rule_delete", deleteAlertRuleHandler)
+	alert.POST("/alert_rule_enable", enableAlertRuleHandler)
+	alert.POST("/alert_dead_letters", getAlertDeadLettersHandler)
+
+	//admin
+	admin := rte.Group("/admin", AdminAuth())
+	admin.POST("/balance_discrepancies", getBalanceDiscrepanciesHandler)
 
 	//other
+	rte.GET("/block_time", getBlockTimeHandler)
+	rte.GET("/block_at", getBlockAtHandler)
 	rte.GET(`/get_attachment/:hash`, getAttachmentHandler)
 	rte.GET("/get_locator", getLocatorHandler)
 
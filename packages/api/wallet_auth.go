@@ -0,0 +1,157 @@
+package api
+
+import (
+	"bytes"
+	"context"
+	"encoding/hex"
+	"errors"
+	"github.com/IBAX-io/go-ibax/packages/common/crypto"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/gin-gonic/gin"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/params"
+	"jutkey-server/packages/services"
+	"jutkey-server/packages/storage/kv"
+	"jutkey-server/packages/storage/sql"
+	"net/http"
+	"strings"
+	"time"
+)
+
+const (
+	walletAuthKey     = "wallet"
+	walletNoncePrefix = "wallet-nonce:"
+)
+
+type walletNonce struct {
+	Nonce   string `json:"nonce"`
+	Message string `json:"message"` //the message to be signed by the wallet key
+	Expire  int64  `json:"expire"`
+}
+
+type walletToken struct {
+	Token  string `json:"token"`
+	Expire int64  `json:"expire"`
+}
+
+// WalletAuth requires a wallet token as a bearer token,the handlers check the wallet with authorizedWallet
+func WalletAuth() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		auth := c.GetHeader("Authorization")
+		token := strings.TrimPrefix(auth, "Bearer ")
+		wallet, err := services.ParseWalletToken(token)
+		if token == auth || err != nil {
+			ret := &Response{}
+			ret.Return(nil, CodePermissionDenied)
+			c.AbortWithStatusJSON(http.StatusUnauthorized, ret)
+			return
+		}
+		c.Set(walletAuthKey, wallet)
+		c.Next()
+	}
+}
+
+// authorizedWallet reports whether the request token was issued to wallet
+func authorizedWallet(c *gin.Context, wallet string) bool {
+	authed := c.GetString(walletAuthKey)
+	keyId := converter.StringToAddress(wallet)
+	return authed != "" && keyId != 0 && converter.StringToAddress(authed) == keyId
+}
+
+func getWalletNonceHandler(c *gin.Context) {
+	ret := &Response{}
+	wallet := c.Param("wallet")
+	if converter.StringToAddress(wallet) == 0 {
+		ret.Return(nil, CodeRequestformat)
+		JsonResponse(c, ret)
+		return
+	}
+	nonce, err := services.NewWalletNonce()
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	err = kv.Default.SetExp(c.Request.Context(), walletNoncePrefix+wallet, nonce, services.WalletNonceExpire)
+	if err != nil {
+		logger(c).WithFields(log.Fields{"type": services.CryptoError, "error": err}).Error("save wallet nonce failed")
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(walletNonce{
+		Nonce:   nonce,
+		Message: services.WalletSignMessage(wallet, nonce),
+		Expire:  time.Now().Add(services.WalletNonceExpire).Unix(),
+	}, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+// walletTokenHandler issues a wallet token for the signature of the nonce message by the key of 1_keys
+func walletTokenHandler(c *gin.Context) {
+	req := &params.WalletTokenRequest{}
+	ret := &Response{}
+	err := params.ParseFrom(c, req)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	if err = checkWalletSignature(c.Request.Context(), req); err != nil {
+		logger(c).WithFields(log.Fields{"type": services.CryptoError, "error": err, "wallet": req.Wallet}).Warn("wallet signature refused")
+		ret.Return(nil, CodeSignError.String(err.Error()))
+		JsonResponse(c, ret)
+		return
+	}
+	token, err := services.IssueWalletToken(req.Wallet)
+	if err != nil {
+		ret.ReturnFailureString(err.Error())
+		JsonResponse(c, ret)
+		return
+	}
+	ret.Return(walletToken{Token: token, Expire: time.Now().Add(services.WalletTokenExpire).Unix()}, CodeSuccess)
+	JsonResponse(c, ret)
+}
+
+func checkWalletSignature(ctx context.Context, req *params.WalletTokenRequest) error {
+	keyId := converter.StringToAddress(req.Wallet)
+	if keyId == 0 {
+		return errors.New("wallet invalid")
+	}
+	pub, err := hex.DecodeString(req.PublicKey)
+	if err != nil {
+		return errors.New("pubkey invalid")
+	}
+	//1_keys keeps the public key without the uncompressed point prefix
+	if len(pub) == 65 && pub[0] == 4 {
+		pub = pub[1:]
+	}
+	sign, err := hex.DecodeString(req.Signature)
+	if err != nil {
+		return errors.New("signature invalid")
+	}
+	//the nonce is used once,of concurrent requests only one gets it
+	nonce, err := kv.Default.GetDel(ctx, walletNoncePrefix+req.Wallet)
+	if errors.Is(err, kv.ErrNotFound) {
+		return errors.New("nonce not found or expired")
+	}
+	if err != nil {
+		return err
+	}
+	var key sql.Key
+	f, err := key.GetEcosystemKeys(1, keyId)
+	if err != nil {
+		return err
+	}
+	if !f || len(key.PublicKey) == 0 || !bytes.Equal(key.PublicKey, pub) {
+		return errors.New("pubkey doesn't belong to the wallet")
+	}
+	ok, err := crypto.CheckSign(pub, []byte(services.WalletSignMessage(req.Wallet, nonce)), sign)
+	if err != nil {
+		return err
+	}
+	if !ok {
+		return errors.New("signature doesn't match")
+	}
+	return nil
+}
@@ -1,9 +1,11 @@
 package crontab
 
 import (
+	"context"
 	"github.com/IBAX-io/go-ibax/packages/smart"
 	log "github.com/sirupsen/logrus"
 	"jutkey-server/packages/storage/sql"
+	"jutkey-server/packages/tracing"
 	"sync"
 )
 
@@ -31,14 +33,37 @@ const (
 	syncSpentInfoHistory
 	syncEcosystemInfo
 	syncUtxoTxData
+	checkNftStakeAlert
 )
 
 //delay
 const (
 	getHonorNode = iota
 	loadContracts
+	recordNodePerformance
+	probeHonorNode
+	reconcileBalances
 )
 
+var realTimeTaskNames = map[byte]string{
+	initPledgeAmount:     "init_pledge_amount",
+	updateHonorNodeInfo:  "update_honor_node_info",
+	initGlobalSwitch:     "init_global_switch",
+	getStatisticsData:    "get_statistics_data",
+	syncSpentInfoHistory: "sync_spent_info_history",
+	syncEcosystemInfo:    "sync_ecosystem_info",
+	syncUtxoTxData:       "sync_utxo_tx_data",
+	checkNftStakeAlert:   "check_nft_stake_alert",
+}
+
+var delayTaskNames = map[byte]string{
+	getHonorNode:          "get_honor_node",
+	loadContracts:         "load_contracts",
+	recordNodePerformance: "record_node_performance",
+	probeHonorNode:        "probe_honor_node",
+	reconcileBalances:     "reconcile_balances",
+}
+
 func (p *crontab) crontabMain() {
 	p.signal = make(chan byte, 3)
 	var (
@@ -49,9 +74,13 @@ func (p *crontab) crontabMain() {
 		r5Task = &task{cmd: syncSpentInfoHistory, getDataOver: true}
 		r6Task = &task{cmd: syncEcosystemInfo, getDataOver: true}
 		r7Task = &task{cmd: syncUtxoTxData, getDataOver: true}
+		r8Task = &task{cmd: checkNftStakeAlert, getDataOver: true}
 
 		d1Task = &task{cmd: getHonorNode, getDataOver: true}
 		d2Task = &task{cmd: loadContracts, getDataOver: true}
+		d3Task = &task{cmd: recordNodePerformance, getDataOver: true}
+		d4Task = &task{cmd: probeHonorNode, getDataOver: true}
+		d5Task = &task{cmd: reconcileBalances, getDataOver: true}
 	)
 	for {
 		select {
@@ -65,9 +94,13 @@ func (p *crontab) crontabMain() {
 				go r5Task.startUpRealTimeTask()
 				go r6Task.startUpRealTimeTask()
 				go r7Task.startUpRealTimeTask()
+				go r8Task.startUpRealTimeTask()
 			case delay:
 				go d1Task.startUpDelayTask()
 				go d2Task.startUpDelayTask()
+				go d3Task.startUpDelayTask()
+				go d4Task.startUpDelayTask()
+				go d5Task.startUpDelayTask()
 			}
 
 		}
@@ -90,7 +123,9 @@ func (rk *task) startUpRealTimeTask() {
 	}
 
 	rk.getDataOver = false
+	_, span := tracing.Start(context.Background(), "crontab."+realTimeTaskNames[rk.cmd])
 	defer func() {
+		span.End()
 		rk.getDataOver = true
 	}()
 	switch rk.cmd {
@@ -114,6 +149,8 @@ func (rk *task) startUpRealTimeTask() {
 		sql.SyncEcosystemInfo()
 	case syncUtxoTxData:
 		sql.SendTxDataSyncSignal()
+	case checkNftStakeAlert:
+		sql.CheckNftStakeAlert()
 	}
 }
 
@@ -122,7 +159,9 @@ func (rk *task) startUpDelayTask() {
 		return
 	}
 	rk.getDataOver = false
+	_, span := tracing.Start(context.Background(), "crontab."+delayTaskNames[rk.cmd])
 	defer func() {
+		span.End()
 		rk.getDataOver = true
 	}()
 
@@ -134,5 +173,11 @@ func (rk *task) startUpDelayTask() {
 		if err != nil {
 			log.WithFields(log.Fields{"err:": err}).Error("Load Contracts Failed")
 		}
+	case recordNodePerformance:
+		sql.RecordNodePerformance()
+	case probeHonorNode:
+		sql.ProbeHonorNodes()
+	case reconcileBalances:
+		sql.ReconcileBalances()
 	}
 }
@@ -4,11 +4,16 @@ import (
 	"github.com/robfig/cron/v3"
 	log "github.com/sirupsen/logrus"
 	"jutkey-server/conf"
+	"sync"
 	"time"
 )
 
 var (
 	cr crontab
+
+	schedulerMu sync.Mutex
+	scheduler   *cron.Cron
+	scheduled   [2]string //real time,delay
 )
 
 func CreateCrontab() {
@@ -20,36 +25,45 @@ func CreateCrontab() {
 	cr.sendCrontabCmd(delay)
 
 	if crontabInfo != nil {
-		go createCrontabFromRealTime(crontabInfo.RealTime)
-		go createCrontabFromDelay(crontabInfo.Delay)
+		schedule(crontabInfo.RealTime, crontabInfo.Delay)
 	}
-
+	conf.OnReload(func(c *conf.EnvConf) {
+		if c.Crontab != nil {
+			schedule(c.Crontab.RealTime, c.Crontab.Delay)
+		}
+	})
 }
 
 func newWithSecond() *cron.Cron {
-	secondParser := cron.NewParser(cron.Second | cron.Minute |
-		cron.Hour | cron.Dom | cron.Month | cron.DowOptional | cron.Descriptor)
-	return cron.New(cron.WithParser(secondParser), cron.WithChain())
+	return cron.New(cron.WithParser(conf.CronParser), cron.WithChain())
 }
 
-func createCrontabFromRealTime(timeSet string) {
+// schedule replaces the running schedules,the running tasks are not interrupted
+func schedule(realTimeSet, delaySet string) {
+	schedulerMu.Lock()
+	defer schedulerMu.Unlock()
+	if scheduler != nil && scheduled == [2]string{realTimeSet, delaySet} {
+		return
+	}
 	c := newWithSecond()
-	_, err := c.AddFunc(timeSet, func() {
+	_, err := c.AddFunc(realTimeSet, func() {
 		cr.sendCrontabCmd(realTime)
 	})
 	if err != nil {
-		log.WithFields(log.Fields{"error": err, "time set": timeSet}).Error("create Crontab From real Time Add Function Failed")
+		log.WithFields(log.Fields{"error": err, "time set": realTimeSet}).Error("create Crontab From real Time Add Function Failed")
+		return
 	}
-	c.Start()
-}
-
-func createCrontabFromDelay(timeSet string) {
-	c := newWithSecond()
-	_, err := c.AddFunc(timeSet, func() {
+	_, err = c.AddFunc(delaySet, func() {
 		cr.sendCrontabCmd(delay)
 	})
 	if err != nil {
-		log.WithFields(log.Fields{"error": err, "time set": timeSet}).Error("create Crontab From delay Add Function Failed")
+		log.WithFields(log.Fields{"error": err, "time set": delaySet}).Error("create Crontab From delay Add Function Failed")
+		return
+	}
+	if scheduler != nil {
+		scheduler.Stop()
+		log.WithFields(log.Fields{"real_time": realTimeSet, "delay": delaySet}).Info("crontab rescheduled")
 	}
 	c.Start()
+	scheduler, scheduled = c, [2]string{realTimeSet, delaySet}
 }
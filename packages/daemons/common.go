@@ -4,7 +4,9 @@ import (
 	"context"
 	"fmt"
 	"jutkey-server/packages/crontab"
+	"jutkey-server/packages/storage/content"
 	"jutkey-server/packages/storage/geoip"
+	"jutkey-server/packages/storage/kv"
 	"jutkey-server/packages/storage/locator"
 	"jutkey-server/packages/storage/sql"
 )
@@ -24,17 +26,27 @@ func StartDaemons(ctx context.Context) {
 		ExitCh <- fmt.Errorf("GeoIp Database Init err:%s\n", err.Error())
 	}
 
-	var node sql.HonorNodeInfo
-	err = node.CreateTable()
+	err = content.InitContentStore()
 	if err != nil {
-		ExitCh <- fmt.Errorf("Create honer node table err:%s\n", err.Error())
+		ExitCh <- fmt.Errorf("Content Store Init err:%s\n", err.Error())
 	}
-	err = sql.InitSpentInfoHistory()
+
+	err = kv.InitStore()
+	if err != nil {
+		ExitCh <- fmt.Errorf("Cache Store Init err:%s\n", err.Error())
+	}
+
+	_, err = sql.MigrateUp(0)
+	if err != nil {
+		ExitCh <- fmt.Errorf("Migrate Database err:%s\n", err.Error())
+	}
+	err = sql.InitAlert()
 	if err != nil {
-		ExitCh <- fmt.Errorf("Init Spent Info History err:%s\n", err.Error())
+		ExitCh <- fmt.Errorf("Init Alert err:%s\n", err.Error())
 	}
 	sql.InitEcosystemInfo()
 	sql.InitTransactionData()
+	sql.InitUtxoBackfill(ctx)
 
 	go crontab.CreateCrontab()
 }
@@ -0,0 +1,67 @@
+package logs
+
+import (
+	"context"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/logger"
+	"time"
+)
+
+// GormLogger writes the gorm logs to logrus,the queries slower than SlowThreshold are logged as warnings
+// and every query is logged at debug level
+type GormLogger struct {
+	SlowThreshold time.Duration
+	level         logger.LogLevel
+}
+
+func NewGormLogger(slowThreshold time.Duration) *GormLogger {
+	return &GormLogger{SlowThreshold: slowThreshold, level: logger.Info}
+}
+
+func (l *GormLogger) LogMode(level logger.LogLevel) logger.Interface {
+	n := *l
+	n.level = level
+	return &n
+}
+
+func (l *GormLogger) Info(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Info {
+		WithContext(ctx).Infof(msg, args...)
+	}
+}
+
+func (l *GormLogger) Warn(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Warn {
+		WithContext(ctx).Warnf(msg, args...)
+	}
+}
+
+func (l *GormLogger) Error(ctx context.Context, msg string, args ...any) {
+	if l.level >= logger.Error {
+		WithContext(ctx).Errorf(msg, args...)
+	}
+}
+
+func (l *GormLogger) Trace(ctx context.Context, begin time.Time, fc func() (sql string, rowsAffected int64), err error) {
+	if l.level <= logger.Silent {
+		return
+	}
+	elapsed := time.Since(begin)
+	slow := l.SlowThreshold > 0 && elapsed > l.SlowThreshold
+	failed := err != nil && !errors.Is(err, gorm.ErrRecordNotFound)
+	if !slow && !failed && !log.IsLevelEnabled(log.DebugLevel) {
+		return
+	}
+	sql, rows := fc()
+	entry := WithContext(ctx).WithFields(log.Fields{"sql": sql, "rows": rows, "elapsed_ms": elapsed.Milliseconds()})
+	switch {
+	case failed && l.level >= logger.Error:
+		entry.WithError(err).Error("sql query failed")
+	case slow && l.level >= logger.Warn:
+		entry.WithField("threshold_ms", l.SlowThreshold.Milliseconds()).Warn("slow sql query")
+	case l.level >= logger.Info:
+		entry.Debug("sql query")
+	}
+}
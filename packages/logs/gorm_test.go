@@ -0,0 +1,50 @@
+package logs
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"testing"
+	"time"
+)
+
+func TestGormLoggerTrace(t *testing.T) {
+	var buf bytes.Buffer
+	out := log.StandardLogger().Out
+	defer log.SetOutput(out)
+	log.SetOutput(&buf)
+	log.SetFormatter(&log.JSONFormatter{})
+	log.SetLevel(log.InfoLevel)
+
+	l := NewGormLogger(100 * time.Millisecond)
+	ctx := ContextWithRequestID(context.Background(), "req-1")
+	query := func() (string, int64) { return `SELECT * FROM "1_history"`, 3 }
+
+	l.Trace(ctx, time.Now(), query, nil)
+	l.Trace(ctx, time.Now(), query, gorm.ErrRecordNotFound)
+	if buf.Len() != 0 {
+		t.Fatalf("fast query logged: %s", buf.String())
+	}
+
+	l.Trace(ctx, time.Now().Add(-time.Second), query, nil)
+	var entry map[string]any
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "warning" || entry[RequestIDField] != "req-1" || entry["rows"] != float64(3) {
+		t.Errorf("slow query entry %v", entry)
+	}
+
+	buf.Reset()
+	l.Trace(context.Background(), time.Now(), query, errors.New("timeout"))
+	entry = nil
+	if err := json.Unmarshal(buf.Bytes(), &entry); err != nil {
+		t.Fatal(err)
+	}
+	if entry["level"] != "error" || entry[RequestIDField] != nil {
+		t.Errorf("failed query entry %v", entry)
+	}
+}
@@ -0,0 +1,51 @@
+package logs
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	log "github.com/sirupsen/logrus"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const (
+	RequestIDField = "request_id"
+	TraceIDField   = "trace_id"
+)
+
+type requestIDKey struct{}
+
+// NewRequestID returns a random 16 bytes hex id
+func NewRequestID() string {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return ""
+	}
+	return hex.EncodeToString(b)
+}
+
+func ContextWithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestID(ctx context.Context) string {
+	if ctx == nil {
+		return ""
+	}
+	id, _ := ctx.Value(requestIDKey{}).(string)
+	return id
+}
+
+// WithContext returns the log entry of ctx,it has the request id when ctx belongs to a request
+func WithContext(ctx context.Context) *log.Entry {
+	entry := log.NewEntry(log.StandardLogger())
+	if id := RequestID(ctx); id != "" {
+		entry = entry.WithField(RequestIDField, id)
+	}
+	if ctx != nil {
+		if sc := trace.SpanContextFromContext(ctx); sc.IsValid() {
+			entry = entry.WithField(TraceIDField, sc.TraceID().String())
+		}
+	}
+	return entry
+}
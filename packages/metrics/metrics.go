@@ -0,0 +1,41 @@
+package metrics
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"net/http"
+)
+
+const namespace = "jutkey"
+
+var (
+	// BalanceDiscrepancies is the number of open balance discrepancies by kind(account,utxo)
+	BalanceDiscrepancies = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "balance_discrepancies",
+		Help:      "Open balance discrepancies found by the reconciliation job.",
+	}, []string{"kind"})
+
+	// ReconcileCheckedKeys counts the wallets checked by the reconciliation job by mode(sample,full)
+	ReconcileCheckedKeys = prometheus.NewCounterVec(prometheus.CounterOpts{
+		Namespace: namespace,
+		Name:      "reconcile_checked_keys_total",
+		Help:      "Wallets checked by the reconciliation job.",
+	}, []string{"mode"})
+
+	// ReconcileLastRun is the unix time of the last finished reconciliation run by mode
+	ReconcileLastRun = prometheus.NewGaugeVec(prometheus.GaugeOpts{
+		Namespace: namespace,
+		Name:      "reconcile_last_run_timestamp_seconds",
+		Help:      "Unix time of the last finished reconciliation run.",
+	}, []string{"mode"})
+)
+
+func init() {
+	prometheus.MustRegister(BalanceDiscrepancies, ReconcileCheckedKeys, ReconcileLastRun)
+}
+
+// Handler serves the registered metrics in the prometheus text format
+func Handler() http.Handler {
+	return promhttp.Handler()
+}
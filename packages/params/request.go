@@ -66,6 +66,67 @@ type HonorNodeStakingInfoRequest struct {
 	ReqType int `json:"reqType"`
 }
 
+type AlertRuleRequest struct {
+	WalletTp
+	Ecosystem int64  `json:"ecosystem"`  //0:all ecosystems
+	EventType string `json:"event_type"` //wallet_received,wallet_sent,node_honor_lost,nft_stake_ended
+	Threshold string `json:"threshold"`
+	Channel   string `json:"channel"` //webhook,email
+	Target    string `json:"target"`  //webhook url or email address
+	Secret    string `json:"secret"`  //webhook signing secret,generated if empty
+}
+
+type AlertRuleIdRequest struct {
+	WalletTp
+	Id      int64 `json:"id"`
+	Enabled bool  `json:"enabled"`
+}
+
+type WalletTokenRequest struct {
+	WalletTp
+	PublicKey string `json:"pubkey"`    //hex public key of the wallet
+	Signature string `json:"signature"` //hex signature of the nonce message
+}
+
+type WalletPageRequest struct {
+	GeneralRequest
+	WalletTp
+}
+
+type NodeRewardEstimateRequest struct {
+	Id     int64  `json:"id"`
+	Amount string `json:"amount" example:"1000"` //hypothetical vote amount
+	Wallet string `json:"wallet"`                //optional,returns the realized reward history of the wallet
+}
+
+type DaoProposalsRequest struct {
+	GeneralRequest
+	Status int `json:"status"` //0:all 1:voting 2:accepted 3:withdrawn
+}
+
+type DaoProposalVotersRequest struct {
+	GeneralRequest
+	Id int64 `json:"id"`
+}
+
+type NftSynthesisPlanRequest struct {
+	WalletTp
+	Target int `json:"target" example:"100"` //target energy point
+	Plans  int `json:"plans"`                //max plans returned
+}
+
+type MemberDirectoryRequest struct {
+	GeneralRequest
+	EcosystemTp
+	Name string `json:"name"` //member name search
+}
+
+type BalanceDiscrepancyRequest struct {
+	GeneralRequest
+	Kind     string `json:"kind"`     //account,utxo,empty for both
+	Resolved bool   `json:"resolved"` //false:open true:resolved
+}
+
 func ParseFrom(c *gin.Context, p paramsValidator) (err error) {
 	err = c.ShouldBindWith(&p, binding.JSON)
 	if err != nil {
@@ -174,3 +235,116 @@ func (p *WalletSearchTp) Validate() error {
 	}
 	return p.SearchTp.Validate()
 }
+
+func (p *AlertRuleRequest) Validate() error {
+	err := p.WalletTp.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Ecosystem < 0 {
+		return errors.New("ecosystem id invalid")
+	}
+	if p.EventType == "" || p.Channel == "" || p.Target == "" {
+		return errors.New("request params event_type,channel,target can not be empty")
+	}
+	return nil
+}
+
+func (p *AlertRuleIdRequest) Validate() error {
+	err := p.WalletTp.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Id <= 0 {
+		return fmt.Errorf("request params invalid! id:%d", p.Id)
+	}
+	return nil
+}
+
+func (p *WalletTokenRequest) Validate() error {
+	err := p.WalletTp.Validate()
+	if err != nil {
+		return err
+	}
+	if p.PublicKey == "" || p.Signature == "" {
+		return errors.New("request params pubkey and signature are required")
+	}
+	return nil
+}
+
+func (p *WalletPageRequest) Validate() error {
+	err := p.GeneralRequest.Validate()
+	if err != nil {
+		return err
+	}
+	return p.WalletTp.Validate()
+}
+
+func (p *NodeRewardEstimateRequest) Validate() error {
+	if p.Id <= 0 {
+		return fmt.Errorf("request params invalid! id:%d", p.Id)
+	}
+	if p.Amount == "" {
+		return errors.New("request params amount can not be empty")
+	}
+	return nil
+}
+
+func (p *DaoProposalsRequest) Validate() error {
+	err := p.GeneralRequest.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Status < 0 || p.Status > 3 {
+		return fmt.Errorf("request params invalid! status:%d", p.Status)
+	}
+	return nil
+}
+
+func (p *DaoProposalVotersRequest) Validate() error {
+	err := p.GeneralRequest.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Id <= 0 {
+		return fmt.Errorf("request params invalid! id:%d", p.Id)
+	}
+	return nil
+}
+
+func (p *NftSynthesisPlanRequest) Validate() error {
+	err := p.WalletTp.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Target <= 0 {
+		return fmt.Errorf("request params invalid! target:%d", p.Target)
+	}
+	return nil
+}
+
+func (p *MemberDirectoryRequest) Validate() error {
+	err := p.GeneralRequest.Validate()
+	if err != nil {
+		return err
+	}
+	err = p.EcosystemTp.Validate()
+	if err != nil {
+		return err
+	}
+	if len(p.Name) > 100 {
+		return errors.New("request params name too long")
+	}
+	return nil
+}
+
+func (p *BalanceDiscrepancyRequest) Validate() error {
+	err := p.GeneralRequest.Validate()
+	if err != nil {
+		return err
+	}
+	if p.Kind != "" && p.Kind != "account" && p.Kind != "utxo" {
+		return fmt.Errorf("request params invalid! kind:%s", p.Kind)
+	}
+	return nil
+}
@@ -0,0 +1,211 @@
+package services
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/conf"
+	"net"
+	"net/http"
+	"net/smtp"
+	"net/url"
+	"strconv"
+	"strings"
+	"syscall"
+	"time"
+)
+
+const (
+	AlertChannelWebhook = "webhook"
+	AlertChannelEmail   = "email"
+
+	AlertSignatureHeader = "X-Jutkey-Signature"
+	AlertTimestampHeader = "X-Jutkey-Timestamp"
+	AlertEventHeader     = "X-Jutkey-Event"
+
+	AlertError = "Alert"
+)
+
+var (
+	defaultAlertRetries  = 5
+	defaultAlertBackoff  = time.Second * 2
+	defaultAlertTimeout  = time.Second * 10
+	maxAlertBackoff      = time.Minute * 5
+	errAlertSmtpDisabled = errors.New("alert smtp host not configured")
+
+	// webhookAllowedIP is replaced by the tests to reach httptest servers
+	webhookAllowedIP = isPublicIP
+	// webhookClient checks the address at dial time,a host resolving to a private address after the rule
+	// was created,or a redirect to one,is refused too
+	webhookClient = &http.Client{Transport: &http.Transport{
+		DialContext:         (&net.Dialer{Timeout: defaultAlertTimeout, Control: webhookDialControl}).DialContext,
+		TLSHandshakeTimeout: defaultAlertTimeout,
+		MaxIdleConnsPerHost: 2,
+	}}
+)
+
+type AlertMessage struct {
+	Channel   string
+	Target    string //webhook url or email address
+	Secret    string
+	EventType string
+	Subject   string
+	Body      []byte
+}
+
+// SignAlertPayload returns the hex HMAC-SHA256 of "timestamp.body" keyed by the rule secret
+func SignAlertPayload(secret string, timestamp int64, body []byte) string {
+	mac := hmac.New(sha256.New, []byte(secret))
+	mac.Write([]byte(strconv.FormatInt(timestamp, 10)))
+	mac.Write([]byte("."))
+	mac.Write(body)
+	return hex.EncodeToString(mac.Sum(nil))
+}
+
+// VerifyAlertSignature is what webhook receivers are expected to run on every request
+func VerifyAlertSignature(secret string, timestamp int64, body []byte, signature string) bool {
+	expected := "sha256=" + SignAlertPayload(secret, timestamp, body)
+	return hmac.Equal([]byte(expected), []byte(signature))
+}
+
+// isPublicIP rejects the loopback,private,link-local,multicast and unspecified addresses
+func isPublicIP(ip net.IP) bool {
+	return !(ip.IsLoopback() || ip.IsPrivate() || ip.IsLinkLocalUnicast() || ip.IsLinkLocalMulticast() ||
+		ip.IsInterfaceLocalMulticast() || ip.IsMulticast() || ip.IsUnspecified())
+}
+
+func webhookDialControl(network, address string, c syscall.RawConn) error {
+	host, _, err := net.SplitHostPort(address)
+	if err != nil {
+		return err
+	}
+	if ip := net.ParseIP(host); ip == nil || !webhookAllowedIP(ip) {
+		return fmt.Errorf("webhook address not allowed:%s", host)
+	}
+	return nil
+}
+
+// CheckWebhookTarget accepts http and https urls whose host only resolves to public addresses
+func CheckWebhookTarget(ctx context.Context, target string) error {
+	u, err := url.Parse(target)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("webhook url invalid:%s", target)
+	}
+	addrs, err := net.DefaultResolver.LookupIPAddr(ctx, u.Hostname())
+	if err != nil {
+		return fmt.Errorf("webhook host resolve failed:%s", u.Hostname())
+	}
+	for _, addr := range addrs {
+		if !webhookAllowedIP(addr.IP) {
+			return fmt.Errorf("webhook address not allowed:%s", addr.IP)
+		}
+	}
+	return nil
+}
+
+// DeliverAlert sends the message through its channel, retrying with exponential backoff.
+// It returns the number of attempts made and the last delivery error
+func DeliverAlert(ctx context.Context, msg *AlertMessage) (int, error) {
+	retries, backoff := alertRetryPolicy()
+	var (
+		attempt int
+		err     error
+	)
+	for attempt = 1; attempt <= retries; attempt++ {
+		switch msg.Channel {
+		case AlertChannelWebhook:
+			err = sendWebhook(ctx, msg)
+		case AlertChannelEmail:
+			err = sendAlertMail(msg)
+		default:
+			return attempt, fmt.Errorf("unknown alert channel:%s", msg.Channel)
+		}
+		if err == nil || errors.Is(err, errAlertSmtpDisabled) {
+			return attempt, err
+		}
+		log.WithFields(log.Fields{"type": AlertError, "error": err, "channel": msg.Channel, "target": msg.Target, "attempt": attempt}).Warn("alert delivery failed")
+		if attempt == retries {
+			break
+		}
+		select {
+		case <-ctx.Done():
+			return attempt, ctx.Err()
+		case <-time.After(backoff):
+		}
+		backoff *= 2
+		if backoff > maxAlertBackoff {
+			backoff = maxAlertBackoff
+		}
+	}
+	return retries, err
+}
+
+func alertRetryPolicy() (int, time.Duration) {
+	retries, backoff := defaultAlertRetries, defaultAlertBackoff
+	if ac := conf.GetAlertConf(); ac != nil {
+		if ac.MaxRetries > 0 {
+			retries = ac.MaxRetries
+		}
+		if ac.RetryBackoff > 0 {
+			backoff = time.Second * time.Duration(ac.RetryBackoff)
+		}
+	}
+	return retries, backoff
+}
+
+func alertWebhookTimeout() time.Duration {
+	if ac := conf.GetAlertConf(); ac != nil && ac.WebhookTimeout > 0 {
+		return time.Second * time.Duration(ac.WebhookTimeout)
+	}
+	return defaultAlertTimeout
+}
+
+func sendWebhook(ctx context.Context, msg *AlertMessage) error {
+	ctx, cancel := context.WithTimeout(ctx, alertWebhookTimeout())
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, msg.Target, bytes.NewReader(msg.Body))
+	if err != nil {
+		return err
+	}
+	ts := time.Now().Unix()
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set(AlertEventHeader, msg.EventType)
+	req.Header.Set(AlertTimestampHeader, strconv.FormatInt(ts, 10))
+	req.Header.Set(AlertSignatureHeader, "sha256="+SignAlertPayload(msg.Secret, ts, msg.Body))
+
+	resp, err := webhookClient.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("webhook response status:%d", resp.StatusCode)
+	}
+	return nil
+}
+
+func sendAlertMail(msg *AlertMessage) error {
+	ac := conf.GetAlertConf()
+	if ac == nil || ac.Smtp.Host == "" {
+		return errAlertSmtpDisabled
+	}
+	var auth smtp.Auth
+	if ac.Smtp.Username != "" {
+		auth = smtp.PlainAuth("", ac.Smtp.Username, ac.Smtp.Password, ac.Smtp.Host)
+	}
+	var mail strings.Builder
+	mail.WriteString("From: " + ac.Smtp.From + "\r\n")
+	mail.WriteString("To: " + msg.Target + "\r\n")
+	mail.WriteString("Subject: " + msg.Subject + "\r\n")
+	mail.WriteString("MIME-Version: 1.0\r\n")
+	mail.WriteString("Content-Type: application/json; charset=UTF-8\r\n\r\n")
+	mail.Write(msg.Body)
+
+	return smtp.SendMail(ac.Smtp.Str(), auth, ac.Smtp.From, []string{msg.Target}, []byte(mail.String()))
+}
@@ -0,0 +1,117 @@
+package services
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strconv"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSignAlertPayload(t *testing.T) {
+	body := []byte(`{"event_type":"wallet_received"}`)
+	sig := "sha256=" + SignAlertPayload("secret", 1700000000, body)
+	if !VerifyAlertSignature("secret", 1700000000, body, sig) {
+		t.Fatal("signature should verify")
+	}
+	if VerifyAlertSignature("other", 1700000000, body, sig) {
+		t.Fatal("signature verified with wrong secret")
+	}
+	if VerifyAlertSignature("secret", 1700000001, body, sig) {
+		t.Fatal("signature verified with wrong timestamp")
+	}
+}
+
+// allowLoopbackWebhook lets the webhooks reach the httptest servers
+func allowLoopbackWebhook(t *testing.T) {
+	webhookAllowedIP = func(ip net.IP) bool { return true }
+	t.Cleanup(func() { webhookAllowedIP = isPublicIP })
+}
+
+func TestDeliverAlertWebhookRetry(t *testing.T) {
+	defaultAlertBackoff = time.Millisecond
+	defer func() { defaultAlertBackoff = time.Second * 2 }()
+	allowLoopbackWebhook(t)
+
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		n := atomic.AddInt32(&calls, 1)
+		body, _ := io.ReadAll(r.Body)
+		ts, _ := strconv.ParseInt(r.Header.Get(AlertTimestampHeader), 10, 64)
+		if !VerifyAlertSignature("secret", ts, body, r.Header.Get(AlertSignatureHeader)) {
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		if n < 3 {
+			w.WriteHeader(http.StatusServiceUnavailable)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+	}))
+	defer srv.Close()
+
+	msg := &AlertMessage{
+		Channel:   AlertChannelWebhook,
+		Target:    srv.URL,
+		Secret:    "secret",
+		EventType: "wallet_received",
+		Body:      []byte(`{"amount":"1"}`),
+	}
+	attempts, err := DeliverAlert(context.Background(), msg)
+	if err != nil {
+		t.Fatalf("deliver alert: %v", err)
+	}
+	if attempts != 3 {
+		t.Fatalf("attempts = %d, want 3", attempts)
+	}
+}
+
+func TestDeliverAlertWebhookExhausted(t *testing.T) {
+	defaultAlertBackoff = time.Millisecond
+	defer func() { defaultAlertBackoff = time.Second * 2 }()
+	allowLoopbackWebhook(t)
+
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer srv.Close()
+
+	msg := &AlertMessage{Channel: AlertChannelWebhook, Target: srv.URL, Secret: "secret", Body: []byte(`{}`)}
+	attempts, err := DeliverAlert(context.Background(), msg)
+	if err == nil {
+		t.Fatal("expected delivery error")
+	}
+	if attempts != defaultAlertRetries {
+		t.Fatalf("attempts = %d, want %d", attempts, defaultAlertRetries)
+	}
+}
+
+func TestWebhookPrivateTarget(t *testing.T) {
+	ctx := context.Background()
+	for _, target := range []string{"http://127.0.0.1:8080/hook", "https://10.1.2.3/", "http://169.254.169.254/latest",
+		"http://[::1]/", "http://0.0.0.0/", "http://192.168.1.1/", "ftp://example.com/"} {
+		if err := CheckWebhookTarget(ctx, target); err == nil {
+			t.Errorf("%s accepted", target)
+		}
+	}
+	if err := CheckWebhookTarget(ctx, "https://93.184.216.34/hook"); err != nil {
+		t.Errorf("public address refused: %v", err)
+	}
+
+	//the address is checked again when the webhook is sent
+	defaultAlertBackoff = time.Millisecond
+	defer func() { defaultAlertBackoff = time.Second * 2 }()
+	var calls int32
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&calls, 1)
+	}))
+	defer srv.Close()
+	msg := &AlertMessage{Channel: AlertChannelWebhook, Target: srv.URL, Secret: "secret", Body: []byte(`{}`)}
+	if _, err := DeliverAlert(ctx, msg); err == nil || atomic.LoadInt32(&calls) != 0 {
+		t.Fatalf("loopback webhook delivered,err %v", err)
+	}
+}
@@ -0,0 +1,84 @@
+package services
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/golang-jwt/jwt/v4"
+	"jutkey-server/conf"
+	"strings"
+	"time"
+)
+
+const (
+	WalletNonceExpire = time.Minute * 5
+	WalletTokenExpire = time.Hour * 24
+
+	walletAuthIssuer = "jutkey-wallet"
+)
+
+var ErrWalletAuthDisabled = errors.New("wallet auth is disabled,server.auth_secret is empty")
+
+type WalletClaims struct {
+	jwt.RegisteredClaims
+}
+
+// NewWalletNonce returns a random nonce to be signed by the wallet key
+func NewWalletNonce() (string, error) {
+	nonce := make([]byte, 16)
+	if _, err := rand.Read(nonce); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(nonce), nil
+}
+
+// WalletSignMessage is the message signed by the wallet to prove it owns the key
+func WalletSignMessage(wallet, nonce string) string {
+	return fmt.Sprintf("jutkey auth %s %s", wallet, nonce)
+}
+
+// IssueWalletToken signs a token for the wallet that has proved it owns the key
+func IssueWalletToken(wallet string) (string, error) {
+	secret := walletAuthSecret()
+	if secret == "" {
+		return "", ErrWalletAuthDisabled
+	}
+	now := time.Now()
+	claims := WalletClaims{RegisteredClaims: jwt.RegisteredClaims{
+		Issuer:    walletAuthIssuer,
+		Subject:   wallet,
+		IssuedAt:  jwt.NewNumericDate(now),
+		ExpiresAt: jwt.NewNumericDate(now.Add(WalletTokenExpire)),
+	}}
+	return jwt.NewWithClaims(jwt.SigningMethodHS256, claims).SignedString([]byte(secret))
+}
+
+// ParseWalletToken returns the wallet of a valid token
+func ParseWalletToken(token string) (string, error) {
+	secret := walletAuthSecret()
+	if secret == "" {
+		return "", ErrWalletAuthDisabled
+	}
+	var claims WalletClaims
+	_, err := jwt.ParseWithClaims(token, &claims, func(t *jwt.Token) (any, error) {
+		if t.Method != jwt.SigningMethodHS256 {
+			return nil, fmt.Errorf("unexpected signing method:%v", t.Header["alg"])
+		}
+		return []byte(secret), nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if claims.Issuer != walletAuthIssuer || strings.TrimSpace(claims.Subject) == "" {
+		return "", errors.New("wallet token invalid")
+	}
+	return claims.Subject, nil
+}
+
+func walletAuthSecret() string {
+	if s := conf.GetEnvConf().ServerInfo; s != nil {
+		return s.AuthSecret
+	}
+	return ""
+}
@@ -0,0 +1,164 @@
+package content
+
+import (
+	"container/list"
+	"context"
+	log "github.com/sirupsen/logrus"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+// LocalStore keeps contents in a directory and evicts the least recently used files
+// once the directory grows over maxBytes
+type LocalStore struct {
+	dir      string
+	maxBytes int64
+
+	mu      sync.Mutex
+	size    int64
+	lru     *list.List //front is the most recently used
+	entries map[string]*list.Element
+}
+
+type lruEntry struct {
+	key  string
+	size int64
+}
+
+type localObject struct {
+	*os.File
+	info os.FileInfo
+}
+
+func (o *localObject) Size() int64 {
+	return o.info.Size()
+}
+
+func (o *localObject) ModTime() time.Time {
+	return o.info.ModTime()
+}
+
+func NewLocalStore(dir string, maxBytes int64) (*LocalStore, error) {
+	if err := os.MkdirAll(dir, 0755); err != nil {
+		return nil, err
+	}
+	s := &LocalStore{
+		dir:      dir,
+		maxBytes: maxBytes,
+		lru:      list.New(),
+		entries:  make(map[string]*list.Element),
+	}
+	type file struct {
+		key     string
+		size    int64
+		modTime time.Time
+	}
+	var files []file
+	err := filepath.WalkDir(dir, func(p string, d fs.DirEntry, err error) error {
+		if err != nil || d.IsDir() || strings.HasSuffix(p, ".tmp") {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(dir, p)
+		if err != nil {
+			return err
+		}
+		files = append(files, file{key: filepath.ToSlash(rel), size: info.Size(), modTime: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(files, func(i, j int) bool { return files[i].modTime.Before(files[j].modTime) })
+	for _, f := range files {
+		s.entries[f.key] = s.lru.PushFront(&lruEntry{key: f.key, size: f.size})
+		s.size += f.size
+	}
+	s.mu.Lock()
+	s.evict()
+	s.mu.Unlock()
+	return s, nil
+}
+
+func (s *LocalStore) path(key string) string {
+	return filepath.Join(s.dir, filepath.FromSlash(key))
+}
+
+func (s *LocalStore) Open(ctx context.Context, key string) (Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	f, err := os.Open(s.path(key))
+	if err != nil {
+		return nil, err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return nil, err
+	}
+	s.touch(key, info.Size())
+	return &localObject{File: f, info: info}, nil
+}
+
+func (s *LocalStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	file := s.path(key)
+	if err = os.MkdirAll(filepath.Dir(file), 0755); err != nil {
+		return err
+	}
+	tmp := file + ".tmp"
+	if err = os.WriteFile(tmp, data, 0644); err != nil {
+		return err
+	}
+	if err = os.Rename(tmp, file); err != nil {
+		return err
+	}
+	s.touch(key, int64(len(data)))
+	return nil
+}
+
+// touch marks the key as the most recently used
+func (s *LocalStore) touch(key string, size int64) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*lruEntry)
+		s.size += size - e.size
+		e.size = size
+		s.lru.MoveToFront(el)
+	} else {
+		s.entries[key] = s.lru.PushFront(&lruEntry{key: key, size: size})
+		s.size += size
+	}
+	s.evict()
+}
+
+// evict removes the least recently used files,the most recent one is always kept
+func (s *LocalStore) evict() {
+	if s.maxBytes <= 0 {
+		return
+	}
+	for s.size > s.maxBytes && s.lru.Len() > 1 {
+		el := s.lru.Back()
+		e := el.Value.(*lruEntry)
+		if err := os.Remove(s.path(e.key)); err != nil && !os.IsNotExist(err) {
+			log.WithFields(log.Fields{"error": err, "key": e.key}).Warn("content cache evict failed")
+		}
+		s.lru.Remove(el)
+		delete(s.entries, e.key)
+		s.size -= e.size
+	}
+}
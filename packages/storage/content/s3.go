@@ -0,0 +1,91 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"github.com/minio/minio-go/v7"
+	"github.com/minio/minio-go/v7/pkg/credentials"
+	"net/http"
+	"time"
+)
+
+const s3Timeout = 10 * time.Second
+
+// S3Store keeps contents in a bucket of an S3 compatible storage such as MinIO
+type S3Store struct {
+	client *minio.Client
+	bucket string
+}
+
+type s3Object struct {
+	*minio.Object
+	info minio.ObjectInfo
+}
+
+func (o *s3Object) Size() int64 {
+	return o.info.Size
+}
+
+func (o *s3Object) ModTime() time.Time {
+	return o.info.LastModified
+}
+
+func NewS3Store(endpoint, accessKey, secretKey, bucket, region string, useSSL bool) (*S3Store, error) {
+	if endpoint == "" || bucket == "" {
+		return nil, errors.New("content s3 endpoint and bucket are required")
+	}
+	client, err := minio.New(endpoint, &minio.Options{
+		Creds:  credentials.NewStaticV4(accessKey, secretKey, ""),
+		Secure: useSSL,
+		Region: region,
+	})
+	if err != nil {
+		return nil, err
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3Timeout)
+	defer cancel()
+	exists, err := client.BucketExists(ctx, bucket)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		if err = client.MakeBucket(ctx, bucket, minio.MakeBucketOptions{Region: region}); err != nil {
+			return nil, err
+		}
+	}
+	return &S3Store{client: client, bucket: bucket}, nil
+}
+
+func (s *S3Store) Open(ctx context.Context, key string) (Object, error) {
+	key, err := cleanKey(key)
+	if err != nil {
+		return nil, err
+	}
+	obj, err := s.client.GetObject(ctx, s.bucket, key, minio.GetObjectOptions{})
+	if err != nil {
+		return nil, s3Error(err)
+	}
+	info, err := obj.Stat()
+	if err != nil {
+		obj.Close()
+		return nil, s3Error(err)
+	}
+	return &s3Object{Object: obj, info: info}, nil
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	key, err := cleanKey(key)
+	if err != nil {
+		return err
+	}
+	_, err = s.client.PutObject(ctx, s.bucket, key, bytes.NewReader(data), int64(len(data)), minio.PutObjectOptions{ContentType: contentType})
+	return err
+}
+
+func s3Error(err error) error {
+	if minio.ToErrorResponse(err).StatusCode == http.StatusNotFound {
+		return ErrNotExist
+	}
+	return err
+}
@@ -0,0 +1,98 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"io"
+	"jutkey-server/conf"
+	"os"
+	"path"
+	"strings"
+	"time"
+)
+
+const (
+	BackendLocal = "local"
+	BackendS3    = "s3"
+
+	defaultCacheDir  = "./upload/"
+	defaultCacheSize = 512 //MB
+)
+
+var (
+	ErrNotExist   = os.ErrNotExist
+	ErrInvalidKey = errors.New("content key invalid")
+	Default       Store
+)
+
+// Object is a stored content opened for reading,it can be served with http.ServeContent
+type Object interface {
+	io.ReadSeekCloser
+	Size() int64
+	ModTime() time.Time
+}
+
+// Store keeps attachment contents by key
+type Store interface {
+	// Open returns ErrNotExist when the key is not stored
+	Open(ctx context.Context, key string) (Object, error)
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+}
+
+func InitContentStore() error {
+	cfg := conf.GetContentConf()
+	if cfg == nil || cfg.Backend == "" || cfg.Backend == BackendLocal {
+		dir, size := defaultCacheDir, int64(defaultCacheSize)
+		if cfg != nil {
+			if cfg.CacheDir != "" {
+				dir = cfg.CacheDir
+			}
+			if cfg.CacheSize > 0 {
+				size = cfg.CacheSize
+			}
+		}
+		store, err := NewLocalStore(dir, size<<20)
+		if err != nil {
+			return err
+		}
+		Default = store
+		return nil
+	}
+	if cfg.Backend != BackendS3 {
+		return errors.New("content backend not supported:" + cfg.Backend)
+	}
+	store, err := NewS3Store(cfg.S3.Endpoint, cfg.S3.AccessKey, cfg.S3.SecretKey, cfg.S3.Bucket, cfg.S3.Region, cfg.S3.UseSSL)
+	if err != nil {
+		return err
+	}
+	Default = store
+	return nil
+}
+
+// cleanKey keeps keys relative and inside the store
+func cleanKey(key string) (string, error) {
+	key = strings.TrimPrefix(path.Clean("/"+strings.ReplaceAll(key, `\`, "/")), "/")
+	if key == "" || key == "." {
+		return "", ErrInvalidKey
+	}
+	return key, nil
+}
+
+type bytesObject struct {
+	*bytes.Reader
+	modTime time.Time
+}
+
+// NewBytesObject serves data that is already in memory
+func NewBytesObject(data []byte, modTime time.Time) Object {
+	return &bytesObject{Reader: bytes.NewReader(data), modTime: modTime}
+}
+
+func (o *bytesObject) Close() error {
+	return nil
+}
+
+func (o *bytesObject) ModTime() time.Time {
+	return o.modTime
+}
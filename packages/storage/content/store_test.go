@@ -0,0 +1,147 @@
+package content
+
+import (
+	"bytes"
+	"context"
+	"encoding/binary"
+	"errors"
+	"hash/crc32"
+	"image"
+	"image/png"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"strings"
+	"testing"
+)
+
+func TestLocalStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir(), 10)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Put(ctx, "a", []byte("12345"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Put(ctx, "b/c", []byte("12345"), ""); err != nil {
+		t.Fatal(err)
+	}
+	//touch a so that b/c is the least recently used
+	obj, err := s.Open(ctx, "a")
+	if err != nil {
+		t.Fatal(err)
+	}
+	obj.Close()
+	if err = s.Put(ctx, "d", []byte("123"), ""); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Open(ctx, "b/c"); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("b/c err = %v, want not exist", err)
+	}
+	for _, key := range []string{"a", "d"} {
+		obj, err := s.Open(ctx, key)
+		if err != nil {
+			t.Fatalf("open %s: %v", key, err)
+		}
+		obj.Close()
+	}
+}
+
+func TestCleanKey(t *testing.T) {
+	for in, want := range map[string]string{"../../etc/passwd": "etc/passwd", "/a/./b": "a/b", `a\\b`: "a/b"} {
+		got, err := cleanKey(in)
+		if err != nil || got != want {
+			t.Fatalf("cleanKey(%q) = %q,%v want %q", in, got, err, want)
+		}
+	}
+	if _, err := cleanKey("/"); err != ErrInvalidKey {
+		t.Fatalf("empty key err = %v", err)
+	}
+}
+
+func TestServeRange(t *testing.T) {
+	ctx := context.Background()
+	s, err := NewLocalStore(t.TempDir(), 0)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err = s.Put(ctx, "file.txt", []byte("0123456789"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := s.Open(ctx, "file.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	req := httptest.NewRequest(http.MethodGet, "/file.txt", nil)
+	req.Header.Set("Range", "bytes=2-5")
+	w := httptest.NewRecorder()
+	http.ServeContent(w, req, "file.txt", obj.ModTime(), obj)
+	body, _ := io.ReadAll(w.Result().Body)
+	if w.Code != http.StatusPartialContent || string(body) != "2345" {
+		t.Fatalf("code = %d body = %q", w.Code, body)
+	}
+}
+
+func TestThumbnail(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 400, 200))); err != nil {
+		t.Fatal(err)
+	}
+	data, err := Thumbnail(buf.Bytes(), 100)
+	if err != nil {
+		t.Fatal(err)
+	}
+	img, err := png.Decode(bytes.NewReader(data))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 100 || b.Dy() != 50 {
+		t.Fatalf("bounds = %v", b)
+	}
+}
+
+func TestThumbnailTooLarge(t *testing.T) {
+	var buf bytes.Buffer
+	if err := png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 1, 1))); err != nil {
+		t.Fatal(err)
+	}
+	//a small file declaring 100000x100000 pixels in its IHDR chunk
+	data := buf.Bytes()
+	binary.BigEndian.PutUint32(data[16:], 100000)
+	binary.BigEndian.PutUint32(data[20:], 100000)
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+	if _, err := Thumbnail(data, 100); err == nil || !strings.Contains(err.Error(), "larger than") {
+		t.Fatalf("err = %v", err)
+	}
+}
+
+// TestS3Store runs against a local MinIO,e.g. docker run -p 9000:9000 minio/minio server /data
+func TestS3Store(t *testing.T) {
+	endpoint := os.Getenv("CONTENT_TEST_S3_ENDPOINT")
+	if endpoint == "" {
+		t.Skip("CONTENT_TEST_S3_ENDPOINT not set")
+	}
+	ctx := context.Background()
+	s, err := NewS3Store(endpoint, os.Getenv("CONTENT_TEST_S3_ACCESS_KEY"), os.Getenv("CONTENT_TEST_S3_SECRET_KEY"), "jutkey-test", "", false)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if _, err = s.Open(ctx, "missing"); !errors.Is(err, ErrNotExist) {
+		t.Fatalf("missing err = %v", err)
+	}
+	if err = s.Put(ctx, "a/b.txt", []byte("hello"), "text/plain"); err != nil {
+		t.Fatal(err)
+	}
+	obj, err := s.Open(ctx, "a/b.txt")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer obj.Close()
+	data, _ := io.ReadAll(obj)
+	if string(data) != "hello" || obj.Size() != 5 {
+		t.Fatalf("data = %q size = %d", data, obj.Size())
+	}
+}
@@ -0,0 +1,65 @@
+package content
+
+import (
+	"bytes"
+	"fmt"
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+)
+
+const (
+	ThumbnailMinSize = 16
+	ThumbnailMaxSize = 1024
+	//largest source image decoded,its declared size is checked before decoding
+	ThumbnailMaxPixels = 50 * 1000 * 1000
+)
+
+// ThumbnailKey returns the store key of the thumbnail of hash
+func ThumbnailKey(hash string, size int) string {
+	return fmt.Sprintf("thumb/%d/%s.png", size, hash)
+}
+
+// Thumbnail scales the image to fit in a size x size box and encodes it as png,
+// smaller images are not enlarged and the images over ThumbnailMaxPixels are refused
+func Thumbnail(data []byte, size int) ([]byte, error) {
+	if size < ThumbnailMinSize || size > ThumbnailMaxSize {
+		return nil, fmt.Errorf("thumbnail size must be between %d and %d", ThumbnailMinSize, ThumbnailMaxSize)
+	}
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	if cfg.Width <= 0 || cfg.Height <= 0 || int64(cfg.Width)*int64(cfg.Height) > ThumbnailMaxPixels {
+		return nil, fmt.Errorf("image %dx%d larger than %d pixels", cfg.Width, cfg.Height, ThumbnailMaxPixels)
+	}
+	src, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, err
+	}
+	b := src.Bounds()
+	w, h := b.Dx(), b.Dy()
+	if w > size || h > size {
+		if w >= h {
+			w, h = size, h*size/w
+		} else {
+			w, h = w*size/h, size
+		}
+	}
+	if w < 1 {
+		w = 1
+	}
+	if h < 1 {
+		h = 1
+	}
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), src, b, draw.Over, nil)
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, dst); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,249 @@
+package kv
+
+import (
+	"container/list"
+	"context"
+	"path"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const memorySubscriptionBuffer = 64 //messages kept for a slow subscriber,the next ones are dropped
+
+// MemoryStore keeps the cache in the process,the least recently used keys are evicted once it holds
+// more than maxKeys,the expired ones are removed when they are met.
+// It's meant for a single server,e.g. local development and tests
+type MemoryStore struct {
+	maxKeys int
+
+	mu      sync.Mutex
+	lru     *list.List //front is the most recently used
+	entries map[string]*list.Element
+
+	subMu sync.RWMutex
+	subs  map[string]map[*memorySubscription]struct{}
+}
+
+type memoryEntry struct {
+	key      string
+	value    string
+	expireAt time.Time //zero never expires
+}
+
+type memorySubscription struct {
+	s        *MemoryStore
+	channels []string
+	ch       chan Message
+	once     sync.Once
+}
+
+func NewMemoryStore(maxKeys int) *MemoryStore {
+	return &MemoryStore{
+		maxKeys: maxKeys,
+		lru:     list.New(),
+		entries: make(map[string]*list.Element),
+		subs:    make(map[string]map[*memorySubscription]struct{}),
+	}
+}
+
+func (e *memoryEntry) expired(now time.Time) bool {
+	return !e.expireAt.IsZero() && !now.Before(e.expireAt)
+}
+
+// get returns the live entry of key and marks it as the most recently used,it must be called with mu held
+func (s *MemoryStore) get(key string) *memoryEntry {
+	el, ok := s.entries[key]
+	if !ok {
+		return nil
+	}
+	e := el.Value.(*memoryEntry)
+	if e.expired(time.Now()) {
+		s.remove(el)
+		return nil
+	}
+	s.lru.MoveToFront(el)
+	return e
+}
+
+// set must be called with mu held
+func (s *MemoryStore) set(key, value string, expireAt time.Time) {
+	if el, ok := s.entries[key]; ok {
+		e := el.Value.(*memoryEntry)
+		e.value, e.expireAt = value, expireAt
+		s.lru.MoveToFront(el)
+		return
+	}
+	s.entries[key] = s.lru.PushFront(&memoryEntry{key: key, value: value, expireAt: expireAt})
+	for s.maxKeys > 0 && s.lru.Len() > s.maxKeys {
+		s.remove(s.lru.Back())
+	}
+}
+
+func (s *MemoryStore) remove(el *list.Element) {
+	s.lru.Remove(el)
+	delete(s.entries, el.Value.(*memoryEntry).key)
+}
+
+func (s *MemoryStore) Get(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.get(key)
+	if e == nil {
+		return "", ErrNotFound
+	}
+	return e.value, nil
+}
+
+func (s *MemoryStore) GetDel(ctx context.Context, key string) (string, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	e := s.get(key)
+	if e == nil {
+		return "", ErrNotFound
+	}
+	s.remove(s.entries[key])
+	return e.value, nil
+}
+
+func (s *MemoryStore) Set(ctx context.Context, key, value string) error {
+	return s.SetExp(ctx, key, value, 0)
+}
+
+func (s *MemoryStore) SetExp(ctx context.Context, key, value string, exp time.Duration) error {
+	var expireAt time.Time
+	if exp > 0 {
+		expireAt = time.Now().Add(exp)
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.set(key, value, expireAt)
+	return nil
+}
+
+func (s *MemoryStore) Del(ctx context.Context, keys ...string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for _, key := range keys {
+		if el, ok := s.entries[key]; ok {
+			s.remove(el)
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Exists(ctx context.Context, key string) (bool, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.get(key) != nil, nil
+}
+
+// Scan matches the keys with path.Match,unlike redis a * doesn't match a /
+func (s *MemoryStore) Scan(ctx context.Context, pattern string) ([]string, error) {
+	if _, err := path.Match(pattern, ""); err != nil {
+		return nil, err
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		keys []string
+		now  = time.Now()
+	)
+	for el := s.lru.Front(); el != nil; {
+		next := el.Next()
+		e := el.Value.(*memoryEntry)
+		if e.expired(now) {
+			s.remove(el)
+		} else if ok, _ := path.Match(pattern, e.key); ok {
+			keys = append(keys, e.key)
+		}
+		el = next
+	}
+	return keys, nil
+}
+
+// Incr keeps the expiration of the key like redis INCRBY
+func (s *MemoryStore) Incr(ctx context.Context, key string, n int64) (int64, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var (
+		val      int64
+		expireAt time.Time
+	)
+	if e := s.get(key); e != nil {
+		v, err := strconv.ParseInt(e.value, 10, 64)
+		if err != nil {
+			return 0, ErrNotInteger
+		}
+		val, expireAt = v, e.expireAt
+	}
+	val += n
+	s.set(key, strconv.FormatInt(val, 10), expireAt)
+	return val, nil
+}
+
+// Publish doesn't wait for the subscribers,a message is dropped for a subscriber whose buffer is full
+func (s *MemoryStore) Publish(ctx context.Context, channel, message string) error {
+	s.subMu.RLock()
+	defer s.subMu.RUnlock()
+	for sub := range s.subs[channel] {
+		select {
+		case sub.ch <- Message{Channel: channel, Payload: message}:
+		default:
+		}
+	}
+	return nil
+}
+
+func (s *MemoryStore) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	sub := &memorySubscription{s: s, channels: channels, ch: make(chan Message, memorySubscriptionBuffer)}
+	s.subMu.Lock()
+	defer s.subMu.Unlock()
+	for _, channel := range channels {
+		if s.subs[channel] == nil {
+			s.subs[channel] = make(map[*memorySubscription]struct{})
+		}
+		s.subs[channel][sub] = struct{}{}
+	}
+	return sub, nil
+}
+
+// Close drops the keys and closes the subscriptions
+func (s *MemoryStore) Close() error {
+	s.mu.Lock()
+	s.lru.Init()
+	s.entries = make(map[string]*list.Element)
+	s.mu.Unlock()
+
+	s.subMu.RLock()
+	var subs []*memorySubscription
+	for _, m := range s.subs {
+		for sub := range m {
+			subs = append(subs, sub)
+		}
+	}
+	s.subMu.RUnlock()
+	for _, sub := range subs {
+		sub.Close()
+	}
+	return nil
+}
+
+func (p *memorySubscription) Channel() <-chan Message {
+	return p.ch
+}
+
+func (p *memorySubscription) Close() error {
+	p.once.Do(func() {
+		p.s.subMu.Lock()
+		defer p.s.subMu.Unlock()
+		for _, channel := range p.channels {
+			delete(p.s.subs[channel], p)
+			if len(p.s.subs[channel]) == 0 {
+				delete(p.s.subs, channel)
+			}
+		}
+		close(p.ch)
+	})
+	return nil
+}
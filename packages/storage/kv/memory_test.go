@@ -0,0 +1,118 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"sort"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestMemoryStoreEviction(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(2)
+	s.Set(ctx, "a", "1")
+	s.Set(ctx, "b", "2")
+	//touch a so that b is the least recently used
+	if v, err := s.Get(ctx, "a"); err != nil || v != "1" {
+		t.Fatalf("a = %q,%v", v, err)
+	}
+	s.Set(ctx, "c", "3")
+	if _, err := s.Get(ctx, "b"); !errors.Is(err, ErrNotFound) {
+		t.Fatalf("b err = %v, want not found", err)
+	}
+	keys, err := s.Scan(ctx, "*")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sort.Strings(keys)
+	if len(keys) != 2 || keys[0] != "a" || keys[1] != "c" {
+		t.Errorf("keys %v", keys)
+	}
+}
+
+func TestMemoryStoreExpiration(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+	s.SetExp(ctx, "a", "1", 20*time.Millisecond)
+	s.Set(ctx, "b", "2")
+	if f, _ := s.Exists(ctx, "a"); !f {
+		t.Fatal("a expired early")
+	}
+	time.Sleep(30 * time.Millisecond)
+	if f, _ := s.Exists(ctx, "a"); f {
+		t.Fatal("a not expired")
+	}
+	if keys, _ := s.Scan(ctx, "*"); len(keys) != 1 || keys[0] != "b" {
+		t.Errorf("keys %v", keys)
+	}
+}
+
+func TestMemoryStoreIncr(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+	for i, want := range []int64{2, 4, 6} {
+		if n, err := s.Incr(ctx, "n", 2); err != nil || n != want {
+			t.Fatalf("incr %d = %d,%v", i, n, err)
+		}
+	}
+	if v, _ := s.Get(ctx, "n"); v != "6" {
+		t.Errorf("n = %q", v)
+	}
+	s.Set(ctx, "s", "a")
+	if _, err := s.Incr(ctx, "s", 1); !errors.Is(err, ErrNotInteger) {
+		t.Errorf("incr s err = %v", err)
+	}
+}
+
+func TestMemoryStoreGetDel(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+	s.Set(ctx, "a", "1")
+	//of concurrent callers only one gets the value
+	var (
+		wg  sync.WaitGroup
+		got int32
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if v, err := s.GetDel(ctx, "a"); err == nil && v == "1" {
+				atomic.AddInt32(&got, 1)
+			}
+		}()
+	}
+	wg.Wait()
+	if got != 1 {
+		t.Errorf("value got %d times, want 1", got)
+	}
+	if _, err := s.GetDel(ctx, "a"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("a err = %v, want not found", err)
+	}
+}
+
+func TestMemoryStorePubSub(t *testing.T) {
+	ctx := context.Background()
+	s := NewMemoryStore(0)
+	sub, err := s.Subscribe(ctx, "a", "b")
+	if err != nil {
+		t.Fatal(err)
+	}
+	s.Publish(ctx, "a", "1")
+	s.Publish(ctx, "c", "2")
+	s.Publish(ctx, "b", "3")
+	for _, want := range []Message{{"a", "1"}, {"b", "3"}} {
+		if m := <-sub.Channel(); m != want {
+			t.Errorf("message %v, want %v", m, want)
+		}
+	}
+	sub.Close()
+	if _, ok := <-sub.Channel(); ok {
+		t.Error("channel not closed")
+	}
+	//publishing without subscribers doesn't block
+	s.Publish(ctx, "a", "4")
+}
@@ -2,37 +2,118 @@ package kv
 
 import (
 	"context"
-	"jutkey-server/conf"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"sync"
 	"time"
 )
 
-var ctx = context.Background()
+const redisScanCount = 100 //keys returned by a SCAN call
 
-type RedisParams struct {
-	Key   string `json:"key"`
-	Value string `json:"value"`
+// RedisStore keeps the cache in redis,it's shared by the servers using the same db.
+// It borrows the client,NewRedisStore callers close it themselves
+type RedisStore struct {
+	rc *redis.Client
 }
 
-func (rp *RedisParams) Set() error {
-	return conf.GetRedisDbConn().Conn().Set(ctx, rp.Key, rp.Value, 0).Err()
+type redisSubscription struct {
+	ps   *redis.PubSub
+	ch   chan Message
+	done chan struct{}
+	once sync.Once
 }
 
-func (rp *RedisParams) SetExp(exp time.Duration) error {
-	return conf.GetRedisDbConn().Conn().Set(ctx, rp.Key, rp.Value, exp).Err()
+func NewRedisStore(rc *redis.Client) *RedisStore {
+	return &RedisStore{rc: rc}
 }
 
-func (rp *RedisParams) Get() error {
-	val, err := conf.GetRedisDbConn().Conn().Get(ctx, rp.Key).Result()
-	if err != nil {
-		return err
+func (s *RedisStore) Get(ctx context.Context, key string) (string, error) {
+	val, err := s.rc.Get(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
 	}
-	rp.Value = val
+	return val, err
+}
+
+// GetDel requires redis 6.2
+func (s *RedisStore) GetDel(ctx context.Context, key string) (string, error) {
+	val, err := s.rc.GetDel(ctx, key).Result()
+	if errors.Is(err, redis.Nil) {
+		return "", ErrNotFound
+	}
+	return val, err
+}
+
+func (s *RedisStore) Set(ctx context.Context, key, value string) error {
+	return s.SetExp(ctx, key, value, 0)
+}
+
+func (s *RedisStore) SetExp(ctx context.Context, key, value string, exp time.Duration) error {
+	return s.rc.Set(ctx, key, value, exp).Err()
+}
+
+func (s *RedisStore) Del(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return s.rc.Del(ctx, keys...).Err()
+}
+
+func (s *RedisStore) Exists(ctx context.Context, key string) (bool, error) {
+	n, err := s.rc.Exists(ctx, key).Result()
+	return n > 0, err
+}
+
+func (s *RedisStore) Scan(ctx context.Context, pattern string) ([]string, error) {
+	var keys []string
+	iter := s.rc.Scan(ctx, 0, pattern, redisScanCount).Iterator()
+	for iter.Next(ctx) {
+		keys = append(keys, iter.Val())
+	}
+	return keys, iter.Err()
+}
+
+func (s *RedisStore) Incr(ctx context.Context, key string, n int64) (int64, error) {
+	return s.rc.IncrBy(ctx, key, n).Result()
+}
+
+func (s *RedisStore) Publish(ctx context.Context, channel, message string) error {
+	return s.rc.Publish(ctx, channel, message).Err()
+}
+
+func (s *RedisStore) Subscribe(ctx context.Context, channels ...string) (Subscription, error) {
+	ps := s.rc.Subscribe(ctx, channels...)
+	//waits for the confirmation,the messages published after it are received
+	if _, err := ps.Receive(ctx); err != nil {
+		ps.Close()
+		return nil, err
+	}
+	sub := &redisSubscription{ps: ps, ch: make(chan Message), done: make(chan struct{})}
+	go func() {
+		defer close(sub.ch)
+		for m := range ps.Channel() {
+			select {
+			case sub.ch <- Message{Channel: m.Channel, Payload: m.Payload}:
+			case <-sub.done:
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Close doesn't close the client,it's owned by the redis config that closes it at exit
+func (s *RedisStore) Close() error {
 	return nil
 }
-func (rp *RedisParams) Del() error {
-	return conf.GetRedisDbConn().Conn().Del(ctx, rp.Key).Err()
+
+func (p *redisSubscription) Channel() <-chan Message {
+	return p.ch
 }
 
-func (rp *RedisParams) Size() (int64, error) {
-	return conf.GetRedisDbConn().Conn().DBSize(ctx).Result()
+func (p *redisSubscription) Close() error {
+	p.once.Do(func() {
+		close(p.done)
+	})
+	return p.ps.Close()
 }
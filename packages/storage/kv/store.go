@@ -0,0 +1,86 @@
+package kv
+
+import (
+	"context"
+	"errors"
+	"jutkey-server/conf"
+	"time"
+)
+
+const (
+	BackendRedis  = "redis"
+	BackendMemory = "memory"
+
+	defaultMaxKeys = 10000
+)
+
+var (
+	ErrNotFound      = errors.New("kv: key not found")
+	ErrNotInteger    = errors.New("kv: value is not an integer")
+	ErrRedisDisabled = errors.New("redis is disabled")
+	Default          Store
+)
+
+// Message is a message published to a channel
+type Message struct {
+	Channel string
+	Payload string
+}
+
+// Subscription receives the messages of the subscribed channels until it's closed
+type Subscription interface {
+	Channel() <-chan Message
+	Close() error
+}
+
+// Store is the cache of the server
+type Store interface {
+	// Get returns ErrNotFound when the key is not stored or has expired
+	Get(ctx context.Context, key string) (string, error)
+	// GetDel returns the value of the key and deletes it atomically,only one of concurrent callers gets it
+	GetDel(ctx context.Context, key string) (string, error)
+	Set(ctx context.Context, key, value string) error
+	// SetExp stores the value for exp,0 keeps it until it's deleted
+	SetExp(ctx context.Context, key, value string, exp time.Duration) error
+	Del(ctx context.Context, keys ...string) error
+	Exists(ctx context.Context, key string) (bool, error)
+	// Scan returns the keys matching the glob pattern
+	Scan(ctx context.Context, pattern string) ([]string, error)
+	// Incr adds n to the integer value of the key atomically,a missing key counts as 0
+	Incr(ctx context.Context, key string, n int64) (int64, error)
+	Publish(ctx context.Context, channel, message string) error
+	Subscribe(ctx context.Context, channels ...string) (Subscription, error)
+	Close() error
+}
+
+// InitStore opens the store of cache.backend,without a backend redis is used when it's enabled
+func InitStore() error {
+	cfg := conf.GetCacheConf()
+	r := conf.GetRedisDbConn()
+	var backend string
+	if cfg != nil {
+		backend = cfg.Backend
+	}
+	if backend == "" {
+		backend = BackendMemory
+		if r != nil && r.Enable {
+			backend = BackendRedis
+		}
+	}
+	switch backend {
+	case BackendRedis:
+		if r == nil || !r.Enable || r.Conn() == nil {
+			return ErrRedisDisabled
+		}
+		Default = NewRedisStore(r.Conn())
+	case BackendMemory:
+		maxKeys := defaultMaxKeys
+		if cfg != nil && cfg.MaxKeys > 0 {
+			maxKeys = cfg.MaxKeys
+		}
+		Default = NewMemoryStore(maxKeys)
+	default:
+		return errors.New("cache backend not supported:" + backend)
+	}
+	return nil
+}
@@ -1,10 +1,9 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
-	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
 	"github.com/shopspring/decimal"
-	log "github.com/sirupsen/logrus"
 	"strconv"
 	"time"
 )
@@ -50,13 +49,13 @@ func AirdropTableExist() bool {
 	return true
 }
 
-func (p *AirdropInfo) Get(account string) (bool, error) {
-	return isFound(GetDB(nil).Where("account = ?", account).Take(p))
+func (p *AirdropInfo) Get(ctx context.Context, account string) (bool, error) {
+	return isFound(GetDBContext(ctx).Where("account = ?", account).Take(p))
 }
 
-func (p *AirdropInfo) GetAirdropInfo() (*AirdropInfoResponse, error) {
+func (p *AirdropInfo) GetAirdropInfo(ctx context.Context) (*AirdropInfoResponse, error) {
 	rets := &AirdropInfoResponse{}
-	f, err := p.Get(p.Account)
+	f, err := p.Get(ctx, p.Account)
 	if err != nil {
 		return rets, err
 	}
@@ -66,20 +65,13 @@ func (p *AirdropInfo) GetAirdropInfo() (*AirdropInfoResponse, error) {
 
 	rets.Total = p.TotalAmount.String()
 	rets.IsGet = p.TotalAmount.Sub(p.BalanceAmount).String()
-	var speed int64 = 1
-	switch p.Priority {
-	case 2:
-		speed = 5
-	case 3:
-		speed = 10
-	case 5:
-		speed = 20
-	}
+	speeds := GetAirdropSpeedTable()
+	speed := speeds.ByPriority(p.Priority).Speed
 
 	now := time.Now()
-	rets.X5Lock = p.LockAmount.Mul(decimal.NewFromInt(2)).String()
-	rets.X10Lock = p.LockAmount.Mul(decimal.NewFromInt(3)).String()
-	rets.X20Lock = p.LockAmount.Mul(decimal.NewFromInt(5)).String()
+	rets.X5Lock = p.LockAmount.Mul(decimal.NewFromInt(speeds.BySpeed(5).LockMultiple)).String()
+	rets.X10Lock = p.LockAmount.Mul(decimal.NewFromInt(speeds.BySpeed(10).LockMultiple)).String()
+	rets.X20Lock = p.LockAmount.Mul(decimal.NewFromInt(speeds.BySpeed(20).LockMultiple)).String()
 
 	unlocking := p.LatestAt > now.Unix()
 	rets.CanSpeedUp = (p.Priority == 0) && unlocking
@@ -136,16 +128,7 @@ func (p *AirdropInfo) GetAirdropInfo() (*AirdropInfoResponse, error) {
 		}
 		surplus = p.PeriodCount - unLock //lock period
 
-		var nowUnLockPeriod int64 = 1
-		switch p.Priority {
-		case 2:
-			nowUnLockPeriod = 5
-		case 3:
-			nowUnLockPeriod = 10
-		case 5:
-			nowUnLockPeriod = 20
-		}
-		rets.PerGet = getSpeedGet(nowUnLockPeriod).String()
+		rets.PerGet = getSpeedGet(speed).String()
 
 		x5lock := decimal.NewFromInt(surplus).Div(decimal.NewFromInt(5))
 		rets.X5Period = x5lock.Ceil().IntPart()
@@ -159,15 +142,7 @@ func (p *AirdropInfo) GetAirdropInfo() (*AirdropInfoResponse, error) {
 		rets.X20Period = x20lock.Ceil().IntPart()
 		rets.X20Get = getSpeedGet(20).String()
 
-		rets.Surplus = surplus
-		switch p.Priority {
-		case 2:
-			rets.Surplus = rets.X5Period
-		case 3:
-			rets.Surplus = rets.X10Period
-		case 5:
-			rets.Surplus = rets.X20Period
-		}
+		rets.Surplus = decimal.NewFromInt(surplus).Div(decimal.NewFromInt(speed)).Ceil().IntPart()
 
 	} else {
 		zeroStr := decimal.Zero.String()
@@ -181,9 +156,9 @@ func (p *AirdropInfo) GetAirdropInfo() (*AirdropInfoResponse, error) {
 	return rets, nil
 }
 
-func (p *AirdropInfo) GetAirdropBalance() (*AirdropBalanceResponse, error) {
+func (p *AirdropInfo) GetAirdropBalance(ctx context.Context) (*AirdropBalanceResponse, error) {
 	rets := &AirdropBalanceResponse{}
-	f, err := p.Get(p.Account)
+	f, err := p.Get(ctx, p.Account)
 	if err != nil {
 		return rets, err
 	}
@@ -212,38 +187,3 @@ func (p *AirdropInfo) GetAirdropBalance() (*AirdropBalanceResponse, error) {
 
 	return rets, nil
 }
-
-func getGenerateBlockTime() {
-	var (
-		p1 = &sqldb.PlatformParameter{}
-		p2 = &sqldb.PlatformParameter{}
-	)
-	f, err := p1.Get(nil, "max_block_generation_time")
-	if err != nil {
-		log.Info("Get max block generation time failed:", err.Error())
-		return
-	}
-	if f {
-		maxGenerationTime, _ := strconv.ParseInt(p1.Value, 10, 64)
-		if maxGenerationTime > 0 {
-			maxGenerationTime = maxGenerationTime / 1000
-		} else {
-			log.Info("max block generation time invalid:", p1.Value)
-			return
-		}
-		f, err = p2.Get(nil, "gap_between_blocks")
-		if err != nil {
-			log.Info("Get gap between blocks failed:", err.Error())
-			return
-		}
-		if f {
-			betweenTime, _ := strconv.ParseInt(p2.Value, 10, 64)
-			if betweenTime == 0 {
-				log.Info("gap between blocks time invalid:", p2.Value)
-				return
-			}
-			//generateBlockTime = betweenTime + maxGenerationTime
-		}
-	}
-
-}
@@ -0,0 +1,243 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// airdropSpeedParam is the app parameter of the ecosystem 1 Airdrop application holding the speed up options,
+// example:[{"priority":2,"speed":5,"lock_multiple":2}]
+const (
+	airdropApp        = "Airdrop"
+	airdropSpeedParam = "airdrop_speed_up"
+)
+
+type AirdropSpeedUp struct {
+	Priority     int64 `json:"priority"`
+	Speed        int64 `json:"speed"`         //periods unlocked per period
+	LockMultiple int64 `json:"lock_multiple"` //lock amount multiple required
+}
+
+type AirdropSpeedTable []AirdropSpeedUp
+
+// defaultAirdropSpeed are the options of the airdrop contract,used for the speeds missing from the parameter
+var defaultAirdropSpeed = AirdropSpeedTable{
+	{Priority: 2, Speed: 5, LockMultiple: 2},
+	{Priority: 3, Speed: 10, LockMultiple: 3},
+	{Priority: 5, Speed: 20, LockMultiple: 5},
+}
+
+// GetAirdropSpeedTable reads the speed up options from the Airdrop application parameters
+func GetAirdropSpeedTable() AirdropSpeedTable {
+	var app Applications
+	f, err := app.GetByName(airdropApp, 1)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("get airdrop app failed")
+		return defaultAirdropSpeed
+	}
+	if !f {
+		log.WithFields(log.Fields{"app": airdropApp}).Warn("airdrop app not found,default speed up options used")
+		return defaultAirdropSpeed
+	}
+	value, err := getAppValue(app.ID, airdropSpeedParam, 1)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Warn("get airdrop speed up param failed")
+		return defaultAirdropSpeed
+	}
+	if value == "" {
+		log.WithFields(log.Fields{"param": airdropSpeedParam}).Warn("airdrop speed up param not found,default speed up options used")
+		return defaultAirdropSpeed
+	}
+	return parseAirdropSpeedTable(value)
+}
+
+// parseAirdropSpeedTable keeps the valid options of the parameter,every default speed it lacks is added
+func parseAirdropSpeedTable(value string) AirdropSpeedTable {
+	var list AirdropSpeedTable
+	if err := json.Unmarshal([]byte(value), &list); err != nil {
+		log.WithFields(log.Fields{"error": err, "value": value}).Warn("airdrop speed up param invalid")
+	}
+	var (
+		table AirdropSpeedTable
+		found = make(map[int64]bool)
+	)
+	for _, v := range list {
+		if v.Speed <= 1 || v.LockMultiple <= 0 || found[v.Speed] {
+			log.WithFields(log.Fields{"value": value, "speed": v.Speed}).Warn("airdrop speed up option invalid")
+			continue
+		}
+		found[v.Speed] = true
+		table = append(table, v)
+	}
+	for _, v := range defaultAirdropSpeed {
+		if !found[v.Speed] {
+			table = append(table, v)
+		}
+	}
+	sort.Slice(table, func(i, j int) bool {
+		return table[i].Speed < table[j].Speed
+	})
+	return table
+}
+
+// ByPriority returns the option of the priority,no speed up if not found
+func (t AirdropSpeedTable) ByPriority(priority int64) AirdropSpeedUp {
+	for _, v := range t {
+		if v.Priority == priority {
+			return v
+		}
+	}
+	return AirdropSpeedUp{Priority: priority, Speed: 1, LockMultiple: 0}
+}
+
+func (t AirdropSpeedTable) BySpeed(speed int64) AirdropSpeedUp {
+	for _, v := range t {
+		if v.Speed == speed {
+			return v
+		}
+	}
+	return AirdropSpeedUp{Speed: speed}
+}
+
+type AirdropPeriod struct {
+	Index  int    `json:"index"`
+	Period string `json:"period"`
+	Start  int64  `json:"start"`
+	End    int64  `json:"end"`
+	Amount string `json:"amount"`
+	Status int    `json:"status"`
+	State  string `json:"state"` //claimed,claimable,unlocking,locked
+}
+
+type AirdropUnlockPoint struct {
+	Time       int64  `json:"time"`
+	Amount     string `json:"amount"`
+	Cumulative string `json:"cumulative"`
+}
+
+type AirdropSimulation struct {
+	Priority   int64                `json:"priority"`
+	Speed      int64                `json:"speed"`
+	LockAmount string               `json:"lock_amount"` //amount locked to enable the speed up
+	Current    bool                 `json:"current"`
+	Periods    int                  `json:"periods"`
+	FinishAt   int64                `json:"finish_at"`
+	Timeline   []AirdropUnlockPoint `json:"timeline"`
+}
+
+type AirdropScheduleResponse struct {
+	Account      string              `json:"account"`
+	Total        string              `json:"total"`
+	Balance      string              `json:"balance"`
+	ClaimableNow string              `json:"claimable_now"`
+	Priority     int64               `json:"priority"`
+	Speed        int64               `json:"speed"`
+	Multipliers  AirdropSpeedTable   `json:"multipliers"`
+	Periods      []AirdropPeriod     `json:"periods"`
+	Simulations  []AirdropSimulation `json:"simulations"`
+}
+
+// GetAirdropSchedule returns every unlock period of the account and simulates the remaining
+// unlock timeline under each speed up option
+func (p *AirdropInfo) GetAirdropSchedule(ctx context.Context) (*AirdropScheduleResponse, error) {
+	f, err := p.Get(ctx, p.Account)
+	if err != nil {
+		return nil, err
+	}
+	if !f {
+		return nil, nil
+	}
+	var dList []detailInfo
+	if p.Detail != "" {
+		if err = json.Unmarshal([]byte(p.Detail), &dList); err != nil {
+			return nil, err
+		}
+	}
+	speeds := GetAirdropSpeedTable()
+	rets := &AirdropScheduleResponse{
+		Account:     p.Account,
+		Total:       p.TotalAmount.String(),
+		Balance:     p.BalanceAmount.String(),
+		Priority:    p.Priority,
+		Speed:       speeds.ByPriority(p.Priority).Speed,
+		Multipliers: speeds,
+	}
+	rets.Periods, rets.ClaimableNow = airdropPeriods(dList, time.Now().Unix())
+
+	options := append(AirdropSpeedTable{{Priority: 0, Speed: 1}}, speeds...)
+	for _, v := range options {
+		sim := simulateAirdropUnlock(rets.Periods, v.Speed)
+		sim.Priority = v.Priority
+		sim.LockAmount = p.LockAmount.Mul(decimal.NewFromInt(v.LockMultiple)).String()
+		sim.Current = v.Speed == rets.Speed
+		rets.Simulations = append(rets.Simulations, sim)
+	}
+	return rets, nil
+}
+
+func airdropPeriods(dList []detailInfo, now int64) ([]AirdropPeriod, string) {
+	var (
+		list      []AirdropPeriod
+		claimable decimal.Decimal
+	)
+	for i, v := range dList {
+		st, _ := strconv.ParseInt(v.Start, 10, 64)
+		end, _ := strconv.ParseInt(v.End, 10, 64)
+		period := AirdropPeriod{
+			Index:  i + 1,
+			Period: v.Period,
+			Start:  st,
+			End:    end,
+			Amount: v.Amount,
+			Status: v.Status,
+		}
+		switch {
+		case now >= end && v.Status == 1:
+			period.State = "claimable"
+			amount, _ := decimal.NewFromString(v.Amount)
+			claimable = claimable.Add(amount)
+		case now >= end:
+			period.State = "claimed"
+		case now >= st:
+			period.State = "unlocking"
+		default:
+			period.State = "locked"
+		}
+		list = append(list, period)
+	}
+	return list, claimable.String()
+}
+
+// simulateAirdropUnlock unlocks speed periods at the end of each remaining period
+func simulateAirdropUnlock(periods []AirdropPeriod, speed int64) AirdropSimulation {
+	sim := AirdropSimulation{Speed: speed}
+	var remaining []AirdropPeriod
+	for _, v := range periods {
+		if v.State == "unlocking" || v.State == "locked" {
+			remaining = append(remaining, v)
+		}
+	}
+	cumulative := decimal.Zero
+	step := int(speed)
+	for i := 0; i*step < len(remaining); i++ {
+		amount := decimal.Zero
+		for j := i * step; j < (i+1)*step && j < len(remaining); j++ {
+			a, _ := decimal.NewFromString(remaining[j].Amount)
+			amount = amount.Add(a)
+		}
+		cumulative = cumulative.Add(amount)
+		sim.Timeline = append(sim.Timeline, AirdropUnlockPoint{
+			Time:       remaining[i].End,
+			Amount:     amount.String(),
+			Cumulative: cumulative.String(),
+		})
+		sim.FinishAt = remaining[i].End
+	}
+	sim.Periods = len(sim.Timeline)
+	return sim
+}
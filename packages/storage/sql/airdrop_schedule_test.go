@@ -0,0 +1,59 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestAirdropPeriods(t *testing.T) {
+	list := []detailInfo{
+		{Start: "0", End: "100", Amount: "10", Status: 1},
+		{Start: "100", End: "200", Amount: "10", Status: 2},
+		{Start: "200", End: "300", Amount: "10", Status: 1},
+		{Start: "300", End: "400", Amount: "10", Status: 1},
+		{Start: "400", End: "500", Amount: "10", Status: 1},
+	}
+	periods, claimable := airdropPeriods(list, 250)
+	if claimable != "10" {
+		t.Fatalf("claimable = %s, want 10", claimable)
+	}
+	want := []string{"claimable", "claimed", "unlocking", "locked", "locked"}
+	for i, v := range periods {
+		if v.State != want[i] {
+			t.Fatalf("period %d state = %s, want %s", i, v.State, want[i])
+		}
+	}
+
+	sim := simulateAirdropUnlock(periods, 2)
+	if sim.Periods != 2 || sim.FinishAt != 400 {
+		t.Fatalf("simulation = %+v", sim)
+	}
+	if sim.Timeline[0].Amount != "20" || sim.Timeline[1].Cumulative != "30" {
+		t.Fatalf("timeline = %+v", sim.Timeline)
+	}
+}
+
+func TestAirdropSpeedTable(t *testing.T) {
+	if s := defaultAirdropSpeed.ByPriority(3); s.Speed != 10 || s.LockMultiple != 3 {
+		t.Fatalf("priority 3 = %+v", s)
+	}
+	if s := defaultAirdropSpeed.ByPriority(0); s.Speed != 1 {
+		t.Fatalf("priority 0 = %+v", s)
+	}
+}
+
+func TestParseAirdropSpeedTable(t *testing.T) {
+	//speed 10 is missing and the speed 20 option is invalid,both fall back to the defaults
+	table := parseAirdropSpeedTable(`[{"priority":2,"speed":5,"lock_multiple":4},{"priority":5,"speed":20,"lock_multiple":0}]`)
+	want := map[int64]int64{5: 4, 10: 3, 20: 5}
+	if len(table) != len(want) {
+		t.Fatalf("table = %+v", table)
+	}
+	for speed, multiple := range want {
+		if s := table.BySpeed(speed); s.LockMultiple != multiple {
+			t.Errorf("speed %d = %+v, want lock multiple %d", speed, s, multiple)
+		}
+	}
+	if table = parseAirdropSpeedTable("{"); len(table) != len(defaultAirdropSpeed) {
+		t.Errorf("invalid json table = %+v", table)
+	}
+}
@@ -0,0 +1,466 @@
+package sql
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/conf"
+	"jutkey-server/packages/services"
+	"net/mail"
+	"time"
+)
+
+const (
+	AlertWalletReceived = "wallet_received"
+	AlertWalletSent     = "wallet_sent"
+	AlertNodeHonorLost  = "node_honor_lost"
+	AlertNftStakeEnded  = "nft_stake_ended"
+)
+
+var (
+	alertQueue        chan AlertEvent
+	alertStakeCheckAt int64
+)
+
+// AlertRule is a wallet subscription to one event type
+type AlertRule struct {
+	ID         int64  `gorm:"primary_key;not null"`
+	Wallet     string `gorm:"column:wallet;not null"`
+	KeyId      int64  `gorm:"column:key_id;not null;index"`
+	Ecosystem  int64  `gorm:"not null"` //0:all ecosystems
+	EventType  string `gorm:"column:event_type;not null;index"`
+	Threshold  string `gorm:"type:decimal(40);default:'0';not null"` //minimum amount for wallet_received/wallet_sent
+	Channel    string `gorm:"not null"`                              //webhook,email
+	Target     string `gorm:"not null"`                              //webhook url or email address
+	Secret     string `gorm:"not null" json:"-"`                     //webhook signing secret
+	StartBlock int64  `gorm:"not null"`                              //events at or below this block are never alerted
+	Enabled    bool   `gorm:"not null"`
+	CreatedAt  int64  `gorm:"not null"`
+}
+
+// AlertDeadLetter keeps the messages that could not be delivered after all retries
+type AlertDeadLetter struct {
+	ID        int64  `gorm:"primary_key;not null"`
+	RuleId    int64  `gorm:"column:rule_id;not null;index"`
+	Channel   string `gorm:"not null"`
+	Target    string `gorm:"not null"`
+	EventType string `gorm:"column:event_type;not null"`
+	Payload   string `gorm:"type:jsonb;not null"`
+	Attempts  int    `gorm:"not null"`
+	LastError string `gorm:"not null"`
+	CreatedAt int64  `gorm:"not null"`
+}
+
+type AlertEvent struct {
+	RuleId       int64  `json:"rule_id"`
+	EventType    string `json:"event_type"`
+	Wallet       string `json:"wallet"`
+	Ecosystem    int64  `json:"ecosystem,omitempty"`
+	TokenSymbol  string `json:"token_symbol,omitempty"`
+	Block        int64  `json:"block,omitempty"`
+	Hash         string `json:"hash,omitempty"`
+	Amount       string `json:"amount,omitempty"`
+	Counterparty string `json:"counterparty,omitempty"`
+	NodeId       int64  `json:"node_id,omitempty"`
+	NodeName     string `json:"node_name,omitempty"`
+	NftMinerId   int64  `json:"nft_miner_id,omitempty"`
+	Time         int64  `json:"time"`
+
+	rule *AlertRule
+}
+
+type alertTransfer struct {
+	Block       int64
+	Hash        []byte
+	SenderId    int64
+	RecipientId int64
+	Amount      decimal.Decimal
+	Ecosystem   int64
+	CreatedAt   int64
+}
+
+func (p *AlertRule) TableName() string {
+	return "alert_rules"
+}
+
+func (p *AlertDeadLetter) TableName() string {
+	return "alert_dead_letters"
+}
+
+func InitAlert() error {
+	ac := conf.GetAlertConf()
+	if ac == nil || !ac.Enable {
+		return nil
+	}
+	workers := ac.Workers
+	if workers <= 0 {
+		workers = 1
+	}
+	alertQueue = make(chan AlertEvent, 1000)
+	alertStakeCheckAt = time.Now().Unix()
+	for i := 0; i < workers; i++ {
+		go alertDeliveryReceive()
+	}
+	return nil
+}
+
+func alertEnabled() bool {
+	return alertQueue != nil
+}
+
+func ValidAlertEventType(eventType string) bool {
+	switch eventType {
+	case AlertWalletReceived, AlertWalletSent, AlertNodeHonorLost, AlertNftStakeEnded:
+		return true
+	}
+	return false
+}
+
+func (p *AlertRule) Create() error {
+	p.KeyId = converter.StringToAddress(p.Wallet)
+	if p.KeyId == 0 {
+		return errors.New("request params wallet invalid")
+	}
+	if !ValidAlertEventType(p.EventType) {
+		return fmt.Errorf("unknown alert event type:%s", p.EventType)
+	}
+	switch p.Channel {
+	case services.AlertChannelWebhook:
+		err := services.CheckWebhookTarget(context.Background(), p.Target)
+		if err != nil {
+			return err
+		}
+		if p.Secret == "" {
+			secret := make([]byte, 16)
+			if _, err = rand.Read(secret); err != nil {
+				return err
+			}
+			p.Secret = hex.EncodeToString(secret)
+		}
+	case services.AlertChannelEmail:
+		if _, err := mail.ParseAddress(p.Target); err != nil {
+			return fmt.Errorf("email address invalid:%s", p.Target)
+		}
+	default:
+		return fmt.Errorf("unknown alert channel:%s", p.Channel)
+	}
+	if p.Threshold == "" {
+		p.Threshold = "0"
+	}
+	threshold, err := decimal.NewFromString(p.Threshold)
+	if err != nil || threshold.LessThan(decimal.Zero) {
+		return fmt.Errorf("threshold invalid:%s", p.Threshold)
+	}
+	p.Threshold = threshold.String()
+
+	var bk Block
+	if _, err = bk.GetMaxBlock(); err != nil {
+		return err
+	}
+	p.StartBlock = bk.ID
+	p.Enabled = true
+	p.CreatedAt = time.Now().Unix()
+	return GetDB(nil).Create(p).Error
+}
+
+func (p *AlertRule) Delete(id int64, wallet string) (bool, error) {
+	db := GetDB(nil).Where("id = ? AND wallet = ?", id, wallet).Delete(&AlertRule{})
+	return db.RowsAffected > 0, db.Error
+}
+
+func (p *AlertRule) SetEnabled(id int64, wallet string, enabled bool) (bool, error) {
+	db := GetDB(nil).Model(&AlertRule{}).Where("id = ? AND wallet = ?", id, wallet).Update("enabled", enabled)
+	return db.RowsAffected > 0, db.Error
+}
+
+// GetAlertRules reads the primary,the wallet lists its rules right after changing them
+func GetAlertRules(ctx context.Context, wallet string, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []AlertRule
+		p    AlertRule
+	)
+	rets.Page = page
+	rets.Limit = limit
+	q := GetDB(nil).WithContext(ctx).Table(p.TableName()).Where("wallet = ?", wallet)
+	if err := q.Count(&rets.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := q.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	rets.List = list
+	return &rets, nil
+}
+
+func GetAlertDeadLetters(ctx context.Context, wallet string, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []AlertDeadLetter
+		p    AlertDeadLetter
+		rule AlertRule
+	)
+	rets.Page = page
+	rets.Limit = limit
+	q := GetDBContext(ctx).Table(p.TableName()).
+		Where("rule_id IN(?)", GetDBContext(ctx).Table(rule.TableName()).Select("id").Where("wallet = ?", wallet))
+	if err := q.Count(&rets.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := q.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	rets.List = list
+	return &rets, nil
+}
+
+func getEnabledAlertRules(eventTypes ...string) ([]AlertRule, error) {
+	var list []AlertRule
+	err := GetDB(nil).Where("enabled = ? AND event_type IN(?)", true, eventTypes).Find(&list).Error
+	return list, err
+}
+
+func sendAlertEvent(ev AlertEvent) {
+	select {
+	case alertQueue <- ev:
+	default:
+		log.WithFields(log.Fields{"type": services.AlertError, "rule_id": ev.RuleId, "event": ev.EventType}).Warn("alert queue is full, event dropped")
+	}
+}
+
+func alertDeliveryReceive() {
+	for ev := range alertQueue {
+		rule := ev.rule
+		body, err := json.Marshal(ev)
+		if err != nil {
+			log.WithFields(log.Fields{"type": services.AlertError, "error": err}).Error("alert event json marshal failed")
+			continue
+		}
+		msg := &services.AlertMessage{
+			Channel:   rule.Channel,
+			Target:    rule.Target,
+			Secret:    rule.Secret,
+			EventType: ev.EventType,
+			Subject:   fmt.Sprintf("[Jutkey] %s %s", ev.EventType, ev.Wallet),
+			Body:      body,
+		}
+		attempts, err := services.DeliverAlert(context.Background(), msg)
+		if err != nil {
+			dl := AlertDeadLetter{
+				RuleId:    rule.ID,
+				Channel:   rule.Channel,
+				Target:    rule.Target,
+				EventType: ev.EventType,
+				Payload:   string(body),
+				Attempts:  attempts,
+				LastError: err.Error(),
+				CreatedAt: time.Now().Unix(),
+			}
+			if err = GetDB(nil).Create(&dl).Error; err != nil {
+				log.WithFields(log.Fields{"type": services.AlertError, "error": err, "rule_id": rule.ID}).Error("alert dead letter insert failed")
+			}
+		}
+	}
+}
+
+func (p *AlertRule) matchTransfer(tr alertTransfer) (AlertEvent, bool) {
+	var (
+		ev           AlertEvent
+		counterparty int64
+	)
+	if tr.Block <= p.StartBlock {
+		return ev, false
+	}
+	if p.Ecosystem > 0 && p.Ecosystem != tr.Ecosystem {
+		return ev, false
+	}
+	switch p.EventType {
+	case AlertWalletReceived:
+		if tr.RecipientId != p.KeyId || tr.SenderId == p.KeyId {
+			return ev, false
+		}
+		counterparty = tr.SenderId
+	case AlertWalletSent:
+		if tr.SenderId != p.KeyId || tr.RecipientId == p.KeyId {
+			return ev, false
+		}
+		counterparty = tr.RecipientId
+	default:
+		return ev, false
+	}
+	threshold, _ := decimal.NewFromString(p.Threshold)
+	if tr.Amount.LessThan(threshold) {
+		return ev, false
+	}
+	ev = AlertEvent{
+		RuleId:       p.ID,
+		EventType:    p.EventType,
+		Wallet:       p.Wallet,
+		Ecosystem:    tr.Ecosystem,
+		TokenSymbol:  Tokens.Get(tr.Ecosystem),
+		Block:        tr.Block,
+		Hash:         hex.EncodeToString(tr.Hash),
+		Amount:       tr.Amount.String(),
+		Counterparty: converter.AddressToString(counterparty),
+		Time:         MsToSeconds(tr.CreatedAt),
+	}
+	return ev, true
+}
+
+func alertTransfers(list []alertTransfer) {
+	if !alertEnabled() || len(list) == 0 {
+		return
+	}
+	rules, err := getEnabledAlertRules(AlertWalletReceived, AlertWalletSent)
+	if err != nil {
+		log.WithFields(log.Fields{"type": services.AlertError, "error": err}).Error("get wallet alert rules failed")
+		return
+	}
+	for i := range rules {
+		for _, tr := range list {
+			if ev, ok := rules[i].matchTransfer(tr); ok {
+				ev.rule = &rules[i]
+				sendAlertEvent(ev)
+			}
+		}
+	}
+}
+
+// alertAccountHistory evaluates the account transfers of blocks (startBlock,endBlock] once tx_data has reached them
+func alertAccountHistory(startBlock, endBlock int64) {
+	if !alertEnabled() || endBlock <= startBlock {
+		return
+	}
+	var (
+		list []alertTransfer
+		his  History
+	)
+	err := GetDB(nil).Table(his.TableName()).
+		Select("block_id AS block,txhash AS hash,sender_id,recipient_id,amount,ecosystem,created_at").
+		Where("block_id > ? AND block_id <= ? AND type <> 24 AND amount > 0", startBlock, endBlock).
+		Order("id asc").Find(&list).Error
+	if err != nil {
+		log.WithFields(log.Fields{"type": services.AlertError, "error": err, "start": startBlock, "end": endBlock}).Error("get alert account history failed")
+		return
+	}
+	alertTransfers(list)
+}
+
+// alertUtxoHistory evaluates utxo_history rows right after they are stored
+func alertUtxoHistory(data []UtxoHistory) {
+	if !alertEnabled() {
+		return
+	}
+	list := make([]alertTransfer, 0, len(data))
+	for _, v := range data {
+		if v.Type == formatSpentInfoHistoryType(UtxoTransferSelf) {
+			continue
+		}
+		amount, _ := decimal.NewFromString(v.Amount)
+		list = append(list, alertTransfer{
+			Block:       v.Block,
+			Hash:        v.Hash,
+			SenderId:    v.SenderId,
+			RecipientId: v.RecipientId,
+			Amount:      amount,
+			Ecosystem:   v.Ecosystem,
+			CreatedAt:   v.CreatedAt,
+		})
+	}
+	alertTransfers(list)
+}
+
+// alertNodeHonorLost notifies the voters of a node that is no longer displayed as honor node
+func alertNodeHonorLost(node NodeValue) {
+	if !alertEnabled() {
+		return
+	}
+	rules, err := getEnabledAlertRules(AlertNodeHonorLost)
+	if err != nil {
+		log.WithFields(log.Fields{"type": services.AlertError, "error": err}).Error("get node alert rules failed")
+		return
+	}
+	if len(rules) == 0 {
+		return
+	}
+	var voters []string
+	if node.ConsensusMode == 2 && NodeReady {
+		err = GetDB(nil).Table("1_candidate_node_decisions").Select("account").
+			Where("request_id = ? AND decision_type = 1 AND decision <> 3", node.Id).Find(&voters).Error
+		if err != nil {
+			log.WithFields(log.Fields{"type": services.AlertError, "error": err, "node_id": node.Id}).Error("get node voters failed")
+			return
+		}
+	}
+	isVoter := make(map[string]bool, len(voters))
+	for _, v := range voters {
+		isVoter[v] = true
+	}
+	now := time.Now().Unix()
+	for i := range rules {
+		if !isVoter[rules[i].Wallet] {
+			continue
+		}
+		sendAlertEvent(AlertEvent{
+			RuleId:    rules[i].ID,
+			EventType: AlertNodeHonorLost,
+			Wallet:    rules[i].Wallet,
+			NodeId:    node.Id,
+			NodeName:  node.NodeName,
+			Time:      now,
+			rule:      &rules[i],
+		})
+	}
+}
+
+// CheckNftStakeAlert notifies stakers whose nft miner staking period ended since the previous check
+func CheckNftStakeAlert() {
+	if !alertEnabled() || !NftMinerReady {
+		return
+	}
+	now := time.Now().Unix()
+	from := alertStakeCheckAt
+	rules, err := getEnabledAlertRules(AlertNftStakeEnded)
+	if err != nil {
+		log.WithFields(log.Fields{"type": services.AlertError, "error": err}).Error("get nft stake alert rules failed")
+		return
+	}
+	alertStakeCheckAt = now
+	if len(rules) == 0 {
+		return
+	}
+	var (
+		list []NftMinerStaking
+		sk   NftMinerStaking
+	)
+	err = GetDB(nil).Table(sk.TableName()).Select("token_id,staker,stake_amount,end_dated").
+		Where("end_dated > ? AND end_dated <= ? AND staking_status = 1", from, now).Find(&list).Error
+	if err != nil {
+		log.WithFields(log.Fields{"type": services.AlertError, "error": err}).Error("get ended nft miner staking failed")
+		return
+	}
+	for i := range rules {
+		for _, v := range list {
+			if v.Staker != rules[i].Wallet {
+				continue
+			}
+			sendAlertEvent(AlertEvent{
+				RuleId:      rules[i].ID,
+				EventType:   AlertNftStakeEnded,
+				Wallet:      rules[i].Wallet,
+				Ecosystem:   1,
+				TokenSymbol: Tokens.Get(1),
+				Amount:      v.StakeAmount,
+				NftMinerId:  v.TokenId,
+				Time:        v.EndDated,
+				rule:        &rules[i],
+			})
+		}
+	}
+}
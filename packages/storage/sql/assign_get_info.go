@@ -1,9 +1,7 @@
 package sql
 
 import (
-	"encoding/json"
 	"errors"
-	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
 	"github.com/shopspring/decimal"
 	//"time"
 )
@@ -66,19 +64,7 @@ func (m *AssignGetInfo) GetBalance(db *DbTransaction, keyId int64) (bool, decima
 	}
 
 	//assign_rule
-	var sp sqldb.StateParameter
-	sp.SetTablePrefix(`1`)
-	found1, err1 := sp.Get(nil, `assign_rule`)
-	if err1 != nil {
-		return false, balance, totalBalance, err1
-	}
-
-	if !found1 || len(sp.Value) == 0 {
-		return false, balance, totalBalance, errors.New("assign_rule not found or not exist assign_rule")
-	}
-
-	rules := make(map[int64]AssignRules, 10)
-	err = json.Unmarshal([]byte(sp.Value), &rules)
+	rules, err := getAssignRules()
 	if err != nil {
 		return false, balance, totalBalance, err
 	}
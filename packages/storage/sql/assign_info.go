@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/shopspring/decimal"
@@ -29,7 +30,7 @@ func (m AssignInfo) TableName() string {
 }
 
 // GetId is retrieving model from database
-func (m *AssignInfo) GetBalance(db *DbTransaction, account string) (bool, decimal.Decimal, decimal.Decimal, error) {
+func (m *AssignInfo) GetBalance(ctx context.Context, account string) (bool, decimal.Decimal, decimal.Decimal, error) {
 
 	var mps []AssignInfo
 	var amount, balance decimal.Decimal
@@ -38,7 +39,7 @@ func (m *AssignInfo) GetBalance(db *DbTransaction, account string) (bool, decima
 	if !HasTable(m) {
 		return false, amount, balance, nil
 	}
-	err := GetDB(nil).Table(m.TableName()).
+	err := GetDBContext(ctx).Table(m.TableName()).
 		Where("account =? AND deleted =? AND balance_amount > 0", account, 0).
 		Find(&mps).Error
 	if err != nil {
@@ -49,8 +50,7 @@ func (m *AssignInfo) GetBalance(db *DbTransaction, account string) (bool, decima
 	}
 
 	//genesis time
-	block := &Block{}
-	genesisAt, err := block.GetSystemTime()
+	genesisAt, _, err := GetBlockTimeEstimator().TimeOf(ctx, 1)
 	if err != nil {
 		return false, amount, balance, err
 	}
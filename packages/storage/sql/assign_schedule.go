@@ -0,0 +1,301 @@
+package sql
+
+import (
+	"context"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
+	"sort"
+	"strconv"
+	"time"
+)
+
+// assignClaimContract matches the log_transactions contract names of the ecosystem 1 assign contracts
+const assignClaimContract = "@1Assign%"
+
+type AssignTranche struct {
+	Source         string `json:"source"` //assign_info,assign_get_info
+	Type           int64  `json:"type"`
+	StartBlock     int64  `json:"start_block,omitempty"`
+	EndBlock       int64  `json:"end_block,omitempty"`
+	StartAt        int64  `json:"start_at"` //estimated by the block time estimator for assign_get_info
+	EndAt          int64  `json:"end_at"`
+	Interval       int64  `json:"interval,omitempty"` //blocks between two unlocks
+	IntervalAmount string `json:"interval_amount"`
+	Total          string `json:"total"`
+	Claimed        string `json:"claimed"`
+	Remaining      string `json:"remaining"`
+	Claimable      string `json:"claimable"` //unlocked and not claimed yet
+	Status         string `json:"status"`    //locked,claimable,claimed
+}
+
+type AssignClaim struct {
+	Amount  string `json:"amount"`
+	BlockId int64  `json:"block_id"`
+	Hash    string `json:"hash"`
+	Time    int64  `json:"time"`
+	Comment string `json:"comment"`
+}
+
+type AssignSeriesPoint struct {
+	Time       int64  `json:"time"`
+	Amount     string `json:"amount"`
+	Cumulative string `json:"cumulative"`
+}
+
+type AssignScheduleResponse struct {
+	Total     string              `json:"total"`
+	Claimed   string              `json:"claimed"`
+	Remaining string              `json:"remaining"`
+	Tranches  []AssignTranche     `json:"tranches"`
+	Claims    []AssignClaim       `json:"claims"`
+	Series    []AssignSeriesPoint `json:"series"` //projected unlock timeline of the remaining amount
+}
+
+// GetAssignSchedule returns the vesting tranches of both assign tables,the claim history and the projected unlock series
+func GetAssignSchedule(ctx context.Context, wallet string) (*AssignScheduleResponse, error) {
+	keyId := converter.StringToAddress(wallet)
+	if keyId == 0 {
+		return nil, errors.New("wallet invalid:" + wallet)
+	}
+	var (
+		rets      AssignScheduleResponse
+		total     = decimal.Zero
+		remaining = decimal.Zero
+		unlocks   = make(map[int64]decimal.Decimal)
+	)
+	now := time.Now().Unix()
+
+	var info AssignInfo
+	if HasTable(&info) {
+		var list []AssignInfo
+		err := GetDBContext(ctx).Table(info.TableName()).Where("account = ? AND deleted = 0", wallet).Order("id asc").Find(&list).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, t := range list {
+			details, err := getAssignDetail(t.Detail, t.Type)
+			if err != nil {
+				return nil, err
+			}
+			for _, v := range details {
+				st, _ := strconv.ParseInt(v.StartAt, 10, 64)
+				am, _ := decimal.NewFromString(v.Amount)
+				tr := AssignTranche{
+					Source:         info.TableName(),
+					Type:           t.Type,
+					StartAt:        st,
+					EndAt:          st,
+					IntervalAmount: am.String(),
+					Total:          am.String(),
+					Claimed:        "0",
+					Remaining:      am.String(),
+					Claimable:      "0",
+				}
+				switch {
+				case v.Status != 1:
+					tr.Status = "claimed"
+					tr.Claimed = am.String()
+					tr.Remaining = "0"
+				case st <= now:
+					tr.Status = "claimable"
+					tr.Claimable = am.String()
+				default:
+					tr.Status = "locked"
+					unlocks[st] = unlocks[st].Add(am)
+				}
+				rets.Tranches = append(rets.Tranches, tr)
+			}
+			total = total.Add(t.TotalAmount)
+			remaining = remaining.Add(t.BalanceAmount)
+		}
+	}
+
+	var legacy AssignGetInfo
+	if HasTable(&legacy) {
+		var list []AssignGetInfo
+		err := GetDBContext(ctx).Table(legacy.TableName()).Where("keyid = ? AND deleted = 0", keyId).Order("id asc").Find(&list).Error
+		if err != nil {
+			return nil, err
+		}
+		if len(list) > 0 {
+			rules, err := getAssignRules()
+			if err != nil {
+				return nil, err
+			}
+			estimator := GetBlockTimeEstimator()
+			lastId, _, err := estimator.Latest(ctx)
+			if err != nil {
+				return nil, err
+			}
+			timeOf := func(blockId int64) int64 {
+				t, _, err := estimator.TimeOf(ctx, blockId)
+				if err != nil {
+					logs.WithContext(ctx).WithFields(log.Fields{"error": err, "block": blockId}).Warn("assign schedule estimate block time failed")
+				}
+				return t
+			}
+			for _, t := range list {
+				rule, ok := rules[t.Type]
+				if !ok {
+					continue
+				}
+				tr := AssignTranche{
+					Source:         legacy.TableName(),
+					Type:           t.Type,
+					StartBlock:     rule.StartBlockID,
+					EndBlock:       rule.EndBlockID,
+					StartAt:        timeOf(rule.StartBlockID),
+					EndAt:          timeOf(rule.EndBlockID),
+					Interval:       rule.IntervalBlockID,
+					IntervalAmount: t.Amount.String(),
+					Total:          t.TotalAmount.String(),
+					Claimed:        t.TotalAmount.Sub(t.BalanceAmount).String(),
+					Remaining:      t.BalanceAmount.String(),
+				}
+				claimable := legacyClaimable(t, rule, lastId)
+				tr.Claimable = claimable.String()
+				switch {
+				case t.BalanceAmount.LessThanOrEqual(decimal.Zero):
+					tr.Status = "claimed"
+				case claimable.GreaterThan(decimal.Zero):
+					tr.Status = "claimable"
+				default:
+					tr.Status = "locked"
+				}
+				legacyUnlocks(t, rule, lastId, timeOf, unlocks)
+				rets.Tranches = append(rets.Tranches, tr)
+				total = total.Add(t.TotalAmount)
+				remaining = remaining.Add(t.BalanceAmount)
+			}
+		}
+	}
+
+	claims, err := getAssignClaims(ctx, keyId)
+	if err != nil {
+		return nil, err
+	}
+	rets.Claims = claims
+	rets.Total = total.String()
+	rets.Remaining = remaining.String()
+	rets.Claimed = total.Sub(remaining).String()
+	rets.Series = assignSeries(unlocks)
+	return &rets, nil
+}
+
+func getAssignRules() (map[int64]AssignRules, error) {
+	var sp sqldb.StateParameter
+	sp.SetTablePrefix(`1`)
+	f, err := sp.Get(nil, `assign_rule`)
+	if err != nil {
+		return nil, err
+	}
+	if !f || len(sp.Value) == 0 {
+		return nil, errors.New("assign_rule not found or not exist assign_rule")
+	}
+	rules := make(map[int64]AssignRules, 10)
+	if err = json.Unmarshal([]byte(sp.Value), &rules); err != nil {
+		return nil, err
+	}
+	return rules, nil
+}
+
+// legacyNextUnlock returns the block of the first unlock not claimed yet
+func legacyNextUnlock(t AssignGetInfo, rule AssignRules) int64 {
+	if t.Latestid > 0 {
+		return t.Latestid + rule.IntervalBlockID
+	}
+	return rule.StartBlockID
+}
+
+// legacyClaimable returns the remaining balance unlocked by lastId,one interval amount per interval
+// since the last claim and the whole balance once the end block is reached
+func legacyClaimable(t AssignGetInfo, rule AssignRules, lastId int64) decimal.Decimal {
+	if t.BalanceAmount.LessThanOrEqual(decimal.Zero) {
+		return decimal.Zero
+	}
+	if lastId >= rule.EndBlockID {
+		return t.BalanceAmount
+	}
+	next := legacyNextUnlock(t, rule)
+	if rule.IntervalBlockID <= 0 || lastId < next {
+		return decimal.Zero
+	}
+	am := t.Amount.Mul(decimal.NewFromInt((lastId-next)/rule.IntervalBlockID + 1))
+	if am.GreaterThan(t.BalanceAmount) {
+		return t.BalanceAmount
+	}
+	return am
+}
+
+// legacyUnlocks adds the future interval unlocks of a legacy assign row,the last one releases the remainder
+func legacyUnlocks(t AssignGetInfo, rule AssignRules, lastId int64, timeOf func(int64) int64, unlocks map[int64]decimal.Decimal) {
+	if rule.IntervalBlockID <= 0 || t.BalanceAmount.LessThanOrEqual(decimal.Zero) {
+		return
+	}
+	left := t.BalanceAmount.Sub(legacyClaimable(t, rule, lastId))
+	next := legacyNextUnlock(t, rule)
+	for next <= lastId {
+		next += rule.IntervalBlockID
+	}
+	for ; next <= rule.EndBlockID && left.GreaterThan(decimal.Zero); next += rule.IntervalBlockID {
+		am := t.Amount
+		if am.GreaterThan(left) || next+rule.IntervalBlockID > rule.EndBlockID {
+			am = left
+		}
+		at := timeOf(next)
+		unlocks[at] = unlocks[at].Add(am)
+		left = left.Sub(am)
+	}
+	if left.GreaterThan(decimal.Zero) {
+		at := timeOf(rule.EndBlockID)
+		unlocks[at] = unlocks[at].Add(left)
+	}
+}
+
+func assignSeries(unlocks map[int64]decimal.Decimal) []AssignSeriesPoint {
+	times := make([]int64, 0, len(unlocks))
+	for t := range unlocks {
+		times = append(times, t)
+	}
+	sort.Slice(times, func(i, j int) bool { return times[i] < times[j] })
+	var (
+		series     []AssignSeriesPoint
+		cumulative = decimal.Zero
+	)
+	for _, t := range times {
+		cumulative = cumulative.Add(unlocks[t])
+		series = append(series, AssignSeriesPoint{Time: t, Amount: unlocks[t].String(), Cumulative: cumulative.String()})
+	}
+	return series
+}
+
+func getAssignClaims(ctx context.Context, keyId int64) ([]AssignClaim, error) {
+	var (
+		his  History
+		list []History
+		rets []AssignClaim
+	)
+	err := GetDBContext(ctx).Table(his.TableName()).Select("amount,block_id,txhash,created_at,comment").
+		Where("ecosystem = 1 AND recipient_id = ? AND txhash IN(?)", keyId,
+			GetDBContext(ctx).Table("log_transactions").Select("hash").Where("ecosystem_id = 1 AND contract_name LIKE ?", assignClaimContract)).
+		Order("id desc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	for _, v := range list {
+		rets = append(rets, AssignClaim{
+			Amount:  v.Amount.String(),
+			BlockId: v.BlockId,
+			Hash:    hex.EncodeToString(v.Txhash),
+			Time:    MsToSeconds(v.CreatedAt),
+			Comment: v.Comment,
+		})
+	}
+	return rets, nil
+}
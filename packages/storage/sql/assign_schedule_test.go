@@ -0,0 +1,48 @@
+package sql
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestLegacyUnlocks(t *testing.T) {
+	timeOf := func(blockId int64) int64 { return 1000 + (blockId-100)*2 }
+	rule := AssignRules{StartBlockID: 50, EndBlockID: 160, IntervalBlockID: 30}
+	row := AssignGetInfo{BalanceAmount: decimal.NewFromInt(25), Amount: decimal.NewFromInt(10), Latestid: 80}
+	unlocks := make(map[int64]decimal.Decimal)
+	legacyUnlocks(row, rule, 100, timeOf, unlocks)
+
+	series := assignSeries(unlocks)
+	if len(series) != 2 {
+		t.Fatalf("series = %+v", series)
+	}
+	//blocks 110 and 140,the last interval releases the remainder
+	if series[0].Time != 1020 || series[0].Amount != "10" {
+		t.Fatalf("first point = %+v", series[0])
+	}
+	if series[1].Time != 1080 || series[1].Amount != "15" || series[1].Cumulative != "25" {
+		t.Fatalf("last point = %+v", series[1])
+	}
+}
+
+func TestLegacyClaimable(t *testing.T) {
+	rule := AssignRules{StartBlockID: 50, EndBlockID: 160, IntervalBlockID: 30}
+	row := AssignGetInfo{BalanceAmount: decimal.NewFromInt(25), Amount: decimal.NewFromInt(10), Latestid: 80}
+	for _, v := range []struct {
+		lastId int64
+		want   string
+	}{
+		{100, "0"},  //next unlock at 110
+		{110, "10"}, //one interval
+		{145, "20"},
+		{159, "20"},
+		{160, "25"}, //the end block releases the remainder
+	} {
+		if got := legacyClaimable(row, rule, v.lastId); got.String() != v.want {
+			t.Errorf("claimable at %d = %s, want %s", v.lastId, got, v.want)
+		}
+	}
+	if got := legacyClaimable(AssignGetInfo{Amount: decimal.NewFromInt(10)}, rule, 200); !got.IsZero() {
+		t.Errorf("claimed row claimable = %s, want 0", got)
+	}
+}
@@ -12,7 +12,7 @@ func IsExist(f string) bool {
 }
 
 func Savefile(file string, buf []byte) error {
-	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE, 066)
+	f, err := os.OpenFile(file, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, 0644)
 	if err != nil {
 		return err
 	}
@@ -21,30 +21,28 @@ func Savefile(file string, buf []byte) error {
 	return err1
 }
 
-func GetFileNameByHash(hash string) (string, int64, error) {
+// GetAttachment returns the binary of the hash without data,
+// an octet-stream binary is resolved to the typed binary of the same app and hash
+func GetAttachment(hash string) (*Binary, error) {
 	var d, b Binary
 	f, err := d.GetByHash(hash)
 	if err != nil {
-		return "", 0, err
+		return nil, err
 	}
-	if f {
-		if d.MimeType != "application/octet-stream" {
-			file := d.GetByJpeg()
-			return file, d.ID, nil
-		} else {
-			f, err := b.GetByPng(&d)
-			if err != nil {
-				return "", 0, err
-			}
-			if f {
-				if b.MimeType != "application/octet-stream" {
-					file := b.GetByJpeg()
-					return file, d.ID, nil
-				}
-			}
-		}
+	if !f {
+		return nil, nil
+	}
+	if d.MimeType != "application/octet-stream" {
+		return &d, nil
+	}
+	f, err = b.GetByPng(&d)
+	if err != nil {
+		return nil, err
+	}
+	if f && b.MimeType != "application/octet-stream" {
+		return &b, nil
 	}
-	return "", 0, nil
+	return nil, nil
 }
 
 func GetFileHash(id int64) (string, error) {
@@ -62,44 +60,13 @@ func GetFileHash(id int64) (string, error) {
 	return d.Hash, nil
 }
 
-func LoadFile(id int64) (string, error) {
-	var d, b Binary
-	f, err := d.GetByID(id)
+// LoadData loads the binary data by id
+func (b *Binary) LoadData() error {
+	var d Binary
+	err := GetDB(nil).Select("data").Where("id = ?", b.ID).Take(&d).Error
 	if err != nil {
-		return "", err
-	}
-	if f {
-		if d.MimeType != "application/octet-stream" {
-			file := d.GetByJpeg()
-			if !IsExist(UploadDir + file) {
-				//fmt.Printf("save file:%s\n", UploadDir+file)
-				err := Savefile(UploadDir+file, d.Data)
-				if err != nil {
-					return "", err
-				}
-				return file, nil
-			}
-			return file, nil
-		} else {
-			f, err := b.GetByPng(&d)
-			if err != nil {
-				return "", err
-			}
-			if f {
-				if b.MimeType != "application/octet-stream" {
-					file := b.GetByJpeg()
-					if !IsExist(UploadDir + file) {
-						//fmt.Printf("save file:%s\n", UploadDir+file)
-						err := Savefile(UploadDir+file, b.Data)
-						if err != nil {
-							return "", err
-						}
-						return file, nil
-					}
-					return file, nil
-				}
-			}
-		}
+		return err
 	}
-	return "", nil
+	b.Data = d.Data
+	return nil
 }
@@ -4,6 +4,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"github.com/IBAX-io/go-ibax/packages/common/crypto"
+	"mime"
 	"path"
 	"strings"
 
@@ -60,27 +61,34 @@ func (b *Binary) GetByPng(d *Binary) (bool, error) {
 	return isFound(GetDB(nil).Where("ecosystem = ? and app_id=? and hash = ? and mime_type !=?", d.Ecosystem, d.AppId, d.Hash, d.MimeType).First(b))
 }
 
+// mimeExtensions are the preferred file extensions,mime.ExtensionsByType returns them in no particular order
+var mimeExtensions = map[string]string{
+	"image/jpeg":       ".jpeg",
+	"image/jpg":        ".jpg",
+	"image/png":        ".png",
+	"image/gif":        ".gif",
+	"image/webp":       ".webp",
+	"image/svg+xml":    ".svg",
+	"image/bmp":        ".bmp",
+	"image/x-icon":     ".ico",
+	"text/plain":       ".txt",
+	"application/pdf":  ".pdf",
+	"application/json": ".json",
+}
+
+// GetByJpeg returns the file name of the binary,the extension comes from the name or the mime type
 func (b *Binary) GetByJpeg() string {
-	file := ""
-	fileSuffix := path.Ext(b.Name) //
-	if fileSuffix == "" {
-		if b.MimeType == "image/jpeg" || b.MimeType == "image/jpg" || b.MimeType == "image/png" {
-			ns := strings.Split(b.MimeType, "/")
-			if len(ns) == 2 {
-				//file = strconv.FormatInt(b.ID, 10) + "-" + b.Name + "." + ns[1]
-				file = b.Hash + "." + ns[1]
-				return file
-			} else {
-				return file
-			}
-		} else {
-			file = b.Hash
-			return file
-		}
-	} else {
-		file = b.Hash + fileSuffix
-		return file
+	if fileSuffix := path.Ext(b.Name); fileSuffix != "" {
+		return b.Hash + fileSuffix
+	}
+	mimeType := strings.TrimSpace(strings.Split(b.MimeType, ";")[0])
+	if ext, ok := mimeExtensions[mimeType]; ok {
+		return b.Hash + ext
+	}
+	if exts, _ := mime.ExtensionsByType(mimeType); len(exts) > 0 {
+		return b.Hash + exts[0]
 	}
+	return b.Hash
 }
 
 func CompareHash(data []byte, urlHash string) bool {
@@ -0,0 +1,204 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
+	log "github.com/sirupsen/logrus"
+	"strconv"
+	"sync"
+	"time"
+)
+
+const (
+	blockTimeSample    = 1000 //recent blocks used to learn the block time
+	blockTimeMinSample = 10
+	blockTimeRefresh   = 30 * time.Second
+)
+
+// BlockTimeEstimator converts between block ids and unix seconds
+type BlockTimeEstimator interface {
+	// TimeOf returns the time of the block,estimated is true when the block is not generated yet
+	TimeOf(ctx context.Context, blockId int64) (t int64, estimated bool, err error)
+	// BlockAt returns the last block generated at or before t,estimated is true when t is after the latest block.
+	// A time before the first block returns ErrBeforeFirstBlock
+	BlockAt(ctx context.Context, t int64) (blockId int64, estimated bool, err error)
+	// Latest returns the latest block id and the average seconds per block
+	Latest(ctx context.Context) (blockId int64, blockTime float64, err error)
+}
+
+var (
+	blockTimeMu sync.RWMutex
+	blockTime   BlockTimeEstimator = newChainBlockTime(dbBlockSource{})
+
+	ErrBeforeFirstBlock = errors.New("time is before the first block")
+)
+
+// GetBlockTimeEstimator returns the estimator used to convert blocks and wall-clock time
+func GetBlockTimeEstimator() BlockTimeEstimator {
+	blockTimeMu.RLock()
+	defer blockTimeMu.RUnlock()
+	return blockTime
+}
+
+func SetBlockTimeEstimator(e BlockTimeEstimator) {
+	blockTimeMu.Lock()
+	defer blockTimeMu.Unlock()
+	blockTime = e
+}
+
+// blockSource reads the blocks the estimator learns from
+type blockSource interface {
+	// Last returns the latest block
+	Last(ctx context.Context) (Block, bool, error)
+	// From returns the first block with an id not less than id
+	From(ctx context.Context, id int64) (Block, bool, error)
+	// ParamsBlockTime returns the seconds per block of the platform parameters
+	ParamsBlockTime() float64
+}
+
+// dbBlockSource reads block_chain with the context of the request
+type dbBlockSource struct{}
+
+func (dbBlockSource) Last(ctx context.Context) (Block, bool, error) {
+	var bk Block
+	f, err := isFound(GetDBContext(ctx).Select("id,time").Last(&bk))
+	return bk, f, err
+}
+
+func (dbBlockSource) From(ctx context.Context, id int64) (Block, bool, error) {
+	var bk Block
+	f, err := isFound(GetDBContext(ctx).Select("id,time").Where("id >= ?", id).Order("id asc").First(&bk))
+	return bk, f, err
+}
+
+func (dbBlockSource) ParamsBlockTime() float64 {
+	return paramsBlockTime()
+}
+
+// chainBlockTime learns the average block time from block_chain,
+// the platform parameters are used until enough blocks are generated
+type chainBlockTime struct {
+	src       blockSource
+	mu        sync.Mutex
+	lastId    int64
+	lastTime  int64
+	blockTime float64
+	updatedAt time.Time
+}
+
+func newChainBlockTime(src blockSource) *chainBlockTime {
+	return &chainBlockTime{src: src}
+}
+
+func (c *chainBlockTime) load(ctx context.Context) (lastId, lastTime int64, avg float64, err error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.lastId > 0 && time.Since(c.updatedAt) < blockTimeRefresh {
+		return c.lastId, c.lastTime, c.blockTime, nil
+	}
+	last, f, err := c.src.Last(ctx)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if !f {
+		return 0, 0, 0, errors.New("maxblockid not found")
+	}
+	avg = c.src.ParamsBlockTime()
+	first, _, err := c.src.From(ctx, last.ID-blockTimeSample)
+	if err != nil {
+		return 0, 0, 0, err
+	}
+	if last.ID-first.ID >= blockTimeMinSample && last.Time > first.Time {
+		avg = float64(last.Time-first.Time) / float64(last.ID-first.ID)
+	}
+	c.lastId, c.lastTime, c.blockTime, c.updatedAt = last.ID, last.Time, avg, time.Now()
+	return c.lastId, c.lastTime, c.blockTime, nil
+}
+
+func (c *chainBlockTime) Latest(ctx context.Context) (int64, float64, error) {
+	lastId, _, avg, err := c.load(ctx)
+	return lastId, avg, err
+}
+
+func (c *chainBlockTime) TimeOf(ctx context.Context, blockId int64) (int64, bool, error) {
+	lastId, lastTime, avg, err := c.load(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if blockId > lastId {
+		return lastTime + int64(float64(blockId-lastId)*avg), true, nil
+	}
+	bk, f, err := c.src.From(ctx, blockId)
+	if err != nil {
+		return 0, false, err
+	}
+	if !f {
+		return 0, false, errors.New("block not found")
+	}
+	return bk.Time, false, nil
+}
+
+// BlockAt binary searches block_chain by id,block times are not decreasing
+func (c *chainBlockTime) BlockAt(ctx context.Context, t int64) (int64, bool, error) {
+	lastId, lastTime, avg, err := c.load(ctx)
+	if err != nil {
+		return 0, false, err
+	}
+	if t >= lastTime {
+		if avg <= 0 {
+			return lastId, true, nil
+		}
+		return lastId + int64(float64(t-lastTime)/avg), t > lastTime, nil
+	}
+	lo, hi := int64(1), lastId
+	ret := int64(0)
+	for lo <= hi {
+		mid := lo + (hi-lo)/2
+		bk, f, err := c.src.From(ctx, mid)
+		if err != nil {
+			return 0, false, err
+		}
+		if !f || bk.ID > hi {
+			hi = mid - 1
+			continue
+		}
+		if bk.Time <= t {
+			ret = bk.ID
+			lo = bk.ID + 1
+		} else {
+			hi = mid - 1
+		}
+	}
+	if ret == 0 {
+		return 0, false, ErrBeforeFirstBlock
+	}
+	return ret, false, nil
+}
+
+// paramsBlockTime returns the block time from the platform parameters
+func paramsBlockTime() float64 {
+	var (
+		p1 = &sqldb.PlatformParameter{}
+		p2 = &sqldb.PlatformParameter{}
+	)
+	var generation, gap float64
+	f, err := p1.Get(nil, "max_block_generation_time")
+	if err != nil {
+		log.Info("Get max block generation time failed:", err.Error())
+	} else if f {
+		ms, _ := strconv.ParseInt(p1.Value, 10, 64)
+		generation = float64(ms) / 1000
+	}
+	f, err = p2.Get(nil, "gap_between_blocks")
+	if err != nil {
+		log.Info("Get gap between blocks failed:", err.Error())
+	} else if f {
+		s, _ := strconv.ParseInt(p2.Value, 10, 64)
+		gap = float64(s)
+	}
+	if gap+generation <= 0 {
+		return 1
+	}
+	return gap + generation
+}
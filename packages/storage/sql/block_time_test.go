@@ -0,0 +1,112 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"testing"
+)
+
+// fakeBlockSource serves blocks sorted by id,ids may have gaps
+type fakeBlockSource struct {
+	blocks []Block
+	params float64
+}
+
+func (s *fakeBlockSource) Last(ctx context.Context) (Block, bool, error) {
+	if len(s.blocks) == 0 {
+		return Block{}, false, nil
+	}
+	return s.blocks[len(s.blocks)-1], true, nil
+}
+
+func (s *fakeBlockSource) From(ctx context.Context, id int64) (Block, bool, error) {
+	for _, b := range s.blocks {
+		if b.ID >= id {
+			return b, true, nil
+		}
+	}
+	return Block{}, false, nil
+}
+
+func (s *fakeBlockSource) ParamsBlockTime() float64 {
+	return s.params
+}
+
+// blocks 1..100 every 2 seconds from 1000,without block 50
+func testBlockTime() *chainBlockTime {
+	src := &fakeBlockSource{params: 5}
+	for id := int64(1); id <= 100; id++ {
+		if id != 50 {
+			src.blocks = append(src.blocks, Block{ID: id, Time: 1000 + (id-1)*2})
+		}
+	}
+	return newChainBlockTime(src)
+}
+
+func TestBlockTimeLatest(t *testing.T) {
+	ctx := context.Background()
+	lastId, avg, err := testBlockTime().Latest(ctx)
+	if err != nil || lastId != 100 || avg != 2 {
+		t.Errorf("latest %d %v %v, want 100 2", lastId, avg, err)
+	}
+	//too few blocks,the platform parameters are used
+	c := newChainBlockTime(&fakeBlockSource{blocks: []Block{{ID: 1, Time: 1000}, {ID: 2, Time: 1001}}, params: 5})
+	if _, avg, _ = c.Latest(ctx); avg != 5 {
+		t.Errorf("avg %v, want the params 5", avg)
+	}
+	if _, _, err = newChainBlockTime(&fakeBlockSource{}).Latest(ctx); err == nil {
+		t.Error("no blocks,want an error")
+	}
+}
+
+func TestBlockTimeTimeOf(t *testing.T) {
+	ctx := context.Background()
+	c := testBlockTime()
+	for _, v := range []struct {
+		block     int64
+		time      int64
+		estimated bool
+	}{
+		{1, 1000, false},
+		{10, 1018, false},
+		{100, 1198, false},
+		{50, 1100, false}, //the next block of a missing id
+		{101, 1200, true},
+		{200, 1398, true},
+	} {
+		tm, estimated, err := c.TimeOf(ctx, v.block)
+		if err != nil || tm != v.time || estimated != v.estimated {
+			t.Errorf("block %d time %d %v %v, want %d %v", v.block, tm, estimated, err, v.time, v.estimated)
+		}
+	}
+}
+
+func TestBlockTimeBlockAt(t *testing.T) {
+	ctx := context.Background()
+	c := testBlockTime()
+	for _, v := range []struct {
+		time      int64
+		block     int64
+		estimated bool
+	}{
+		{1000, 1, false},
+		{1001, 1, false},
+		{1018, 10, false},
+		{1019, 10, false},
+		{1098, 49, false},
+		{1099, 49, false}, //block 50 is missing
+		{1100, 51, false},
+		{1197, 99, false},
+		{1198, 100, false},
+		{1199, 100, true},
+		{1210, 106, true},
+	} {
+		block, estimated, err := c.BlockAt(ctx, v.time)
+		if err != nil || block != v.block || estimated != v.estimated {
+			t.Errorf("time %d block %d %v %v, want %d %v", v.time, block, estimated, err, v.block, v.estimated)
+		}
+	}
+	if _, _, err := c.BlockAt(ctx, 999); !errors.Is(err, ErrBeforeFirstBlock) {
+		t.Errorf("time before the first block err %v", err)
+	}
+}
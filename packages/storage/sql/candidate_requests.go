@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -11,6 +12,7 @@ import (
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"jutkey-server/packages/logs"
 	"jutkey-server/packages/params"
 	"reflect"
 	"time"
@@ -53,14 +55,18 @@ type nodeDetailInfo struct {
 	MyVote       decimal.Decimal
 	MyStaking    string
 	Decision     int
+	ProbeStatus  int
+	Latency      int64
+	BlockHeight  int64
+	LastSeen     int64
 }
 
 func (p *CandidateNodeRequests) TableName() string {
 	return "1_candidate_node_requests"
 }
 
-func (p *CandidateNodeRequests) GetPubKeyById(id int64) (bool, error) {
-	return isFound(GetDB(nil).Select("node_pub_key").Where("id = ?", id).First(&p))
+func (p *CandidateNodeRequests) GetPubKeyById(ctx context.Context, id int64) (bool, error) {
+	return isFound(GetDBContext(ctx).Select("node_pub_key").Where("id = ?", id).First(&p))
 }
 
 func InitPledgeAmount() {
@@ -75,7 +81,7 @@ func InitPledgeAmount() {
 	}
 }
 
-func NodeListSearch(page, limit int, wallet string) (*GeneralResponse, error) {
+func NodeListSearch(ctx context.Context, page, limit int, wallet string) (*GeneralResponse, error) {
 	var (
 		list []NodeListResponse
 		rets GeneralResponse
@@ -86,8 +92,9 @@ func NodeListSearch(page, limit int, wallet string) (*GeneralResponse, error) {
 	rets.Page = page
 	rets.Limit = limit
 
-	err := GetDB(nil).Raw(`
+	err := GetDBContext(ctx).Raw(`
 SELECT cs.node_name,cs.id,cs.website,cs.api_address,hr.address,cs.vote,RANK() OVER (ORDER BY vote DESC) AS ranking,cs.packed,
+	coalesce(hr.probe_status,0) AS probe_status,coalesce(hr.latency,0) AS latency,coalesce(hr.block_height,0) AS block_height,coalesce(hr.last_seen,0) AS last_seen,
 	CASE WHEN cs.packed > 0 THEN
 		round(cs.packed*100 / cast( (SELECT max(id) FROM block_chain)  as numeric),2) 
 	ELSE
@@ -122,12 +129,12 @@ SELECT cs.node_name,cs.id,cs.website,cs.api_address,hr.address,cs.vote,RANK() OV
 	FROM "1_candidate_node_requests" AS c1 WHERE deleted = 0
 ) AS cs
 LEFT JOIN(
-	SELECT value,address FROM honor_node_info AS he
+	SELECT value,address,probe_status,latency,block_height,last_seen FROM honor_node_info AS he
 )AS hr ON (cs.id = CAST(hr.value->>'id' AS numeric) AND CAST(hr.value->>'consensus_mode' AS numeric) = 2)
 ORDER BY vote desc,date_updated_referendum asc OFFSET ? LIMIT ?
 `, PledgeAmount, wallet, (page-1)*limit, limit).Find(&info).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Error("Node List Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Error("Node List Search Failed")
 		return &rets, err
 	}
 
@@ -136,7 +143,7 @@ ORDER BY vote desc,date_updated_referendum asc OFFSET ? LIMIT ?
 		VoteTotal decimal.Decimal
 	}
 	var ti totalInfo
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT (SELECT count(1) FROM "1_candidate_node_requests" WHERE deleted = 0) total,case WHEN coalesce(sum(earnest),0) > 0 THEN 
 	round(coalesce(sum(earnest),0) / 1e12,12)
 ELSE
@@ -145,19 +152,19 @@ END as vote_total FROM "1_candidate_node_decisions" WHERE decision_type = 1 AND
 AND request_id IN (SELECT id FROM "1_candidate_node_requests" WHERE deleted = 0)
 `).Take(&ti).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Error("Node List Search Total Info Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Error("Node List Search Total Info Failed")
 		return &rets, err
 	}
 	rets.Total = ti.Total
 	for i := 0; i < len(info); i++ {
 		account := converter.IDToAddress(smart.PubToID(info[i].NodePubKey))
 		if account == "invalid" {
-			log.WithFields(log.Fields{"pub_key": info[i].NodePubKey}).Error("Node List Search Pub Key Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"pub_key": info[i].NodePubKey}).Error("Node List Search Pub Key Failed")
 			return &rets, errors.New("candidate requests pub_key invalid")
 		}
-		rts, err := info[i].getNodeDetailInfo(ti.VoteTotal, account)
+		rts, err := info[i].getNodeDetailInfo(ctx, ti.VoteTotal, account)
 		if err != nil {
-			log.WithFields(log.Fields{"err": err, "info": info[i]}).Error("Get Node Detail Info Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"err": err, "info": info[i]}).Error("Get Node Detail Info Failed")
 			return &rets, err
 		}
 
@@ -167,7 +174,7 @@ AND request_id IN (SELECT id FROM "1_candidate_node_requests" WHERE deleted = 0)
 	return &rets, nil
 }
 
-func (p *nodeDetailInfo) getNodeDetailInfo(voteTotal decimal.Decimal, account string) (NodeListResponse, error) {
+func (p *nodeDetailInfo) getNodeDetailInfo(ctx context.Context, voteTotal decimal.Decimal, account string) (NodeListResponse, error) {
 	var rets NodeListResponse
 	zeroDec := decimal.New(0, 0)
 
@@ -175,7 +182,7 @@ func (p *nodeDetailInfo) getNodeDetailInfo(voteTotal decimal.Decimal, account st
 		FrontCommittee bool
 	}
 	var rlt committee
-	f, _ := isFound(GetDB(nil).Raw(`
+	f, _ := isFound(GetDBContext(ctx).Raw(`
 	SELECT CASE WHEN (SELECT control_mode FROM "1_ecosystems" WHERE id = 1) = 2 THEN
 		CASE WHEN (SELECT count(1) FROM "1_votings_participants" WHERE
 				voting_id = (SELECT id FROM "1_votings" WHERE deleted = 0 AND voting->>'name' like '%%voting_for_control_mode_template%%' AND ecosystem = 1 ORDER BY id DESC LIMIT 1)
@@ -210,6 +217,10 @@ func (p *nodeDetailInfo) getNodeDetailInfo(voteTotal decimal.Decimal, account st
 	rets.Id = p.Id
 	rets.Packed = p.Packed
 	rets.PackedRate = p.PackedRate
+	rets.Status = nodeProbeStatusName(p.ProbeStatus)
+	rets.Latency = p.Latency
+	rets.BlockHeight = p.BlockHeight
+	rets.LastSeen = p.LastSeen
 	rets.MyVote, _ = p.MyVote.Float64()
 	rets.MyStaking = p.MyStaking
 	if p.Decision == 2 || p.Decision == 4 {
@@ -226,7 +237,7 @@ func CandidateTableExist() bool {
 	return true
 }
 
-func NodeDetail(search any, wallet string) (NodeDetailResponse, error) {
+func NodeDetail(ctx context.Context, search any, wallet string) (NodeDetailResponse, error) {
 	var (
 		nodeInfo  nodeDetailInfo
 		rets      NodeDetailResponse
@@ -241,11 +252,11 @@ func NodeDetail(search any, wallet string) (NodeDetailResponse, error) {
 			return rets, err
 		}
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Detail Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Detail Failed")
 		return rets, errors.New("request params invalid")
 	}
 
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT case WHEN coalesce(sum(earnest),0) > 0 THEN
 	round(coalesce(sum(earnest),0) / 1e12,12)
 ELSE
@@ -254,12 +265,13 @@ END as vote_total FROM "1_candidate_node_decisions" WHERE decision_type = 1 AND
 AND request_id IN (SELECT id FROM "1_candidate_node_requests" WHERE deleted = 0)
 `).Take(&voteTotal).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Vote Total Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Vote Total Failed")
 		return rets, err
 	}
 
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT cs.node_name,cs.id,cs.website,cs.api_address,hr.address,cs.vote,cs.packed,
+	coalesce(hr.probe_status,0) AS probe_status,coalesce(hr.latency,0) AS latency,coalesce(hr.block_height,0) AS block_height,coalesce(hr.last_seen,0) AS last_seen,
 	CASE WHEN cs.packed > 0 THEN
 		round(cs.packed*100 / cast( (SELECT max(id) FROM block_chain)  as numeric),2)
 	ELSE
@@ -305,22 +317,22 @@ END committee,cs.node_pub_key,
 	FROM "1_candidate_node_requests" AS cs WHERE deleted = 0 AND id = ?
 ) AS cs
 LEFT JOIN(
-	SELECT value,address FROM honor_node_info AS he
+	SELECT value,address,probe_status,latency,block_height,last_seen FROM honor_node_info AS he
 )AS hr ON (cs.id = CAST(hr.value->>'id' AS numeric) AND CAST(hr.value->>'consensus_mode' AS numeric) = 2)
 `, PledgeAmount, wallet, wallet, wallet, id).Take(&nodeInfo).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Failed")
 		return rets, err
 	}
 	account := converter.IDToAddress(smart.PubToID(nodeInfo.NodePubKey))
 	if account == "invalid" {
-		log.WithFields(log.Fields{"pub_key": nodeInfo.NodePubKey}).Error("Get Node Detail Pub Key Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"pub_key": nodeInfo.NodePubKey}).Error("Get Node Detail Pub Key Failed")
 		return rets, errors.New("candidate requests pub_key invalid")
 	}
 
-	info, err := nodeInfo.getNodeDetailInfo(voteTotal, account)
+	info, err := nodeInfo.getNodeDetailInfo(ctx, voteTotal, account)
 	if err != nil {
-		log.WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Info Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err, "node id": id}).Error("Get Node Detail Info Failed")
 		return rets, err
 	}
 	rets.NodeListResponse = info
@@ -337,7 +349,7 @@ LEFT JOIN(
 	return rets, nil
 }
 
-func GetDaoVoteList(search any, page, limit int) (GeneralResponse, error) {
+func GetDaoVoteList(ctx context.Context, search any, page, limit int) (GeneralResponse, error) {
 	var (
 		can     CandidateNodeRequests
 		list    []NodeVoteResponse
@@ -355,17 +367,17 @@ func GetDaoVoteList(search any, page, limit int) (GeneralResponse, error) {
 	case "json.Number":
 		nodeId, err = search.(json.Number).Int64()
 		if err != nil {
-			log.WithFields(log.Fields{"err": err}).Warn("Get Node Dao Vote List Json Number Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Dao Vote List Json Number Failed")
 			return rets, err
 		}
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Dao Vote List Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Dao Vote List Search Failed")
 		return rets, errors.New("request params invalid")
 	}
 
-	f, err := can.GetPubKeyById(nodeId)
+	f, err := can.GetPubKeyById(ctx, nodeId)
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Error("Get Node Dao Voting Pub Key Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Error("Get Node Dao Voting Pub Key Failed")
 		return rets, err
 	}
 	if !f {
@@ -374,22 +386,22 @@ func GetDaoVoteList(search any, page, limit int) (GeneralResponse, error) {
 
 	account = converter.IDToAddress(smart.PubToID(can.NodePubKey))
 	if account == "invalid" {
-		log.WithFields(log.Fields{"pub_key": can.NodePubKey}).Error("Node Pub Key Invalid")
+		logs.WithContext(ctx).WithFields(log.Fields{"pub_key": can.NodePubKey}).Error("Node Pub Key Invalid")
 		return rets, errors.New("candidate requests pub_key invalid")
 	}
 
-	err = GetDB(nil).Raw(fmt.Sprintf(`
+	err = GetDBContext(ctx).Raw(fmt.Sprintf(`
 SELECT count(1) FROM "1_votings_participants" WHERE 
 	voting_id IN(SELECT id FROM "1_votings" WHERE deleted = 0 AND voting->>'name' like '%%voting_for_control_mode_template%%' AND ecosystem = 1) 
 	AND member->>'account'='%s'
 `, account)).Take(&total).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Error("Get Node Dao Vote List Total Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Error("Get Node Dao Vote List Total Failed")
 		return rets, err
 	}
 	rets.Total = total.Count
 
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT vs.title,vs.created,vs.voted_rate,sub.result_rate,sub.rejected_rate,vs.id,vs.result,coalesce((
 	SELECT CASE WHEN decision = 1 THEN
 		1
@@ -424,7 +436,7 @@ LEFT JOIN (
 	ORDER BY id desc OFFSET ? LIMIT ?
 `, account, account, (page-1)*limit, limit).Find(&list).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Error("Get Node Dao Vote List Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Error("Get Node Dao Vote List Failed")
 		return rets, err
 	}
 	rets.List = list
@@ -432,7 +444,7 @@ LEFT JOIN (
 	return rets, nil
 }
 
-func GetNodeBlockList(search any, page, limit int, order string) (GeneralResponse, error) {
+func GetNodeBlockList(ctx context.Context, search any, page, limit int, order string) (GeneralResponse, error) {
 	var (
 		list   []NodeBlockListResponse
 		rets   GeneralResponse
@@ -453,25 +465,25 @@ func GetNodeBlockList(search any, page, limit int, order string) (GeneralRespons
 	case "json.Number":
 		id, err = search.(json.Number).Int64()
 		if err != nil {
-			log.WithFields(log.Fields{"err": err}).Warn("Get Node Block List Json Number Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Block List Json Number Failed")
 			return rets, err
 		}
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Block List Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Block List Search Failed")
 		return rets, errors.New("request params invalid")
 	}
 	if id <= 0 {
 		return rets, errors.New("unknown node id 0")
 	}
-	err = GetDB(nil).Table(bk.TableName()).Where("node_position = ? AND consensus_mode = 2", id).Count(&rets.Total).Error
+	err = GetDBContext(ctx).Table(bk.TableName()).Where("node_position = ? AND consensus_mode = 2", id).Count(&rets.Total).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Warn("Get Node Block List Total Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Block List Total Failed")
 		return rets, err
 	}
 	if rets.Total > 0 {
-		err = GetDB(nil).Select("id,tx,time").Where("node_position = ? AND consensus_mode = 2", id).Offset((page - 1) * limit).Limit(limit).Order(order).Find(&bkList).Error
+		err = GetDBContext(ctx).Select("id,tx,time").Where("node_position = ? AND consensus_mode = 2", id).Offset((page - 1) * limit).Limit(limit).Order(order).Find(&bkList).Error
 		if err != nil {
-			log.WithFields(log.Fields{"err": err}).Warn("Get Node Block Block List Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Block Block List Failed")
 			return rets, err
 		}
 
@@ -479,9 +491,9 @@ func GetNodeBlockList(search any, page, limit int, order string) (GeneralRespons
 			var rts NodeBlockListResponse
 			rts.BlockId = value.ID
 
-			err = GetDB(nil).Select("hash").Where("block = ?", value.ID).Find(&txList).Error
+			err = GetDBContext(ctx).Select("hash").Where("block = ?", value.ID).Find(&txList).Error
 			if err != nil {
-				log.WithFields(log.Fields{"err": err}).Warn("Get Node Block Tx List Failed")
+				logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Block Tx List Failed")
 				return rets, err
 			}
 			var hashList [][]byte
@@ -494,7 +506,7 @@ func GetNodeBlockList(search any, page, limit int, order string) (GeneralRespons
 				TokenSymbol string
 			}
 			var gasFee []txGasFee
-			err = GetDB(nil).Raw(`
+			err = GetDBContext(ctx).Raw(`
 				SELECT h1.ecosystem,h1.amount,es.token_symbol FROM(
 					SELECT ecosystem,sum(amount)amount FROM "1_history" WHERE txhash IN(?) AND type IN(1,2) GROUP BY ecosystem
 				)AS h1
@@ -502,7 +514,7 @@ func GetNodeBlockList(search any, page, limit int, order string) (GeneralRespons
 					SELECT coalesce(token_symbol,'') token_symbol,id FROM "1_ecosystems"
 				)AS es ON(es.id = h1.ecosystem)`, hashList).Find(&gasFee).Error
 			if err != nil {
-				log.WithFields(log.Fields{"err": err, "block_id": value.ID}).Warn("Get Node Block Tx List Failed")
+				logs.WithContext(ctx).WithFields(log.Fields{"err": err, "block_id": value.ID}).Warn("Get Node Block Tx List Failed")
 				return rets, err
 			}
 			gasFeeCursor := 1
@@ -543,7 +555,7 @@ func GetNodeBlockList(search any, page, limit int, order string) (GeneralRespons
 	return rets, nil
 }
 
-func GetNodeVoteHistory(req *params.HistoryFindForm, getType int) (*NodeStakingHistoryResponse, error) {
+func GetNodeVoteHistory(ctx context.Context, req *params.HistoryFindForm, getType int) (*NodeStakingHistoryResponse, error) {
 	var (
 		nodeId             int64
 		err                error
@@ -575,14 +587,14 @@ func GetNodeVoteHistory(req *params.HistoryFindForm, getType int) (*NodeStakingH
 	case "json.Number":
 		nodeId, err = req.Search.(json.Number).Int64()
 		if err != nil {
-			log.WithFields(log.Fields{"err": err}).Warn("Get Node Vote History json number invalid")
+			logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Node Vote History json number invalid")
 			return nil, err
 		}
 		if nodeId <= 0 {
 			return nil, errors.New("request params node id invalid")
 		}
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(req.Search).String()}).Warn("Get Node Vote History Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(req.Search).String()}).Warn("Get Node Vote History Search Failed")
 		return nil, errors.New("request params Node Id invalid")
 	}
 	rets.Page = req.Page
@@ -600,7 +612,7 @@ func GetNodeVoteHistory(req *params.HistoryFindForm, getType int) (*NodeStakingH
 	}
 
 	if getType == 1 {
-		getNowStakingQuery = GetDB(nil).Raw(`
+		getNowStakingQuery = GetDBContext(ctx).Raw(`
 SELECT earnest as staking,CASE WHEN COALESCE(earnest,0) > 0 THEN
 	round(coalesce(earnest,0) / 1e12,12)
 ELSE
@@ -608,14 +620,14 @@ ELSE
 END AS my_vote FROM "1_candidate_node_decisions" WHERE request_id = ? AND account = ? AND decision_type = 1 AND decision <> 3
 `, nodeId, req.Wallet)
 	} else {
-		getNowStakingQuery = GetDB(nil).Raw(`
+		getNowStakingQuery = GetDBContext(ctx).Raw(`
 SELECT earnest as staking,date_withdraw,decision FROM "1_candidate_node_decisions" WHERE request_id = ? AND account = ? AND decision_type = 2 AND decision <> 3
 `, nodeId, req.Wallet)
 	}
 
 	f, err := isFound(getNowStakingQuery.Take(&info))
 	if err != nil {
-		log.WithFields(log.Fields{"error": err, "wallet": req.Wallet}).Warn("Get Node Vote History Info Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "wallet": req.Wallet}).Warn("Get Node Vote History Info Failed")
 		return nil, errors.New("request params wallet invalid")
 	}
 	if !f {
@@ -623,23 +635,23 @@ SELECT earnest as staking,date_withdraw,decision FROM "1_candidate_node_decision
 		return &rets, nil
 	}
 
-	_, err = isFound(GetDB(nil).Select("block_id,created_at").
+	_, err = isFound(GetDBContext(ctx).Select("block_id,created_at").
 		Where("recipient_id = ? AND type IN(?) AND comment IN(?)", keyId, outType, outComment).
 		Order("block_id desc").First(&h1))
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Withdraw referendum Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Withdraw referendum Failed")
 		return nil, err
 	}
 
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT count(1) FROM "1_history" WHERE sender_id = ? AND TYPE IN(?) AND
 			comment IN(?) AND block_id >= ? AND created_at > ?
 `, keyId, inType, inComment, h1.BlockId, h1.CreatedAt).Count(&rets.Total).Error
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Total Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Total Failed")
 		return nil, err
 	}
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT txhash,created_at,amount,CASE WHEN coalesce(amount,0)>0 THEN
 			round(coalesce(amount,0) / 1e12,12)
 		ELSE
@@ -650,7 +662,7 @@ SELECT txhash,created_at,amount,CASE WHEN coalesce(amount,0)>0 THEN
 OFFSET ? Limit ?
 `, keyId, inType, inComment, h1.BlockId, h1.CreatedAt, (req.Page-1)*req.Limit, req.Limit).Find(&list).Error
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Warn("Get Node Vote History Failed")
 		return nil, err
 	}
 
@@ -681,21 +693,21 @@ OFFSET ? Limit ?
 	return &rets, nil
 }
 
-func GetNodeStatistics() (*NodeStatisticsResponse, error) {
+func GetNodeStatistics(ctx context.Context) (*NodeStatisticsResponse, error) {
 	var (
 		rets         NodeStatisticsResponse
 		eligibleNode int64
 		p            CandidateNodeRequests
 	)
-	err := GetDB(nil).Table(p.TableName()).Where("deleted = 0 AND earnest_total >= ?", PledgeAmount).Count(&eligibleNode).Error
+	err := GetDBContext(ctx).Table(p.TableName()).Where("deleted = 0 AND earnest_total >= ?", PledgeAmount).Count(&eligibleNode).Error
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Get Node Map Eligible Node Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("Get Node Map Eligible Node Failed")
 		return nil, err
 	}
 
-	err = GetDB(nil).Table(p.TableName()).Where("deleted = 0 AND earnest_total < ?", PledgeAmount).Count(&rets.CandidateTotal).Error
+	err = GetDBContext(ctx).Table(p.TableName()).Where("deleted = 0 AND earnest_total < ?", PledgeAmount).Count(&rets.CandidateTotal).Error
 	if err != nil {
-		log.WithFields(log.Fields{"error": err}).Error("Get Node Map Candidate Total Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("Get Node Map Candidate Total Failed")
 		return nil, err
 	}
 
@@ -0,0 +1,278 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/IBAX-io/go-ibax/packages/smart"
+	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
+	"strconv"
+)
+
+const (
+	daoProposalVoting = iota + 1
+	daoProposalAccepted
+	daoProposalWithdrawn
+)
+
+// DaoProposal is a candidate node request,the node joins the honor nodes once it's accepted by the votes
+type DaoProposal struct {
+	Id          int64  `json:"id"`
+	Title       string `json:"title"` //node name
+	Website     string `json:"website"`
+	Creator     string `json:"creator"`
+	NodePubKey  string `json:"-"`
+	DateStarted int64  `json:"date_started"`
+	DateEnded   int64  `json:"date_ended"` //0 until the request is withdrawn
+	Voters      int64  `json:"voters"`
+	Vote        string `json:"vote"`
+	Stakers     int64  `json:"stakers"`
+	Earnest     string `json:"earnest"`
+	Eligible    bool   `json:"eligible"` //earnest reached the pledge amount
+	Ranking     int64  `json:"ranking"`  //by vote among the requests not withdrawn
+	Status      int    `json:"status"`   //1:voting 2:accepted 3:withdrawn
+}
+
+type DaoProposalVoter struct {
+	Account string `json:"account"`
+	Vote    string `json:"vote"`
+}
+
+type DaoWalletVote struct {
+	NodeId   int64  `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Vote     string `json:"vote"`
+	Deleted  bool   `json:"deleted"` //the node withdrew its request
+}
+
+// DaoMembershipChange is a node joining or leaving the honor nodes between two hourly snapshots
+type DaoMembershipChange struct {
+	Time     int64  `json:"time"` //hour start,unix seconds
+	NodeId   int64  `json:"node_id"`
+	NodeName string `json:"node_name"`
+	Added    bool   `json:"added"` //false when the node left
+	Vote     string `json:"vote"`
+}
+
+// daoProposalQuery tallies the referendum votes (decision_type 1) and the substitute stakes (decision_type 2)
+// of every candidate node request,the args are the pledge amount and the honor node seats twice
+const daoProposalQuery = `
+SELECT * FROM (
+	SELECT ps.*,CASE WHEN ps.deleted <> 0 THEN 3 WHEN ps.eligible AND (? <= 0 OR ps.ranking <= ?) THEN 2 ELSE 1 END AS status FROM (
+		SELECT cs.id,cs.node_name AS title,cs.website,cs.node_pub_key,cs.deleted,cs.date_created AS date_started,cs.date_deleted AS date_ended,
+			coalesce(ds.voters,0) AS voters,round(coalesce(ds.vote,0) / 1e12,12) AS vote,coalesce(ss.stakers,0) AS stakers,
+			coalesce(cs.earnest_total,0) AS earnest,cs.deleted = 0 AND coalesce(cs.earnest_total,0) >= ? AS eligible,
+			CASE WHEN cs.deleted <> 0 THEN 0 ELSE
+				row_number() OVER (PARTITION BY cs.deleted ORDER BY coalesce(cs.referendum_total,0) DESC,cs.date_updated_referendum ASC)
+			END AS ranking
+		FROM "1_candidate_node_requests" AS cs
+		LEFT JOIN (
+			SELECT request_id,count(1) AS voters,sum(earnest) AS vote FROM "1_candidate_node_decisions" WHERE decision_type = 1 AND decision <> 3 GROUP BY request_id
+		)AS ds ON (ds.request_id = cs.id)
+		LEFT JOIN (
+			SELECT request_id,count(1) AS stakers FROM "1_candidate_node_decisions" WHERE decision_type = 2 AND decision <> 3 GROUP BY request_id
+		)AS ss ON (ss.request_id = cs.id)
+	)AS ps
+)AS dp`
+
+// daoMembershipQuery compares every hourly snapshot of a candidate node with its previous one,
+// a node first met after the first snapshot hour joined if it's displayed
+const daoMembershipQuery = `
+FROM (
+	SELECT hour,node_id,display,vote,lag(display) OVER (PARTITION BY node_id ORDER BY hour) AS prev
+	FROM honor_node_performance WHERE consensus_mode = 2
+)AS np WHERE (np.prev IS NULL AND np.display AND np.hour > (SELECT min(hour) FROM honor_node_performance WHERE consensus_mode = 2))
+	OR np.prev <> np.display`
+
+var errDaoNotReady = errors.New("candidate node not ready")
+
+// getHonorNodeSeats returns the number_of_nodes platform parameter,0 doesn't limit the honor nodes
+func getHonorNodeSeats(ctx context.Context) (int64, error) {
+	var sp sqldb.PlatformParameter
+	f, err := sp.Get(nil, "number_of_nodes")
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("get number of nodes failed")
+		return 0, err
+	}
+	if !f {
+		return 0, nil
+	}
+	return parseHonorNodeSeats(sp.Value), nil
+}
+
+func parseHonorNodeSeats(value string) int64 {
+	seats, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || seats < 0 {
+		return 0
+	}
+	return seats
+}
+
+func daoProposalArgs(ctx context.Context) ([]any, error) {
+	seats, err := getHonorNodeSeats(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return []any{seats, seats, PledgeAmount}, nil
+}
+
+func setDaoProposalCreator(list []DaoProposal) {
+	for i := range list {
+		if account := converter.IDToAddress(smart.PubToID(list[i].NodePubKey)); account != "invalid" {
+			list[i].Creator = account
+		}
+	}
+}
+
+// GetDaoProposals lists the candidate node requests,status 0 returns all
+func GetDaoProposals(ctx context.Context, status, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []DaoProposal
+	)
+	if status < 0 || status > daoProposalWithdrawn {
+		return nil, errors.New("request params status invalid")
+	}
+	if !NodeReady {
+		return nil, errDaoNotReady
+	}
+	rets.Page = page
+	rets.Limit = limit
+	args, err := daoProposalArgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	where := ` WHERE status = ? OR ? = 0`
+	args = append(args, status, status)
+
+	err = GetDBContext(ctx).Raw(`SELECT count(1) FROM (`+daoProposalQuery+where+`)AS dc`, args...).Take(&rets.Total).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("get dao proposals total failed")
+		return nil, err
+	}
+	args = append(args, (page-1)*limit, limit)
+	err = GetDBContext(ctx).Raw(daoProposalQuery+where+` ORDER BY id DESC OFFSET ? LIMIT ?`, args...).Find(&list).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("get dao proposals failed")
+		return nil, err
+	}
+	setDaoProposalCreator(list)
+	rets.List = list
+	return &rets, nil
+}
+
+func GetDaoProposal(ctx context.Context, id int64) (*DaoProposal, error) {
+	var rets DaoProposal
+	if !NodeReady {
+		return nil, errDaoNotReady
+	}
+	args, err := daoProposalArgs(ctx)
+	if err != nil {
+		return nil, err
+	}
+	f, err := isFound(GetDBContext(ctx).Raw(daoProposalQuery+` WHERE id = ?`, append(args, id)...).Take(&rets))
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "id": id}).Error("get dao proposal failed")
+		return nil, err
+	}
+	if !f {
+		return nil, errors.New("unknown proposal id:" + strconv.FormatInt(id, 10))
+	}
+	list := []DaoProposal{rets}
+	setDaoProposalCreator(list)
+	return &list[0], nil
+}
+
+// GetDaoProposalVoters lists the referendum votes counted for the candidate node,the largest first
+func GetDaoProposalVoters(ctx context.Context, id int64, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []DaoProposalVoter
+	)
+	if !NodeReady {
+		return nil, errDaoNotReady
+	}
+	rets.Page = page
+	rets.Limit = limit
+	q := GetDBContext(ctx).Table("1_candidate_node_decisions").Where("request_id = ? AND decision_type = 1 AND decision <> 3", id)
+	if err := q.Count(&rets.Total).Error; err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "id": id}).Error("get dao proposal voters total failed")
+		return nil, err
+	}
+	err := q.Select("account,round(coalesce(earnest,0) / 1e12,12) AS vote").Order("earnest DESC,id ASC").
+		Offset((page - 1) * limit).Limit(limit).Find(&list).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "id": id}).Error("get dao proposal voters failed")
+		return nil, err
+	}
+	rets.List = list
+	return &rets, nil
+}
+
+// GetDaoWalletVotes returns the referendum votes of the wallet counted on the candidate nodes
+func GetDaoWalletVotes(ctx context.Context, wallet string, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []DaoWalletVote
+	)
+	if converter.StringToAddress(wallet) == 0 {
+		return nil, errors.New("request params wallet invalid")
+	}
+	if !NodeReady {
+		return nil, errDaoNotReady
+	}
+	rets.Page = page
+	rets.Limit = limit
+	q := GetDBContext(ctx).Table(`"1_candidate_node_decisions" AS ds`).
+		Joins(`LEFT JOIN "1_candidate_node_requests" AS cs ON (cs.id = ds.request_id)`).
+		Where("ds.account = ? AND ds.decision_type = 1 AND ds.decision <> 3", wallet)
+	if err := q.Count(&rets.Total).Error; err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "wallet": wallet}).Error("get dao wallet votes total failed")
+		return nil, err
+	}
+	err := q.Select(`ds.request_id AS node_id,coalesce(cs.node_name,'') AS node_name,round(coalesce(ds.earnest,0) / 1e12,12) AS vote,
+	coalesce(cs.deleted,0) <> 0 AS deleted`).Order("ds.earnest DESC,ds.id ASC").
+		Offset((page - 1) * limit).Limit(limit).Find(&list).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "wallet": wallet}).Error("get dao wallet votes failed")
+		return nil, err
+	}
+	rets.List = list
+	return &rets, nil
+}
+
+// GetDaoMembershipChanges reports the candidate nodes joining or leaving the honor nodes,newest first.
+// The changes come from the hourly node performance snapshots,so they're known from the first recorded hour on
+func GetDaoMembershipChanges(ctx context.Context, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		np   NodePerformance
+		list []DaoMembershipChange
+	)
+	rets.Page = page
+	rets.Limit = limit
+	if !HasTableOrView(np.TableName()) {
+		rets.List = []DaoMembershipChange{}
+		return &rets, nil
+	}
+	err := GetDBContext(ctx).Raw(`SELECT count(1) ` + daoMembershipQuery).Take(&rets.Total).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("get dao membership changes total failed")
+		return nil, err
+	}
+	err = GetDBContext(ctx).Raw(`
+SELECT np.hour AS time,np.node_id,coalesce(cs.node_name,'') AS node_name,np.display AS added,np.vote FROM (
+	SELECT hour,node_id,display,vote `+daoMembershipQuery+`
+	ORDER BY hour DESC,node_id ASC OFFSET ? LIMIT ?
+)AS np
+LEFT JOIN "1_candidate_node_requests" AS cs ON (cs.id = np.node_id)
+ORDER BY np.hour DESC,np.node_id ASC`, (page-1)*limit, limit).Find(&list).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err}).Error("get dao membership changes failed")
+		return nil, err
+	}
+	rets.List = list
+	return &rets, nil
+}
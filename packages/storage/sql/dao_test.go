@@ -0,0 +1,13 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestParseHonorNodeSeats(t *testing.T) {
+	for value, want := range map[string]int64{"101": 101, "0": 0, "-1": 0, "": 0, "abc": 0} {
+		if seats := parseHonorNodeSeats(value); seats != want {
+			t.Errorf("seats of %q = %d, want %d", value, seats, want)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"errors"
 	"gorm.io/gorm"
 	"jutkey-server/conf"
@@ -18,12 +19,19 @@ func GormClose() error {
 	return nil
 }
 
-// GetDB is returning gorm.DB
+// GetDB is returning gorm.DB,the queries are sent to the primary
 func GetDB(db *DbTransaction) *gorm.DB {
 	if db != nil && db.conn != nil {
 		return db.conn
 	}
-	return conf.GetDbConn().Conn()
+	return conf.GetDbConn().Primary()
+}
+
+// GetDBContext returns the connection bound to ctx,the queries are traced and logged
+// as children of the request of ctx and cancelled with it.
+// The reads are sent to the read replicas,so it must not be used by the sync that reads its own writes
+func GetDBContext(ctx context.Context) *gorm.DB {
+	return conf.GetDbConn().Conn().WithContext(ctx)
 }
 
 func isFound(db *gorm.DB) (bool, error) {
@@ -1,12 +1,14 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/IBAX-io/go-ibax/packages/converter"
 	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
 	"reflect"
 	"strconv"
 	"sync"
@@ -31,7 +33,7 @@ type Ecosystem struct {
 	ControlMode    int64
 }
 
-//EcosystemKeyTotalResult
+// EcosystemKeyTotalResult
 type EcosystemKeyTotalResult struct {
 	Total int64                  `json:"total"`
 	Page  int                    `json:"page"`
@@ -91,14 +93,14 @@ func (e *Ecosystem) GetTokenExist(id int64) (bool, error) {
 	return isFound(GetDB(nil).Where("token_symbol <> ''").First(e, "id = ?", id))
 }
 
-func (e *Ecosystem) GetFind(limit, page int, order string, where map[string]any) ([]Ecosystem, int64, error) {
+func (e *Ecosystem) GetFind(ctx context.Context, limit, page int, order string, where map[string]any) ([]Ecosystem, int64, error) {
 	var rs []Ecosystem
 	var total int64
 	if len(where) == 0 {
-		if err := GetDB(nil).Table(e.TableName()).Count(&total).Error; err != nil {
+		if err := GetDBContext(ctx).Table(e.TableName()).Count(&total).Error; err != nil {
 			return nil, 0, err
 		}
-		if err := GetDB(nil).Order(order).Offset((page - 1) * limit).Limit(limit).Find(&rs).Error; err != nil {
+		if err := GetDBContext(ctx).Order(order).Offset((page - 1) * limit).Limit(limit).Find(&rs).Error; err != nil {
 			return nil, 0, err
 		}
 
@@ -107,10 +109,10 @@ func (e *Ecosystem) GetFind(limit, page int, order string, where map[string]any)
 		if err != nil {
 			return nil, 0, err
 		}
-		if err := GetDB(nil).Table(e.TableName()).Where(cond, vals...).Count(&total).Error; err != nil {
+		if err := GetDBContext(ctx).Table(e.TableName()).Where(cond, vals...).Count(&total).Error; err != nil {
 			return nil, 0, err
 		}
-		if err := GetDB(nil).Where(cond, vals...).Order(order).Offset((page - 1) * limit).Limit(limit).Find(&rs).Error; err != nil {
+		if err := GetDBContext(ctx).Where(cond, vals...).Order(order).Offset((page - 1) * limit).Limit(limit).Find(&rs).Error; err != nil {
 			return nil, 0, err
 		}
 
@@ -140,13 +142,13 @@ func GetAllSystemStatesIDs() ([]int64, []string, error) {
 	return ids, names, nil
 }
 
-func EcosystemSearch(search any, order, account string) (*[]EcosystemSearchResponse, error) {
+func EcosystemSearch(ctx context.Context, search any, order, account string) (*[]EcosystemSearchResponse, error) {
 	var keyword string
 	if search != nil {
 		switch reflect.TypeOf(search).String() {
 		case "string":
 		default:
-			log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("ecosystem search params invalid")
+			logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("ecosystem search params invalid")
 			return nil, errors.New("request params invalid")
 		}
 		keyword = "%" + search.(string) + "%"
@@ -167,7 +169,7 @@ func EcosystemSearch(search any, order, account string) (*[]EcosystemSearchRespo
 		return nil, errors.New("request params account invalid")
 	}
 	if order == "all" {
-		query := GetDB(nil).Raw(`
+		query := GetDBContext(ctx).Raw(`
 SELECT name,id,COALESCE(token_symbol,'')token_symbol,true AS is_join,info FROM "1_ecosystems" WHERE id in(SELECT ecosystem FROM "1_keys" WHERE 
 id = ?) and (name like ? or token_symbol like ?) AND token_symbol <> '' AND id <> 1
 
@@ -190,14 +192,14 @@ WHERE (v2.name like ? or v2.token_symbol like ?) AND token_symbol <> '' AND id <
 ORDER BY id asc
 `, kid, keyword, keyword, kid, keyword, keyword)
 		if err := query.Find(&list).Error; err != nil {
-			log.Info("Ecosystem Search failed:", err, " keyword:", keyword, " account:", account)
+			logs.WithContext(ctx).Info("Ecosystem Search failed:", err, " keyword:", keyword, " account:", account)
 			return nil, errors.New("search account ecosystem failed")
 		}
 	} else if order == "token_join" {
-		query := GetDB(nil).Table("1_ecosystems").Select("name,id,token_symbol,true as is_join").Where(`id in(SELECT ecosystem FROM "1_keys" WHERE 
+		query := GetDBContext(ctx).Table("1_ecosystems").Select("name,id,token_symbol,true as is_join").Where(`id in(SELECT ecosystem FROM "1_keys" WHERE 
 id = ?) and (name like ? or token_symbol like ?)`, kid, keyword, keyword)
 		if err := query.Where("token_symbol <> ''").Order("id asc").Find(&list).Error; err != nil {
-			log.Info("Ecosystem Search failed:", err, " account:", account)
+			logs.WithContext(ctx).Info("Ecosystem Search failed:", err, " account:", account)
 			return nil, errors.New("search account ecosystem failed")
 		}
 	} else {
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"encoding/hex"
 	"errors"
 	"fmt"
@@ -45,7 +46,7 @@ func (th *History) TableName() string {
 	return "1_history"
 }
 
-func (th *History) GetMonthFind(req *params.HistoryFindForm) (*WalletMonthDetailResponse, error) {
+func (th *History) GetMonthFind(ctx context.Context, req *params.HistoryFindForm) (*WalletMonthDetailResponse, error) {
 	if req.Time <= 0 {
 		return nil, errors.New("invalid request parameter time")
 	}
@@ -56,7 +57,7 @@ func (th *History) GetMonthFind(req *params.HistoryFindForm) (*WalletMonthDetail
 	stTime := time.Unix(req.Time, 0)
 	edTime := stTime.AddDate(0, 1, 0)
 
-	err := GetDB(nil).Raw(`
+	err := GetDBContext(ctx).Raw(`
 SELECT count(1) FROM(
 	SELECT block_id AS block,txhash AS hash,sender_id,recipient_id,type,created_at,amount,false AS isutxo,sender_balance,recipient_balance FROM "1_history"
 		WHERE ecosystem = ? AND (sender_id = ? or recipient_id = ?) and created_at >= ? and created_at < ? AND type <> 24
@@ -71,7 +72,7 @@ SELECT count(1) FROM(
 	}
 
 	var list []historyMonthRet
-	err = GetDB(nil).Raw(`
+	err = GetDBContext(ctx).Raw(`
 SELECT v1.block,v1.hash,v1.sender_id,v1.recipient_id,v1.type,v1.created_at,v1.amount,v1.isutxo,
 	CASE WHEN v1.isutxo = FALSE THEN
 		v1.sender_balance+COALESCE((
@@ -167,7 +168,7 @@ func (th *historyMonthRet) ChangeMonthResult(kid int64) *MonthHistoryResponse {
 	return &s
 }
 
-func (th *History) GetWalletMonthHistoryTotals(eid, kid int64, month int) ([]WalletMonthHistory, error) {
+func (th *History) GetWalletMonthHistoryTotals(ctx context.Context, eid, kid int64, month int) ([]WalletMonthHistory, error) {
 	var (
 		list []WalletMonthHistory
 		err  error
@@ -178,7 +179,7 @@ func (th *History) GetWalletMonthHistoryTotals(eid, kid int64, month int) ([]Wal
 	for i := 0; i < month; i++ {
 		te := t
 		te = te.AddDate(0, 1, 0)
-		if dat, err := th.GetWalletMonthHistory(eid, kid, t.UnixMilli(), te.UnixMilli()); err == nil {
+		if dat, err := th.GetWalletMonthHistory(ctx, eid, kid, t.UnixMilli(), te.UnixMilli()); err == nil {
 			dat.Month = t.Month().String()
 			dat.Time = t.Unix()
 			list = append(list, *dat)
@@ -191,12 +192,12 @@ func (th *History) GetWalletMonthHistoryTotals(eid, kid int64, month int) ([]Wal
 	return list, err
 }
 
-func (th *History) GetDBDayNftInComeinfo(kid, t1, t2 int64) (decimal.Decimal, error) {
+func (th *History) GetDBDayNftInComeinfo(ctx context.Context, kid, t1, t2 int64) (decimal.Decimal, error) {
 	type allAmount struct {
 		Amounts decimal.Decimal `gorm:"column:amounts"`
 	}
 	var at allAmount
-	err := GetDB(nil).Table(th.TableName()).Select("sum(amount) amounts").Where(`type = ? and recipient_id = ? and created_at >= ? and created_at < ?`, 12, kid, t1, t2).Take(&at).Error
+	err := GetDBContext(ctx).Table(th.TableName()).Select("sum(amount) amounts").Where(`type = ? and recipient_id = ? and created_at >= ? and created_at < ?`, 12, kid, t1, t2).Take(&at).Error
 	//.Row().Scan(&allAmount)
 
 	if err != nil {
@@ -205,7 +206,7 @@ func (th *History) GetDBDayNftInComeinfo(kid, t1, t2 int64) (decimal.Decimal, er
 	return at.Amounts, err
 }
 
-func (th *History) GetList(c *params.MineHistoryRequest) (*GeneralResponse, error) {
+func (th *History) GetList(ctx context.Context, c *params.MineHistoryRequest) (*GeneralResponse, error) {
 	var (
 		rets   GeneralResponse
 		txList []AccountTxHistory
@@ -231,31 +232,31 @@ func (th *History) GetList(c *params.MineHistoryRequest) (*GeneralResponse, erro
 	var whereSql *gorm.DB
 	switch c.Opt {
 	case "send":
-		whereSql = GetDB(nil).Where("ecosystem = ? AND sender_id = ?", c.Ecosystem, kid)
+		whereSql = GetDBContext(ctx).Where("ecosystem = ? AND sender_id = ?", c.Ecosystem, kid)
 	case "recipient":
-		whereSql = GetDB(nil).Where("ecosystem = ? AND recipient_id = ?", c.Ecosystem, kid)
+		whereSql = GetDBContext(ctx).Where("ecosystem = ? AND recipient_id = ?", c.Ecosystem, kid)
 	case "all":
-		whereSql = GetDB(nil).Where("ecosystem = ?", c.Ecosystem).Where(GetDB(nil).Where("recipient_id = ?", kid).Or("sender_id = ?", kid))
+		whereSql = GetDBContext(ctx).Where("ecosystem = ?", c.Ecosystem).Where(GetDBContext(ctx).Where("recipient_id = ?", kid).Or("sender_id = ?", kid))
 	}
 
-	err := GetDB(nil).Raw("SELECT count(1) FROM(? UNION ALL ?)AS v1",
-		GetDB(nil).Select("FALSE AS isutxo").Where(whereSql).Where("type <> 24").Table("1_history"),
-		GetDB(nil).Select("TRUE AS isutxo").Where(whereSql).Where("type <> 1").Table("utxo_history"),
+	err := GetDBContext(ctx).Raw("SELECT count(1) FROM(? UNION ALL ?)AS v1",
+		GetDBContext(ctx).Select("FALSE AS isutxo").Where(whereSql).Where("type <> 24").Table("1_history"),
+		GetDBContext(ctx).Select("TRUE AS isutxo").Where(whereSql).Where("type <> 1").Table("utxo_history"),
 	).Take(&rets.Total).Error
 	if err != nil {
 		return nil, err
 	}
 
-	err = GetDB(nil).Raw(
+	err = GetDBContext(ctx).Raw(
 		`SELECT v1.*,v2.contract_name,v2.address FROM(
 				SELECT * FROM(? UNION ALL ?) as v1 ORDER BY block DESC,created_at DESC OFFSET ? LIMIT ?
 			)AS v1
 			LEFT JOIN (SELECT contract_name,hash,address FROM log_transactions)AS v2 ON(v2.hash = v1.hash)
 	`,
-		GetDB(nil).Select("block_id AS block,txhash AS hash,sender_id,recipient_id,type,created_at,amount,false AS isutxo").
+		GetDBContext(ctx).Select("block_id AS block,txhash AS hash,sender_id,recipient_id,type,created_at,amount,false AS isutxo").
 			Where(whereSql).Where("type <> 24").Table("1_history"),
 
-		GetDB(nil).Select("block,hash,sender_id,recipient_id,type,created_at,amount,true AS isutxo").Where(whereSql).
+		GetDBContext(ctx).Select("block,hash,sender_id,recipient_id,type,created_at,amount,true AS isutxo").Where(whereSql).
 			Where("type <> 1").Table("utxo_history"),
 		(c.Page-1)*c.Limit,
 		c.Limit,
@@ -293,7 +294,7 @@ func (th *History) GetList(c *params.MineHistoryRequest) (*GeneralResponse, erro
 	return &rets, nil
 }
 
-func (th *History) GetWalletMonthHistory(eid, keyId, t1, t2 int64) (*WalletMonthHistory, error) {
+func (th *History) GetWalletMonthHistory(ctx context.Context, eid, keyId, t1, t2 int64) (*WalletMonthHistory, error) {
 	var (
 		ret    WalletMonthHistory
 		sCount int64
@@ -303,13 +304,13 @@ func (th *History) GetWalletMonthHistory(eid, keyId, t1, t2 int64) (*WalletMonth
 		err    error
 	)
 
-	rCount, sCount, err = getAccountTxCount(eid, keyId, t1, t2)
+	rCount, sCount, err = getAccountTxCount(ctx, eid, keyId, t1, t2)
 	if err != nil {
 		return nil, err
 	}
 
 	if rCount > 0 {
-		err = GetDB(nil).Raw(`
+		err = GetDBContext(ctx).Raw(`
 SELECT COALESCE(sum(amount),0)+
 	(SELECT COALESCE(sum(amount),0) FROM utxo_history WHERE 
 	recipient_id = ? AND ecosystem = ? AND type <> 1 AND created_at >= ? AND created_at < ?)AS in_amount 
@@ -325,7 +326,7 @@ FROM "1_history" WHERE recipient_id = ? AND ecosystem = ? AND created_at >= ? AN
 
 	if sCount > 0 {
 
-		err = GetDB(nil).Raw(`
+		err = GetDBContext(ctx).Raw(`
 SELECT COALESCE(sum(amount),0)+
 	(SELECT COALESCE(sum(amount),0) FROM utxo_history 
 	WHERE sender_id = ? AND ecosystem = ? AND type <> 1 AND created_at >= ? AND created_at < ?)AS out_amount 
@@ -355,7 +356,7 @@ FROM "1_history" WHERE sender_id = ? AND ecosystem = ? AND created_at >= ? AND c
 	return &ret, err
 }
 
-func (th *History) GetAccountHistoryTotals(id int64, keyId int64) (*WalletHistoryHex, error) {
+func (th *History) GetAccountHistoryTotals(ctx context.Context, id int64, keyId int64) (*WalletHistoryHex, error) {
 	var (
 		ret    WalletHistoryHex
 		sCount int64
@@ -365,14 +366,14 @@ func (th *History) GetAccountHistoryTotals(id int64, keyId int64) (*WalletHistor
 		err    error
 	)
 
-	rCount, sCount, err = getAccountTxCount(id, keyId, 0, 0)
+	rCount, sCount, err = getAccountTxCount(ctx, id, keyId, 0, 0)
 	if err != nil {
 		return nil, err
 	}
 
 	//in amount
 	if rCount > 0 {
-		err = GetDB(nil).Raw(`
+		err = GetDBContext(ctx).Raw(`
 SELECT COALESCE(sum(amount),0)+
 	(SELECT COALESCE(sum(amount),0) FROM utxo_history WHERE recipient_id = ? AND ecosystem = ? AND type <> 1)AS in_amount 
 FROM "1_history" WHERE recipient_id = ? AND ecosystem = ? AND type <> 24
@@ -384,7 +385,7 @@ FROM "1_history" WHERE recipient_id = ? AND ecosystem = ? AND type <> 24
 
 	//out amount
 	if sCount > 0 {
-		err = GetDB(nil).Raw(`
+		err = GetDBContext(ctx).Raw(`
 SELECT COALESCE(sum(amount),0)+
 	(SELECT COALESCE(sum(amount),0) FROM utxo_history WHERE sender_id = ? AND ecosystem = ? AND type <> 1)AS out_amount 
 FROM "1_history" WHERE sender_id = ? AND ecosystem = ? AND type <> 24
@@ -407,11 +408,11 @@ FROM "1_history" WHERE sender_id = ? AND ecosystem = ? AND type <> 24
 	return &ret, err
 }
 
-func GetAccountHistoryTotal(account string, ecosystem int64) (*AccountHistoryTotal, error) {
+func GetAccountHistoryTotal(ctx context.Context, account string, ecosystem int64) (*AccountHistoryTotal, error) {
 	var rets AccountHistoryTotal
 	kid := converter.StringToAddress(account)
 	ts := &History{}
-	dh, err := ts.GetAccountHistoryTotals(ecosystem, kid)
+	dh, err := ts.GetAccountHistoryTotals(ctx, ecosystem, kid)
 	if err != nil {
 		return nil, err
 	}
@@ -427,7 +428,7 @@ func GetAccountHistoryTotal(account string, ecosystem int64) (*AccountHistoryTot
 	return &rets, nil
 }
 
-func getAccountTxCount(ecosystem int64, keyId int64, st, ed int64) (inTx, outTx int64, err error) {
+func getAccountTxCount(ctx context.Context, ecosystem int64, keyId int64, st, ed int64) (inTx, outTx int64, err error) {
 	var (
 		inSqlQuery  string
 		outSqlQuery string
@@ -480,11 +481,11 @@ WHERE sender_id = ?
 `
 		}
 		if st > 0 {
-			q1 = GetDB(nil).Raw(inSqlQuery, ecosystem, st, ed, ecosystem, st, ed, keyId)
-			q2 = GetDB(nil).Raw(outSqlQuery, ecosystem, st, ed, ecosystem, st, ed, keyId)
+			q1 = GetDBContext(ctx).Raw(inSqlQuery, ecosystem, st, ed, ecosystem, st, ed, keyId)
+			q2 = GetDBContext(ctx).Raw(outSqlQuery, ecosystem, st, ed, ecosystem, st, ed, keyId)
 		} else {
-			q1 = GetDB(nil).Raw(inSqlQuery, ecosystem, ecosystem, keyId)
-			q2 = GetDB(nil).Raw(outSqlQuery, ecosystem, ecosystem, keyId)
+			q1 = GetDBContext(ctx).Raw(inSqlQuery, ecosystem, ecosystem, keyId)
+			q2 = GetDBContext(ctx).Raw(outSqlQuery, ecosystem, ecosystem, keyId)
 		}
 	} else {
 		inSqlQuery = `
@@ -503,8 +504,8 @@ SELECT count(1) FROM(
 )AS v1
 WHERE sender_id = ?
 `
-		q1 = GetDB(nil).Raw(inSqlQuery, keyId)
-		q2 = GetDB(nil).Raw(outSqlQuery, keyId)
+		q1 = GetDBContext(ctx).Raw(inSqlQuery, keyId)
+		q2 = GetDBContext(ctx).Raw(outSqlQuery, keyId)
 	}
 	err = q1.Row().Scan(&inTx)
 	if err != nil {
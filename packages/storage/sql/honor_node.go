@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
@@ -33,6 +34,13 @@ type HonorNodeInfo struct {
 	Latitude  float64 `json:"latitude,omitempty" gorm:"not null"`
 	Longitude float64 `json:"longitude,omitempty" gorm:"not null"`
 	Display   bool    `json:"display,omitempty" gorm:"not null"`
+
+	ProbeStatus int    `json:"probe_status,omitempty" gorm:"not null;default:0"` //0:unknown 1:online 2:offline 3:lagging
+	Latency     int64  `json:"latency,omitempty" gorm:"not null;default:0"`      //ms
+	BlockHeight int64  `json:"block_height,omitempty" gorm:"not null;default:0"`
+	ProbeError  string `json:"probe_error,omitempty" gorm:"not null;default:''"`
+	LastSeen    int64  `json:"last_seen,omitempty" gorm:"not null;default:0"`
+	ProbedAt    int64  `json:"probed_at,omitempty" gorm:"not null;default:0"`
 }
 
 type NodeValue struct {
@@ -88,16 +96,6 @@ func (p *HonorNodeInfo) TableName() string {
 	return "honor_node_info"
 }
 
-func (p *HonorNodeInfo) CreateTable() (err error) {
-	err = nil
-	if !GetDB(nil).Migrator().HasTable(p) {
-		if err = GetDB(nil).Migrator().CreateTable(p); err != nil {
-			return err
-		}
-	}
-	return err
-}
-
 func GetHonorNode() {
 	var (
 		err      error
@@ -260,6 +258,7 @@ func syncNodeDisplayStatus(nodeValue []NodeValue) {
 						log.WithFields(log.Fields{"error": err}).Error("sync display status update1 err")
 						continue
 					}
+					alertNodeHonorLost(oldVal[i])
 				}
 			}
 		}
@@ -268,6 +267,11 @@ func syncNodeDisplayStatus(nodeValue []NodeValue) {
 			log.WithFields(log.Fields{"error": err}).Error("sync all node display false failed")
 			return
 		}
+		for i := 0; i < len(list); i++ {
+			if list[i].Display {
+				alertNodeHonorLost(oldVal[i])
+			}
+		}
 
 	}
 }
@@ -333,18 +337,14 @@ FROM block_chain AS bk GROUP BY node_position,key_id,consensus_mode
 }
 
 func (p *HonorNodeInfo) GetRedis() (bool, string) {
-	rd := kv.RedisParams{
-		Key:   "honor-node",
-		Value: "",
-	}
-	err := rd.Get()
+	val, err := kv.Default.Get(context.Background(), HonorNodeCacheKey)
 	if err != nil {
-		if err.Error() == "redis: nil" || err.Error() == "EOF" {
+		if errors.Is(err, kv.ErrNotFound) {
 			return false, ""
 		}
 		return false, err.Error()
 	}
-	return true, rd.Value
+	return true, val
 }
 
 func (p *HonorNodeInfo) InsertRedis() {
@@ -362,23 +362,15 @@ func (p *HonorNodeInfo) InsertRedis() {
 		log.WithFields(log.Fields{"error": err}).Error("get honer node db failed")
 		return
 	}
-	rd := kv.RedisParams{
-		Key:   "honor-node",
-		Value: string(value),
-	}
-	if err := rd.Set(); err != nil {
+	if err := kv.Default.Set(context.Background(), HonorNodeCacheKey, string(value)); err != nil {
 		log.WithFields(log.Fields{"error": err}).Error("insert honerNode redis failed")
 		return
 	}
 }
 
 func (p *HonorNodeInfo) DelRedis() {
-	rd := kv.RedisParams{
-		Key:   "honor-node",
-		Value: "",
-	}
-	if err := rd.Del(); err != nil {
-		log.WithFields(log.Fields{"err": err}).Warn("DelRedis failed:", " key:", rd.Key)
+	if err := kv.Default.Del(context.Background(), HonorNodeCacheKey); err != nil {
+		log.WithFields(log.Fields{"err": err}).Warn("DelRedis failed:", " key:", HonorNodeCacheKey)
 	}
 }
 
@@ -0,0 +1,291 @@
+package sql
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"gorm.io/gorm"
+	"time"
+)
+
+const (
+	StatisticsCacheKey = "statistics"
+	HonorNodeCacheKey  = "honor-node"
+)
+
+// indexerLock is held by the writers of tx_data and utxo_history of every server sharing the database,
+// a reindex holds it for its whole run and the syncs skip their rounds meanwhile
+const indexerLock = 0x6a75746b6978 //"jutkix"
+
+// CacheKeys are the redis keys written by the server,they are rebuilt by the crontab tasks
+var CacheKeys = []string{StatisticsCacheKey, HonorNodeCacheKey}
+
+// IndexerLag is the sync state of a table built by the server from the chain
+type IndexerLag struct {
+	Table  string
+	Block  int64 //last synced block
+	Target int64 //last block to be synced
+	Lag    int64 //blocks behind the target
+	Delay  int64 //seconds between the block times of the last synced block and the target
+}
+
+// ReindexPlan is what Reindex deletes and rebuilds
+type ReindexPlan struct {
+	FromBlock   int64
+	ChainBlock  int64
+	TxData      int64 //rows deleted from tx_data
+	UtxoHistory int64 //rows deleted from utxo_history
+}
+
+type BalanceMismatch struct {
+	Ecosystem int64
+	KeyId     int64
+	Expected  decimal.Decimal //unspent outputs of spent_info,or the amount of 1_keys
+	Stored    decimal.Decimal //latest balance of utxo_history,or of 1_history
+	Block     int64           //block of the stored balance
+}
+
+type blockIdTime struct {
+	Id   int64
+	Time int64
+}
+
+func getBlockTime(blockId int64) (blockIdTime, error) {
+	var bt blockIdTime
+	_, err := isFound(GetDB(nil).Table("block_chain").Select("id,time").Where("id = ?", blockId).Take(&bt))
+	return bt, err
+}
+
+func getLastBlockTime() (blockIdTime, error) {
+	var bt blockIdTime
+	_, err := isFound(GetDB(nil).Table("block_chain").Select("id,time").Order("id desc").Take(&bt))
+	return bt, err
+}
+
+func newIndexerLag(table string, block int64, target blockIdTime) (IndexerLag, error) {
+	lag := IndexerLag{Table: table, Block: block, Target: target.Id}
+	if block >= target.Id {
+		return lag, nil
+	}
+	lag.Lag = target.Id - block
+	bt, err := getBlockTime(block)
+	if err != nil {
+		return lag, err
+	}
+	if bt.Time > 0 {
+		lag.Delay = target.Time - bt.Time
+	}
+	return lag, nil
+}
+
+// GetIndexerLag returns how far the tables synced by the server are behind block_chain,
+// the last block of the chain is returned with its age
+func GetIndexerLag() ([]IndexerLag, error) {
+	chain, err := getLastBlockTime()
+	if err != nil {
+		return nil, err
+	}
+	var (
+		list []IndexerLag
+		td   TransactionData
+		utd  TransactionData
+		uh   UtxoHistory
+	)
+	list = append(list, IndexerLag{Table: "block_chain", Block: chain.Id, Target: chain.Id, Delay: time.Now().Unix() - chain.Time})
+
+	if err = GetDB(nil).Model(&TransactionData{}).Select("coalesce(max(block),0)").Scan(&td.Block).Error; err != nil {
+		return nil, err
+	}
+	lag, err := newIndexerLag(td.TableName(), td.Block, chain)
+	if err != nil {
+		return nil, err
+	}
+	list = append(list, lag)
+
+	//utxo_history is built from the utxo transactions of tx_data
+	if _, err = utd.GetLastByType(formatTxDataType(true)); err != nil {
+		return nil, err
+	}
+	if _, err = uh.GetLast(); err != nil {
+		return nil, err
+	}
+	target, err := getBlockTime(utd.Block)
+	if err != nil {
+		return nil, err
+	}
+	lag, err = newIndexerLag(uh.TableName(), uh.Block, target)
+	if err != nil {
+		return nil, err
+	}
+	return append(list, lag), nil
+}
+
+// GetReindexPlan counts the rows Reindex deletes from fromBlock
+func GetReindexPlan(fromBlock int64) (*ReindexPlan, error) {
+	plan := &ReindexPlan{FromBlock: fromBlock}
+	chain, err := getLastBlockTime()
+	if err != nil {
+		return nil, err
+	}
+	plan.ChainBlock = chain.Id
+	err = GetDB(nil).Model(&TransactionData{}).Where("block >= ?", fromBlock).Count(&plan.TxData).Error
+	if err != nil {
+		return nil, err
+	}
+	err = GetDB(nil).Model(&UtxoHistory{}).Where("block >= ?", fromBlock).Count(&plan.UtxoHistory).Error
+	if err != nil {
+		return nil, err
+	}
+	return plan, nil
+}
+
+// withIndexerLock runs fn holding indexerLock,it's a transaction lock so it's released with the connection
+// whatever happens to the process. When wait is false it returns false at once if the lock is held elsewhere
+func withIndexerLock(wait bool, fn func() error) (bool, error) {
+	var locked bool
+	err := GetDB(nil).Transaction(func(tx *gorm.DB) error {
+		if wait {
+			if err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, indexerLock).Error; err != nil {
+				return err
+			}
+			locked = true
+		} else if err := tx.Raw(`SELECT pg_try_advisory_xact_lock(?)`, indexerLock).Scan(&locked).Error; err != nil || !locked {
+			return err
+		}
+		return fn()
+	})
+	return locked, err
+}
+
+// Reindex deletes the tx_data and utxo_history rows from fromBlock and rebuilds them from block_chain
+// and spent_info,utxo_history is rebuilt by a backfill.
+// progress is called with the table being rebuilt and its last synced block.
+// It holds indexerLock,the syncs of the running servers wait until it's done
+func Reindex(fromBlock int64, interval time.Duration, progress func(table string, block int64)) error {
+	_, err := withIndexerLock(true, func() error {
+		return reindex(fromBlock, interval, progress)
+	})
+	return err
+}
+
+func reindex(fromBlock int64, interval time.Duration, progress func(table string, block int64)) error {
+	var err error
+	if fromBlock <= 1 {
+		//the whole tables,without the dead rows of a delete
+		err = GetDB(nil).Exec(`TRUNCATE TABLE utxo_history,tx_data`).Error
+	} else {
+		err = GetDB(nil).Transaction(func(tx *gorm.DB) error {
+			if err := tx.Where("block >= ?", fromBlock).Delete(&UtxoHistory{}).Error; err != nil {
+				return err
+			}
+			return tx.Where("block >= ?", fromBlock).Delete(&TransactionData{}).Error
+		})
+	}
+	if err != nil {
+		return err
+	}
+	//the deleted blocks are not rolled back again
+	txDataStart, initStart = false, false
+
+	var td TransactionData
+	err = syncWithProgress(transactionDataSync, interval, func() (int64, error) {
+		err := GetDB(nil).Model(&TransactionData{}).Select("coalesce(max(block),0)").Scan(&td.Block).Error
+		return td.Block, err
+	}, func(block int64) { progress(td.TableName(), block) })
+	if err != nil {
+		return err
+	}
+
+	var uh UtxoHistory
+	//indexerLock is already held
+	utxoSyncMu.Lock()
+	defer utxoSyncMu.Unlock()
+	_, err = backfillUtxoHistory(context.Background(), GetBackfillOptions(), func(p BackfillProgress) {
+		progress(uh.TableName(), p.Block)
+	})
+	return err
+}
+
+// syncWithProgress runs sync and reports the block returned by last every interval and when it is done
+func syncWithProgress(sync func() error, interval time.Duration, last func() (int64, error), progress func(block int64)) error {
+	done := make(chan error, 1)
+	go func() {
+		done <- sync()
+	}()
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	report := func() {
+		if block, err := last(); err == nil {
+			progress(block)
+		}
+	}
+	for {
+		select {
+		case err := <-done:
+			report()
+			return err
+		case <-ticker.C:
+			report()
+		}
+	}
+}
+
+// GetUtxoEcosystems returns the ecosystems having utxo outputs
+func GetUtxoEcosystems() ([]int64, error) {
+	var list []int64
+	err := GetDB(nil).Model(&SpentInfo{}).Distinct("ecosystem").Order("ecosystem asc").Pluck("ecosystem", &list).Error
+	return list, err
+}
+
+// utxoBalancesQuery selects the utxo balances at @block,the unspent outputs of spent_info,an input spent
+// after @block is unspent,against the latest sender_balance/recipient_balance of utxo_history.
+// It covers the keys of @ecosystem,or the (ecosystem,key_id) pairs of @keys when byKeys is set.
+// It starts with SELECT so that the resolver can send it to a replica
+func utxoBalancesQuery(byKeys bool) string {
+	filter := func(ecosystem, key string) string {
+		if byKeys {
+			return "(" + ecosystem + "," + key + ") IN @keys"
+		}
+		return ecosystem + " = @ecosystem"
+	}
+	return `
+SELECT * FROM (
+	WITH unspent AS (
+		SELECT s1.ecosystem,s1.output_key_id AS key_id,sum(s1.output_value) AS amount FROM spent_info AS s1
+		LEFT JOIN log_transactions AS lg ON(lg.hash = s1.input_tx_hash)
+		WHERE ` + filter("s1.ecosystem", "s1.output_key_id") + ` AND s1.block_id <= @block AND (s1.input_tx_hash IS NULL OR lg.block > @block)
+		GROUP BY s1.ecosystem,s1.output_key_id
+	),stored AS (
+		SELECT DISTINCT ON(ecosystem,key_id) ecosystem,key_id,balance,block FROM(
+			SELECT id,ecosystem,sender_id AS key_id,sender_balance AS balance,block FROM utxo_history
+			WHERE ` + filter("ecosystem", "sender_id") + ` AND block <= @block AND type <> 5
+				UNION ALL
+			SELECT id,ecosystem,recipient_id AS key_id,recipient_balance AS balance,block FROM utxo_history
+			WHERE ` + filter("ecosystem", "recipient_id") + ` AND block <= @block
+		)AS v1 ORDER BY ecosystem,key_id,id DESC
+	)
+	SELECT coalesce(u.ecosystem,s.ecosystem) AS ecosystem,coalesce(u.key_id,s.key_id) AS key_id,
+		coalesce(u.amount,0) AS expected,coalesce(s.balance,0) AS stored,coalesce(s.block,0) AS block
+	FROM unspent AS u FULL JOIN stored AS s ON(s.ecosystem = u.ecosystem AND s.key_id = u.key_id)
+)AS balances`
+}
+
+// VerifyUtxoBalances recomputes the utxo balances of the ecosystem at block from the unspent outputs of
+// spent_info,and compares them with the latest sender_balance/recipient_balance of utxo_history.
+// It returns the number of checked keys and the mismatched ones
+func VerifyUtxoBalances(ecosystem, block int64) (int64, []BalanceMismatch, error) {
+	var (
+		checked int64
+		list    []BalanceMismatch
+	)
+	args := map[string]any{"ecosystem": ecosystem, "block": block}
+	balances := utxoBalancesQuery(false)
+	err := GetDB(nil).Raw(`SELECT count(1) FROM (`+balances+`)AS bc`, args).Take(&checked).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	err = GetDB(nil).Raw(balances+` WHERE expected <> stored ORDER BY key_id`, args).Find(&list).Error
+	if err != nil {
+		return 0, nil, err
+	}
+	return checked, list, nil
+}
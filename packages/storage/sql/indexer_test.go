@@ -0,0 +1,39 @@
+package sql
+
+import (
+	"errors"
+	"testing"
+	"time"
+)
+
+func TestSyncWithProgress(t *testing.T) {
+	var blocks []int64
+	block := int64(0)
+	release := make(chan struct{})
+	sync := func() error {
+		block = 10
+		<-release
+		block = 20
+		return errors.New("stopped")
+	}
+	reported := make(chan struct{}, 1)
+	go func() {
+		<-reported
+		close(release)
+	}()
+	err := syncWithProgress(sync, 10*time.Millisecond, func() (int64, error) {
+		return block, nil
+	}, func(b int64) {
+		blocks = append(blocks, b)
+		select {
+		case reported <- struct{}{}:
+		default:
+		}
+	})
+	if err == nil || err.Error() != "stopped" {
+		t.Fatalf("sync error %v", err)
+	}
+	if len(blocks) < 2 || blocks[len(blocks)-1] != 20 {
+		t.Errorf("progress %v", blocks)
+	}
+}
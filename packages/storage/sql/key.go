@@ -1,12 +1,14 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	"fmt"
 	"github.com/IBAX-io/go-ibax/packages/converter"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
 	"reflect"
 	"strconv"
 	"strings"
@@ -30,9 +32,9 @@ func (p Key) TableName() string {
 	return `1_keys`
 }
 
-func (p *Key) GetEcosystemsKeysCount(ecoId int64) (int64, error) {
+func (p *Key) GetEcosystemsKeysCount(ctx context.Context, ecoId int64) (int64, error) {
 	var cnt int64
-	if err := GetDB(nil).Table("1_keys").Where("ecosystem = ?", ecoId).Count(&cnt).Error; err != nil {
+	if err := GetDBContext(ctx).Table("1_keys").Where("ecosystem = ?", ecoId).Count(&cnt).Error; err != nil {
 		return 0, err
 	}
 	return cnt, nil
@@ -42,7 +44,7 @@ func (p *Key) GetEcosystemKeys(ecoId, keyId int64) (bool, error) {
 	return isFound(GetDB(nil).Where("id = ? and ecosystem = ?", keyId, ecoId).First(p))
 }
 
-func (key *Key) GetEcosystemsKeyAmount(keyId int64, page, limit int, search any, ids []int64) (*EcosystemKeyTotalResult, error) {
+func (key *Key) GetEcosystemsKeyAmount(ctx context.Context, keyId int64, page, limit int, search any, ids []int64) (*EcosystemKeyTotalResult, error) {
 	var (
 		list  []keyEcosystem
 		rss   []EcosystemKeyTotalRet
@@ -69,7 +71,7 @@ func (key *Key) GetEcosystemsKeyAmount(keyId int64, page, limit int, search any,
 				where = str
 			}
 		default:
-			log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Detail Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Node Detail Failed")
 			return rets, errors.New("request params invalid")
 		}
 	}
@@ -85,14 +87,14 @@ func (key *Key) GetEcosystemsKeyAmount(keyId int64, page, limit int, search any,
 		where += fmt.Sprintf(" ecosystem NOT IN(%s)", idsWhere)
 	}
 	if len(where) > 0 {
-		err := GetDB(nil).Raw(fmt.Sprintf(`
+		err := GetDBContext(ctx).Raw(fmt.Sprintf(`
 	SELECT count(*) FROM "1_keys" as k1 LEFT JOIN "1_ecosystems" AS e1 ON(e1.id = k1.ecosystem AND k1.id = ?) WHERE token_symbol <> '' AND %s
 `, where), keyId).Count(&rets.Total).Error
 		if err != nil {
 			return rets, err
 		}
 
-		err = GetDB(nil).Raw(fmt.Sprintf(`
+		err = GetDBContext(ctx).Raw(fmt.Sprintf(`
 	SELECT * FROM "1_keys" as k1 LEFT JOIN "1_ecosystems" AS e1 ON(e1.id = k1.ecosystem AND k1.id = ?) WHERE token_symbol <> '' AND %s
 ORDER BY ecosystem asc,k1.id asc OFFSET ? LIMIT ?
 `, where), keyId, (page-1)*limit, limit).Find(&list).Error
@@ -100,14 +102,14 @@ ORDER BY ecosystem asc,k1.id asc OFFSET ? LIMIT ?
 			return rets, err
 		}
 	} else {
-		err := GetDB(nil).Raw(`
+		err := GetDBContext(ctx).Raw(`
 	SELECT count(*) FROM "1_keys" as k1 LEFT JOIN "1_ecosystems" AS e1 ON(e1.id = k1.ecosystem AND k1.id = ?) WHERE token_symbol <> ''
 `, keyId).Count(&rets.Total).Error
 		if err != nil {
 			return rets, err
 		}
 
-		err = GetDB(nil).Raw(`
+		err = GetDBContext(ctx).Raw(`
 	SELECT * FROM "1_keys" as k1 LEFT JOIN "1_ecosystems" AS e1 ON(e1.id = k1.ecosystem AND k1.id = ?) WHERE token_symbol <> ''
 ORDER BY ecosystem asc,k1.id asc OFFSET ? LIMIT ?
 `, keyId, (page-1)*limit, limit).Find(&list).Error
@@ -220,7 +222,7 @@ func (m *keyEcosystem) ChangeResults(keyId int64) (*EcosystemKeyTotalRet, error)
 	return &s, nil
 }
 
-func GetKeyAmountByEcosystem(ecosystem int64, wallet string) (*WalletAmount, error) {
+func GetKeyAmountByEcosystem(ctx context.Context, ecosystem int64, wallet string) (*WalletAmount, error) {
 	var (
 		spent SpentInfo
 		rets  WalletAmount
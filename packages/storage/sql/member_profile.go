@@ -0,0 +1,166 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
+	"github.com/shopspring/decimal"
+	"strings"
+)
+
+type MemberActivity struct {
+	TxCount  int64  `json:"tx_count"`
+	InTx     int64  `json:"in_tx"`
+	OutTx    int64  `json:"out_tx"`
+	Received string `json:"received"`
+	Sent     string `json:"sent"`
+	FirstAt  int64  `json:"first_at"`
+	LastAt   int64  `json:"last_at"`
+}
+
+type MemberProfileResponse struct {
+	Ecosystem   int64          `json:"ecosystem"`
+	Account     string         `json:"account"`
+	MemberName  string         `json:"member_name"`
+	MemberInfo  string         `json:"member_info"`
+	ImageId     int64          `json:"image_id"`
+	ImageHash   string         `json:"image_hash"`
+	Avatar      string         `json:"avatar"`       //attachment url
+	AvatarThumb string         `json:"avatar_thumb"` //thumbnail url
+	Roles       []RoleInfo     `json:"roles"`
+	JoinedAt    int64          `json:"joined_at"` //first history record in the ecosystem
+	Activity    MemberActivity `json:"activity"`
+}
+
+type MemberListItem struct {
+	Account    string `json:"account"`
+	MemberName string `json:"member_name"`
+	ImageId    int64  `json:"image_id"`
+	ImageHash  string `json:"image_hash"`
+}
+
+type memberActivityRow struct {
+	InTx     int64
+	OutTx    int64
+	Received decimal.Decimal
+	Sent     decimal.Decimal
+	FirstAt  int64 //milliseconds
+	LastAt   int64
+}
+
+func (r memberActivityRow) activity() MemberActivity {
+	return MemberActivity{
+		TxCount:  r.InTx + r.OutTx,
+		InTx:     r.InTx,
+		OutTx:    r.OutTx,
+		Received: r.Received.String(),
+		Sent:     r.Sent.String(),
+		FirstAt:  MsToSeconds(r.FirstAt),
+		LastAt:   MsToSeconds(r.LastAt),
+	}
+}
+
+// SetAvatar sets the avatar urls from the attachment url base,the profile keeps no avatar without an image
+func (p *MemberProfileResponse) SetAvatar(attachmentUrl string) {
+	if p.ImageHash == "" {
+		return
+	}
+	p.Avatar = attachmentUrl + p.ImageHash
+	p.AvatarThumb = p.Avatar + "?size=128"
+}
+
+// GetMemberProfile returns nil when the account is not a member of the ecosystem
+func GetMemberProfile(ctx context.Context, ecosystem int64, account string) (*MemberProfileResponse, error) {
+	var mb Member
+	f, err := mb.GetAccount(ecosystem, account)
+	if err != nil {
+		return nil, err
+	}
+	if !f {
+		return nil, nil
+	}
+	rets := &MemberProfileResponse{
+		Ecosystem:  ecosystem,
+		Account:    account,
+		MemberName: mb.MemberName,
+		MemberInfo: mb.MemberInfo,
+	}
+	if mb.ImageID != nil && *mb.ImageID != 0 {
+		rets.ImageId = *mb.ImageID
+		rets.ImageHash, err = GetFileHash(rets.ImageId)
+		if err != nil {
+			return nil, err
+		}
+	}
+	rets.Roles, err = GetMemberRoles(ecosystem, account)
+	if err != nil {
+		return nil, err
+	}
+	rets.Activity, err = getMemberActivity(ctx, ecosystem, converter.StringToAddress(account))
+	if err != nil {
+		return nil, err
+	}
+	rets.JoinedAt = rets.Activity.FirstAt
+	return rets, nil
+}
+
+func GetMemberRoles(ecosystem int64, account string) ([]RoleInfo, error) {
+	ra := &sqldb.RolesParticipants{}
+	roles, err := ra.SetTablePrefix(ecosystem).GetActiveMemberRoles(account)
+	if err != nil {
+		return nil, err
+	}
+	list := make([]RoleInfo, 0, len(roles))
+	for _, r := range roles {
+		var role RoleInfo
+		if err := json.Unmarshal([]byte(r.Role), &role); err != nil {
+			return nil, err
+		}
+		list = append(list, role)
+	}
+	return list, nil
+}
+
+func getMemberActivity(ctx context.Context, ecosystem, keyId int64) (MemberActivity, error) {
+	var agg memberActivityRow
+	err := GetDBContext(ctx).Raw(`SELECT count(1) FILTER(WHERE recipient_id = ?) AS in_tx,count(1) FILTER(WHERE sender_id = ?) AS out_tx,
+	coalesce(sum(amount) FILTER(WHERE recipient_id = ?),0) AS received,coalesce(sum(amount) FILTER(WHERE sender_id = ?),0) AS sent,
+	coalesce(min(created_at),0) AS first_at,coalesce(max(created_at),0) AS last_at
+FROM "1_history" WHERE ecosystem = ? AND (sender_id = ? OR recipient_id = ?)`,
+		keyId, keyId, keyId, keyId, ecosystem, keyId, keyId).Take(&agg).Error
+	if err != nil {
+		return MemberActivity{}, err
+	}
+	return agg.activity(), nil
+}
+
+// GetMemberDirectory returns the members of the ecosystem,name matches member names containing it
+func GetMemberDirectory(ctx context.Context, ecosystem int64, name string, page, limit int) (*GeneralResponse, error) {
+	var (
+		mb   Member
+		rets GeneralResponse
+		list []MemberListItem
+	)
+	q := GetDBContext(ctx).Table(mb.TableName()+" AS m").Where("m.ecosystem = ?", ecosystem)
+	if name != "" {
+		q = q.Where("m.member_name ILIKE ?", "%"+escapeLike(name)+"%")
+	}
+	if err := q.Count(&rets.Total).Error; err != nil {
+		return nil, err
+	}
+	err := q.Select(`m.account,m.member_name,coalesce(m.image_id,0) AS image_id,coalesce(b.hash,'') AS image_hash`).
+		Joins(`LEFT JOIN "1_binaries" AS b ON b.id = m.image_id`).
+		Order("m.id asc").Offset((page - 1) * limit).Limit(limit).Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+	rets.Page = page
+	rets.Limit = limit
+	rets.List = list
+	return &rets, nil
+}
+
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "%", `\%`, "_", `\_`).Replace(s)
+}
@@ -0,0 +1,32 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestMemberActivity(t *testing.T) {
+	a := memberActivityRow{InTx: 3, OutTx: 2, Received: decimal.NewFromInt(30), Sent: decimal.Zero, FirstAt: 1600000000123, LastAt: 1600000999999}.activity()
+	if a.TxCount != 5 || a.Received != "30" || a.Sent != "0" || a.FirstAt != 1600000000 || a.LastAt != 1600000999 {
+		t.Errorf("activity %+v", a)
+	}
+}
+
+func TestSetAvatar(t *testing.T) {
+	p := &MemberProfileResponse{ImageHash: "ab12"}
+	p.SetAvatar("https://scan.example.com/api/v2/get_attachment/")
+	if p.Avatar != "https://scan.example.com/api/v2/get_attachment/ab12" || p.AvatarThumb != p.Avatar+"?size=128" {
+		t.Errorf("avatar %s thumb %s", p.Avatar, p.AvatarThumb)
+	}
+	p = &MemberProfileResponse{}
+	if p.SetAvatar("/api/v2/get_attachment/"); p.Avatar != "" || p.AvatarThumb != "" {
+		t.Errorf("avatar without image %s", p.Avatar)
+	}
+}
+
+func TestEscapeLike(t *testing.T) {
+	if got := escapeLike(`a%b_c\d`); got != `a\%b\_c\\d` {
+		t.Errorf("escapeLike = %s", got)
+	}
+}
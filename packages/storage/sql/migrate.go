@@ -0,0 +1,227 @@
+package sql
+
+import (
+	"embed"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed migrations/*.sql
+var migrationFiles embed.FS
+
+// migrationLock serializes the migrations of several servers sharing the database
+const migrationLock = 0x6a75746b6579 //"jutkey"
+
+var migrationName = regexp.MustCompile(`^(\d+)_(\w+)\.(up|down)\.sql$`)
+
+type Migration struct {
+	Version int64
+	Name    string
+	Up      string
+	Down    string
+}
+
+type SchemaMigration struct {
+	Version   int64  `gorm:"primary_key;autoIncrement:false;not null"`
+	Name      string `gorm:"not null"`
+	AppliedAt int64  `gorm:"not null"`
+}
+
+type MigrationStatus struct {
+	Version   int64
+	Name      string
+	Applied   bool
+	AppliedAt int64
+}
+
+func (p *SchemaMigration) TableName() string {
+	return "schema_migrations"
+}
+
+// LoadMigrations returns the embedded migrations ordered by version,every version needs an up and a down file
+func LoadMigrations() ([]Migration, error) {
+	files, err := fs.ReadDir(migrationFiles, "migrations")
+	if err != nil {
+		return nil, err
+	}
+	byVersion := make(map[int64]*Migration)
+	for _, f := range files {
+		m := migrationName.FindStringSubmatch(f.Name())
+		if m == nil {
+			return nil, fmt.Errorf("migration file name invalid:%s", f.Name())
+		}
+		version, _ := strconv.ParseInt(m[1], 10, 64)
+		data, err := migrationFiles.ReadFile(path.Join("migrations", f.Name()))
+		if err != nil {
+			return nil, err
+		}
+		mg, ok := byVersion[version]
+		if !ok {
+			mg = &Migration{Version: version, Name: m[2]}
+			byVersion[version] = mg
+		} else if mg.Name != m[2] {
+			return nil, fmt.Errorf("migration %d has two names:%s,%s", version, mg.Name, m[2])
+		}
+		if m[3] == "up" {
+			mg.Up = string(data)
+		} else {
+			mg.Down = string(data)
+		}
+	}
+	list := make([]Migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.Up == "" || mg.Down == "" {
+			return nil, fmt.Errorf("migration %d_%s needs both up and down files", mg.Version, mg.Name)
+		}
+		list = append(list, *mg)
+	}
+	sort.Slice(list, func(i, j int) bool { return list[i].Version < list[j].Version })
+	return list, nil
+}
+
+func createSchemaMigrations() error {
+	return GetDB(nil).Exec(`CREATE TABLE IF NOT EXISTS schema_migrations (
+	version bigint NOT NULL,
+	name text NOT NULL,
+	applied_at bigint NOT NULL,
+	PRIMARY KEY (version)
+)`).Error
+}
+
+func appliedMigrations() (map[int64]SchemaMigration, error) {
+	if err := createSchemaMigrations(); err != nil {
+		return nil, err
+	}
+	var list []SchemaMigration
+	if err := GetDB(nil).Order("version asc").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	applied := make(map[int64]SchemaMigration, len(list))
+	for _, m := range list {
+		applied[m.Version] = m
+	}
+	return applied, nil
+}
+
+// MigrateStatus lists the embedded migrations and whether they are applied
+func MigrateStatus() ([]MigrationStatus, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	rets := make([]MigrationStatus, 0, len(list))
+	for _, m := range list {
+		a, ok := applied[m.Version]
+		rets = append(rets, MigrationStatus{Version: m.Version, Name: m.Name, Applied: ok, AppliedAt: a.AppliedAt})
+	}
+	return rets, nil
+}
+
+// MigrateUp applies the pending migrations in version order,steps limits how many are applied,0 applies all
+func MigrateUp(steps int) ([]Migration, error) {
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for _, m := range list {
+		if steps > 0 && len(done) >= steps {
+			break
+		}
+		if _, ok := applied[m.Version]; ok {
+			continue
+		}
+		ok, err := runMigration(m, true)
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s up: %w", m.Version, m.Name, err)
+		}
+		if ok {
+			log.WithFields(log.Fields{"version": m.Version, "name": m.Name}).Info("migration applied")
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+// MigrateDown reverts the latest applied migrations,steps defaults to 1
+func MigrateDown(steps int) ([]Migration, error) {
+	if steps <= 0 {
+		steps = 1
+	}
+	list, err := LoadMigrations()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := appliedMigrations()
+	if err != nil {
+		return nil, err
+	}
+	var done []Migration
+	for i := len(list) - 1; i >= 0 && len(done) < steps; i-- {
+		m := list[i]
+		if _, ok := applied[m.Version]; !ok {
+			continue
+		}
+		ok, err := runMigration(m, false)
+		if err != nil {
+			return done, fmt.Errorf("migration %d_%s down: %w", m.Version, m.Name, err)
+		}
+		if ok {
+			log.WithFields(log.Fields{"version": m.Version, "name": m.Name}).Info("migration reverted")
+			done = append(done, m)
+		}
+	}
+	return done, nil
+}
+
+// runMigration runs one migration in a transaction,it returns false when another server did it first
+func runMigration(m Migration, up bool) (bool, error) {
+	var done bool
+	err := GetDB(nil).Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`SELECT pg_advisory_xact_lock(?)`, migrationLock).Error; err != nil {
+			return err
+		}
+		var sm SchemaMigration
+		f, err := isFound(tx.Where("version = ?", m.Version).Take(&sm))
+		if err != nil {
+			return err
+		}
+		if f == up {
+			return nil
+		}
+		if up {
+			if err = tx.Exec(m.Up).Error; err != nil {
+				return err
+			}
+			sm = SchemaMigration{Version: m.Version, Name: m.Name, AppliedAt: time.Now().Unix()}
+			if err = tx.Create(&sm).Error; err != nil {
+				return err
+			}
+		} else {
+			if err = tx.Exec(m.Down).Error; err != nil {
+				return err
+			}
+			if err = tx.Delete(&SchemaMigration{}, "version = ?", m.Version).Error; err != nil {
+				return err
+			}
+		}
+		done = true
+		return nil
+	})
+	return done, err
+}
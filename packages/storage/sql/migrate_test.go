@@ -0,0 +1,42 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestLoadMigrations(t *testing.T) {
+	list, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list) == 0 {
+		t.Fatal("no migrations embedded")
+	}
+	for i, m := range list {
+		if m.Version != int64(i+1) {
+			t.Errorf("migration %d_%s: versions must be consecutive from 1", m.Version, m.Name)
+		}
+		if strings.TrimSpace(m.Up) == "" || strings.TrimSpace(m.Down) == "" {
+			t.Errorf("migration %d_%s: empty up or down", m.Version, m.Name)
+		}
+		//the statements are executed without bind variables
+		if strings.Contains(m.Up, "?") || strings.Contains(m.Down, "?") {
+			t.Errorf("migration %d_%s: ? is taken as a placeholder", m.Version, m.Name)
+		}
+	}
+}
+
+func TestMigrationsKeepAdoptedTables(t *testing.T) {
+	list, err := LoadMigrations()
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, m := range list {
+		for _, table := range []string{"honor_node_info", "tx_data", "utxo_history"} {
+			if strings.Contains(m.Down, "DROP TABLE IF EXISTS "+table+";") {
+				t.Errorf("migration %d_%s down drops the adopted table %s", m.Version, m.Name, table)
+			}
+		}
+	}
+}
@@ -0,0 +1,5 @@
+-- honor_node_info,tx_data and utxo_history were adopted from the deployments created before the migrations,
+-- reverting doesn't drop them with their synced data
+DROP TABLE IF EXISTS alert_dead_letters;
+DROP TABLE IF EXISTS alert_rules;
+DROP TABLE IF EXISTS honor_node_performance;
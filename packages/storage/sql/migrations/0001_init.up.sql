@@ -0,0 +1,90 @@
+-- tables owned by jutkey-server,deployments created before the migrations already have them
+CREATE TABLE IF NOT EXISTS honor_node_info (
+	id bigserial NOT NULL,
+	value jsonb NOT NULL,
+	address text NOT NULL,
+	latitude decimal NOT NULL,
+	longitude decimal NOT NULL,
+	display boolean NOT NULL,
+	PRIMARY KEY (id)
+);
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS probe_status bigint NOT NULL DEFAULT 0;
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS latency bigint NOT NULL DEFAULT 0;
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS block_height bigint NOT NULL DEFAULT 0;
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS probe_error text NOT NULL DEFAULT '';
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS last_seen bigint NOT NULL DEFAULT 0;
+ALTER TABLE honor_node_info ADD COLUMN IF NOT EXISTS probed_at bigint NOT NULL DEFAULT 0;
+
+CREATE TABLE IF NOT EXISTS honor_node_performance (
+	id bigserial NOT NULL,
+	node_id bigint NOT NULL,
+	consensus_mode integer NOT NULL,
+	hour bigint NOT NULL,
+	blocks bigint NOT NULL,
+	expected_blocks bigint NOT NULL,
+	missed_slots bigint NOT NULL,
+	reply_rate decimal(10,2) NOT NULL DEFAULT '0',
+	vote decimal(40,12) NOT NULL DEFAULT '0',
+	display boolean NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_node_performance_hour ON honor_node_performance (node_id, consensus_mode, hour);
+
+CREATE TABLE IF NOT EXISTS tx_data (
+	hash bytea NOT NULL,
+	block bigint NOT NULL,
+	data bytea NOT NULL,
+	tx_time bigint NOT NULL,
+	type bigint NOT NULL,
+	PRIMARY KEY (hash)
+);
+CREATE INDEX IF NOT EXISTS idx_tx_data_block ON tx_data (block);
+
+CREATE TABLE IF NOT EXISTS utxo_history (
+	id bigserial NOT NULL,
+	block bigint NOT NULL,
+	hash bytea NOT NULL,
+	sender_id bigint NOT NULL,
+	recipient_id bigint NOT NULL,
+	amount decimal(40) NOT NULL DEFAULT '0',
+	created_at bigint NOT NULL,
+	ecosystem bigint NOT NULL,
+	type bigint NOT NULL,
+	sub_type bigint NOT NULL,
+	sender_balance decimal(50) NOT NULL DEFAULT '0',
+	recipient_balance decimal(50) NOT NULL DEFAULT '0',
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_utxo_history_block ON utxo_history (block);
+
+CREATE TABLE IF NOT EXISTS alert_rules (
+	id bigserial NOT NULL,
+	wallet text NOT NULL,
+	key_id bigint NOT NULL,
+	ecosystem bigint NOT NULL,
+	event_type text NOT NULL,
+	threshold decimal(40) NOT NULL DEFAULT '0',
+	channel text NOT NULL,
+	target text NOT NULL,
+	secret text NOT NULL,
+	start_block bigint NOT NULL,
+	enabled boolean NOT NULL,
+	created_at bigint NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_key_id ON alert_rules (key_id);
+CREATE INDEX IF NOT EXISTS idx_alert_rules_event_type ON alert_rules (event_type);
+
+CREATE TABLE IF NOT EXISTS alert_dead_letters (
+	id bigserial NOT NULL,
+	rule_id bigint NOT NULL,
+	channel text NOT NULL,
+	target text NOT NULL,
+	event_type text NOT NULL,
+	payload jsonb NOT NULL,
+	attempts bigint NOT NULL,
+	last_error text NOT NULL,
+	created_at bigint NOT NULL,
+	PRIMARY KEY (id)
+);
+CREATE INDEX IF NOT EXISTS idx_alert_dead_letters_rule_id ON alert_dead_letters (rule_id);
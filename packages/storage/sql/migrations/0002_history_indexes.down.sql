@@ -0,0 +1,4 @@
+DROP INDEX IF EXISTS idx_tx_data_tx_time;
+DROP INDEX IF EXISTS idx_utxo_history_created_at;
+DROP INDEX IF EXISTS idx_utxo_history_ecosystem_recipient;
+DROP INDEX IF EXISTS idx_utxo_history_ecosystem_sender;
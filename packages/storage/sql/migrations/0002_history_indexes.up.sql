@@ -0,0 +1,6 @@
+-- wallet history queries filter utxo_history by ecosystem and one side of the transfer,the month views by created_at
+CREATE INDEX IF NOT EXISTS idx_utxo_history_ecosystem_sender ON utxo_history (ecosystem, sender_id);
+CREATE INDEX IF NOT EXISTS idx_utxo_history_ecosystem_recipient ON utxo_history (ecosystem, recipient_id);
+CREATE INDEX IF NOT EXISTS idx_utxo_history_created_at ON utxo_history (created_at);
+-- the tx_data sync resumes from the latest tx_time
+CREATE INDEX IF NOT EXISTS idx_tx_data_tx_time ON tx_data (tx_time);
@@ -0,0 +1,16 @@
+-- balances found by the reconciliation job that differ from the latest history snapshots,resolved_at is 0 while open
+CREATE TABLE IF NOT EXISTS balance_discrepancies (
+	id bigserial NOT NULL,
+	kind text NOT NULL,
+	ecosystem bigint NOT NULL,
+	key_id bigint NOT NULL,
+	expected decimal(50) NOT NULL DEFAULT '0',
+	stored decimal(50) NOT NULL DEFAULT '0',
+	block bigint NOT NULL,
+	mode text NOT NULL,
+	found_at bigint NOT NULL,
+	checked_at bigint NOT NULL,
+	resolved_at bigint NOT NULL DEFAULT 0,
+	PRIMARY KEY (id)
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_balance_discrepancies_open ON balance_discrepancies (kind, ecosystem, key_id) WHERE resolved_at = 0;
@@ -0,0 +1,178 @@
+package sql
+
+import (
+	"context"
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/shopspring/decimal"
+	"strings"
+	"time"
+)
+
+const (
+	NftCalendarMaxDays = 90
+	nftRewardSampleDay = 7
+	icsTimeFormat      = "20060102T150405Z"
+)
+
+type NftMinerCalendarEvent struct {
+	Type        string `json:"type"` //stake_expire,withdrawable
+	TokenId     int64  `json:"token_id"`
+	Time        int64  `json:"time"`
+	StakeAmount string `json:"stake_amount"`
+	EnergyPower int64  `json:"energy_power"`
+}
+
+type NftMinerRewardProjection struct {
+	Time        int64  `json:"time"` //day start,utc
+	EnergyPower int64  `json:"energy_power"`
+	Amount      string `json:"amount"`
+}
+
+type NftMinerCalendarResponse struct {
+	Wallet           string                     `json:"wallet"`
+	NetworkDayReward string                     `json:"network_day_reward"` //average type 12 reward per day over the sample days
+	NetworkPower     int64                      `json:"network_power"`      //energy power of all active stakes
+	Events           []NftMinerCalendarEvent    `json:"events"`
+	Projections      []NftMinerRewardProjection `json:"projections"`
+	ProjectedTotal   string                     `json:"projected_total"`
+}
+
+// NftMinerCalendar returns the upcoming stake expirations of wallet and projects its daily nft miner
+// reward for the next days from the recent network reward and the share of active energy power
+func NftMinerCalendar(ctx context.Context, wallet string, days int) (*NftMinerCalendarResponse, error) {
+	var (
+		sk     NftMinerStaking
+		stakes []NftMinerStaking
+		ret    NftMinerCalendarResponse
+	)
+	if converter.StringToAddress(wallet) == 0 {
+		return nil, fmt.Errorf("wallet invalid:%s", wallet)
+	}
+	if days <= 0 || days > NftCalendarMaxDays {
+		return nil, fmt.Errorf("days must be between 1 and %d", NftCalendarMaxDays)
+	}
+	ret.Wallet = wallet
+	now := time.Now().Unix()
+	today := GetZoneTimes()
+
+	err := GetDBContext(ctx).Table(sk.TableName()).Where("staker = ? AND staking_status = 1", wallet).Order("end_dated asc,id asc").Find(&stakes).Error
+	if err != nil {
+		return nil, err
+	}
+	dayReward, network, err := nftNetworkDayReward(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret.NetworkPower = network.EnergyPower
+	ret.NetworkDayReward = dayReward.StringFixed(0)
+
+	for _, v := range stakes {
+		tp := "stake_expire"
+		if v.EndDated <= now {
+			tp = "withdrawable"
+		}
+		ret.Events = append(ret.Events, NftMinerCalendarEvent{
+			Type:        tp,
+			TokenId:     v.TokenId,
+			Time:        v.EndDated,
+			StakeAmount: v.StakeAmount,
+			EnergyPower: v.EnergyPower,
+		})
+	}
+
+	projected := decimal.Zero
+	for i := 0; i < days; i++ {
+		start := today.AddDate(0, 0, i)
+		end := start.AddDate(0, 0, 1).Unix()
+		var power int64
+		for _, v := range stakes {
+			//a stake counts for every day it overlaps
+			if v.StartDated < end && v.EndDated > start.Unix() {
+				power += v.EnergyPower
+			}
+		}
+		amount := decimal.Zero
+		if ret.NetworkPower > 0 && power > 0 {
+			amount = dayReward.Mul(decimal.NewFromInt(power)).Div(decimal.NewFromInt(ret.NetworkPower)).Floor()
+		}
+		projected = projected.Add(amount)
+		ret.Projections = append(ret.Projections, NftMinerRewardProjection{
+			Time:        start.Unix(),
+			EnergyPower: power,
+			Amount:      amount.String(),
+		})
+	}
+	ret.ProjectedTotal = projected.String()
+	return &ret, nil
+}
+
+type nftNetworkPower struct {
+	EnergyPower int64
+	EnergyPoint int64
+}
+
+// nftNetworkDayReward returns the average type 12 reward per day over the sample days
+// and the energy of all active stakes
+func nftNetworkDayReward(ctx context.Context) (decimal.Decimal, nftNetworkPower, error) {
+	var (
+		sk    NftMinerStaking
+		his   History
+		total SumAmount
+		power nftNetworkPower
+	)
+	now := time.Now().Unix()
+	today := GetZoneTimes()
+	err := GetDBContext(ctx).Table(sk.TableName()).Select("coalesce(sum(energy_power),0) AS energy_power,coalesce(sum(energy_point),0) AS energy_point").
+		Where("staking_status = 1 AND start_dated <= ? AND end_dated >= ?", now, now).Take(&power).Error
+	if err != nil {
+		return decimal.Zero, power, err
+	}
+	sampleStart := today.AddDate(0, 0, -nftRewardSampleDay)
+	_, err = isFound(GetDBContext(ctx).Table(his.TableName()).Select("sum(amount)").
+		Where("type = 12 AND created_at >= ? AND created_at < ?", sampleStart.UnixMilli(), today.UnixMilli()).Take(&total))
+	if err != nil {
+		return decimal.Zero, power, err
+	}
+	return total.Sum.Div(decimal.NewFromInt(nftRewardSampleDay)), power, nil
+}
+
+func icsEscape(s string) string {
+	r := strings.NewReplacer(`\`, `\\`, ";", `\;`, ",", `\,`, "\n", `\n`)
+	return r.Replace(s)
+}
+
+// ICS renders the stake events as an iCalendar document with a reminder one day before each expiration
+func (p *NftMinerCalendarResponse) ICS() string {
+	var b strings.Builder
+	stamp := time.Now().UTC().Format(icsTimeFormat)
+	b.WriteString("BEGIN:VCALENDAR\r\n")
+	b.WriteString("VERSION:2.0\r\n")
+	b.WriteString("PRODID:-//jutkey-server//nft miner calendar//EN\r\n")
+	b.WriteString("CALSCALE:GREGORIAN\r\n")
+	b.WriteString("X-WR-CALNAME:" + icsEscape("NFT Miner "+p.Wallet) + "\r\n")
+	for _, v := range p.Events {
+		start := time.Unix(v.Time, 0).UTC()
+		summary := fmt.Sprintf("NFT Miner #%d stake expires", v.TokenId)
+		if v.Type == "withdrawable" {
+			summary = fmt.Sprintf("NFT Miner #%d stake withdrawable", v.TokenId)
+		}
+		b.WriteString("BEGIN:VEVENT\r\n")
+		b.WriteString(fmt.Sprintf("UID:nft-miner-%d-%d@jutkey-server\r\n", v.TokenId, v.Time))
+		b.WriteString("DTSTAMP:" + stamp + "\r\n")
+		b.WriteString("DTSTART:" + start.Format(icsTimeFormat) + "\r\n")
+		b.WriteString("DTEND:" + start.Add(time.Hour).Format(icsTimeFormat) + "\r\n")
+		b.WriteString("SUMMARY:" + icsEscape(summary) + "\r\n")
+		b.WriteString("DESCRIPTION:" + icsEscape(fmt.Sprintf("stake amount %s,energy power %d", v.StakeAmount, v.EnergyPower)) + "\r\n")
+		if v.Type == "stake_expire" {
+			b.WriteString("BEGIN:VALARM\r\n")
+			b.WriteString("ACTION:DISPLAY\r\n")
+			b.WriteString("DESCRIPTION:" + icsEscape(summary) + "\r\n")
+			b.WriteString("TRIGGER:-P1D\r\n")
+			b.WriteString("END:VALARM\r\n")
+		}
+		b.WriteString("END:VEVENT\r\n")
+	}
+	b.WriteString("END:VCALENDAR\r\n")
+	return b.String()
+}
@@ -0,0 +1,32 @@
+package sql
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestNftMinerCalendarICS(t *testing.T) {
+	cal := &NftMinerCalendarResponse{
+		Wallet: "0000-0000-0000-0000-0001",
+		Events: []NftMinerCalendarEvent{
+			{Type: "stake_expire", TokenId: 3, Time: 1700000000, StakeAmount: "100", EnergyPower: 20},
+			{Type: "withdrawable", TokenId: 4, Time: 1600000000, StakeAmount: "50", EnergyPower: 10},
+		},
+	}
+	ics := cal.ICS()
+	if !strings.HasPrefix(ics, "BEGIN:VCALENDAR\r\n") || !strings.HasSuffix(ics, "END:VCALENDAR\r\n") {
+		t.Fatalf("calendar not wrapped: %q", ics)
+	}
+	if n := strings.Count(ics, "BEGIN:VEVENT"); n != 2 {
+		t.Fatalf("events = %d, want 2", n)
+	}
+	if n := strings.Count(ics, "BEGIN:VALARM"); n != 1 {
+		t.Fatalf("alarms = %d, want 1", n)
+	}
+	if !strings.Contains(ics, "DTSTART:20231114T221320Z") {
+		t.Fatalf("missing start time: %q", ics)
+	}
+	if !strings.Contains(ics, `stake amount 100\,energy power 20`) {
+		t.Fatalf("description not escaped: %q", ics)
+	}
+}
@@ -0,0 +1,361 @@
+package sql
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"github.com/srwiley/oksvg"
+	"github.com/srwiley/rasterx"
+	"golang.org/x/image/font"
+	"golang.org/x/image/font/gofont/goregular"
+	"golang.org/x/image/font/opentype"
+	"golang.org/x/image/math/fixed"
+	"image"
+	"image/color"
+	"image/png"
+	"io"
+	"jutkey-server/packages/storage/content"
+	"regexp"
+	"runtime"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+const (
+	NftImageSvg = "svg"
+	NftImagePng = "png"
+
+	NftImageThumbSize   = 128
+	nftImageDefaultSize = "512"
+	nftImageMaxSize     = 1024 //largest width and height of a rendered png and of a template view box
+	nftImageCachePath   = "nft_miner/"
+	svgTemplateVerbs    = 6
+)
+
+// nftImageSizes are the widths a png is rendered at,an empty size is nftImageDefaultSize
+var nftImageSizes = map[string]int{"thumb": NftImageThumbSize, "128": 128, "256": 256, "512": 512, "1024": 1024}
+
+var (
+	svgTemplateChecked sync.Map //temp id -> validated template hash
+	nftImageRender     = make(chan struct{}, runtime.NumCPU())
+	svgRenderFont      *opentype.Font
+	svgRenderFontOnce  sync.Once
+	svgVerbRegexp      = regexp.MustCompile(`%[^%]`)
+	svgFontSizeRegexp  = regexp.MustCompile(`font-size\s*:\s*([0-9.]+)`)
+)
+
+type NftMinerImage struct {
+	Data        []byte
+	ContentType string
+	ETag        string
+}
+
+// svgEscape escapes the values inserted into the svg template
+func svgEscape(s string) string {
+	var buf bytes.Buffer
+	_ = xml.EscapeText(&buf, []byte(s))
+	return buf.String()
+}
+
+func svgTemplateHash(tpl string) string {
+	sum := sha256.Sum256([]byte(tpl))
+	return hex.EncodeToString(sum[:8])
+}
+
+// ValidateSvgTemplate checks that the nft miner template takes exactly six %s values
+// and renders to a well-formed svg document
+func ValidateSvgTemplate(tpl string) error {
+	verbs := svgVerbRegexp.FindAllString(strings.ReplaceAll(tpl, "%%", ""), -1)
+	if len(verbs) != svgTemplateVerbs {
+		return fmt.Errorf("svg template expects %d values,got %d", svgTemplateVerbs, len(verbs))
+	}
+	for _, v := range verbs {
+		if v != "%s" {
+			return fmt.Errorf("svg template verb %s not supported", v)
+		}
+	}
+	sample := fmt.Sprintf(tpl, "0", "#0", "Location", "00:00:00 01-01-2006 (UTC)", "0000-0000-0000-0000-0000", "★")
+	dec := xml.NewDecoder(strings.NewReader(sample))
+	root := ""
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return fmt.Errorf("svg template invalid xml:%s", err.Error())
+		}
+		if se, ok := tok.(xml.StartElement); ok && root == "" {
+			root = se.Name.Local
+		}
+	}
+	if root != "svg" {
+		return fmt.Errorf("svg template root element is %q", root)
+	}
+	return checkSvgViewBox(sample)
+}
+
+// checkSvgViewBox rejects a view box larger than nftImageMaxSize
+func checkSvgViewBox(svg string) error {
+	icon, err := oksvg.ReadIconStream(strings.NewReader(svg), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return fmt.Errorf("svg template invalid:%s", err.Error())
+	}
+	if vb := icon.ViewBox; vb.W > nftImageMaxSize || vb.H > nftImageMaxSize {
+		return fmt.Errorf("svg template view box %gx%g larger than %d", vb.W, vb.H, nftImageMaxSize)
+	}
+	return nil
+}
+
+// checkSvgTemplate validates the template once per temp id and again whenever its value changes
+func checkSvgTemplate(tempId int64, tpl string) (string, error) {
+	hash := svgTemplateHash(tpl)
+	if v, ok := svgTemplateChecked.Load(tempId); ok && v.(string) == hash {
+		return hash, nil
+	}
+	if err := ValidateSvgTemplate(tpl); err != nil {
+		return "", fmt.Errorf("nft miner template %d:%s", tempId, err.Error())
+	}
+	svgTemplateChecked.Store(tempId, hash)
+	return hash, nil
+}
+
+func parseNftImageSize(size string) (int, error) {
+	if size == "" {
+		size = nftImageDefaultSize
+	}
+	n, ok := nftImageSizes[size]
+	if !ok {
+		return 0, errors.New("image size must be one of thumb,128,256,512,1024")
+	}
+	return n, nil
+}
+
+// RenderImage returns the nft miner image in format,svg or png,the png images are kept in the content store
+// and rendered by at most one goroutine per cpu
+func (p *NftMinerItems) RenderImage(ctx context.Context, format, sizeStr string) (*NftMinerImage, error) {
+	size, err := parseNftImageSize(sizeStr)
+	if err != nil {
+		return nil, err
+	}
+	switch format {
+	case "", NftImageSvg:
+		format = NftImageSvg
+	case NftImagePng:
+	default:
+		return nil, fmt.Errorf("unsupported image format:%s,only svg and png are served", format)
+	}
+	svg, tplHash, err := p.renderSvg()
+	if err != nil {
+		return nil, err
+	}
+	if svg == "" {
+		return nil, fmt.Errorf("nft miner template %d not found", p.TempId)
+	}
+	key := sha256.Sum256([]byte(fmt.Sprintf("%d|%s|%d|%s|%s|%d", p.ID, p.Attributes, p.EnergyPoint, tplHash, format, size)))
+	etag := `"` + hex.EncodeToString(key[:12]) + `"`
+	if format == NftImageSvg {
+		return &NftMinerImage{Data: []byte(svg), ContentType: "image/svg+xml;utf8", ETag: etag}, nil
+	}
+
+	contentType := "image/" + format
+	file := fmt.Sprintf("%s%d_%s.%s", nftImageCachePath, p.ID, hex.EncodeToString(key[:12]), format)
+	if data, err := readNftImageCache(ctx, file); err == nil {
+		return &NftMinerImage{Data: data, ContentType: contentType, ETag: etag}, nil
+	}
+
+	select {
+	case nftImageRender <- struct{}{}:
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+	img, err := rasterizeSvg(svg, size)
+	<-nftImageRender
+	if err != nil {
+		return nil, err
+	}
+	var buf bytes.Buffer
+	if err = png.Encode(&buf, img); err != nil {
+		return nil, err
+	}
+	if content.Default != nil {
+		if err = content.Default.Put(ctx, file, buf.Bytes(), contentType); err != nil {
+			//the image is still served,only caching failed
+			return &NftMinerImage{Data: buf.Bytes(), ContentType: contentType, ETag: etag}, err
+		}
+	}
+	return &NftMinerImage{Data: buf.Bytes(), ContentType: contentType, ETag: etag}, nil
+}
+
+func readNftImageCache(ctx context.Context, file string) ([]byte, error) {
+	if content.Default == nil {
+		return nil, content.ErrNotExist
+	}
+	obj, err := content.Default.Open(ctx, file)
+	if err != nil {
+		return nil, err
+	}
+	defer obj.Close()
+	return io.ReadAll(obj)
+}
+
+func rasterizeSvg(svg string, size int) (*image.RGBA, error) {
+	icon, err := oksvg.ReadIconStream(strings.NewReader(svg), oksvg.IgnoreErrorMode)
+	if err != nil {
+		return nil, err
+	}
+	vb := icon.ViewBox
+	if vb.W <= 0 || vb.H <= 0 {
+		return nil, errors.New("svg view box invalid")
+	}
+	if size <= 0 || size > nftImageMaxSize {
+		size = nftImageMaxSize
+	}
+	scale := float64(size) / vb.W
+	//a tall view box is fit in the largest size too
+	if vb.H*scale > nftImageMaxSize {
+		scale = nftImageMaxSize / vb.H
+	}
+	w, h := int(vb.W*scale+0.5), int(vb.H*scale+0.5)
+	icon.SetTarget(0, 0, float64(w), float64(h))
+	img := image.NewRGBA(image.Rect(0, 0, w, h))
+	scanner := rasterx.NewScannerGV(w, h, img, img.Bounds())
+	icon.Draw(rasterx.NewDasher(w, h, scanner), 1)
+
+	//oksvg does not render text,the text elements are drawn on top with the go font
+	if err = drawSvgText(img, svg, vb.X, vb.Y, scale); err != nil {
+		return nil, err
+	}
+	return img, nil
+}
+
+type svgText struct {
+	X, Y     float64
+	FontSize float64
+	Anchor   string
+	Fill     color.Color
+	Content  string
+}
+
+func parseSvgTexts(svg string) ([]svgText, error) {
+	var (
+		list  []svgText
+		cur   *svgText
+		depth int
+	)
+	dec := xml.NewDecoder(strings.NewReader(svg))
+	for {
+		tok, err := dec.Token()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		switch t := tok.(type) {
+		case xml.StartElement:
+			if t.Name.Local == "text" && cur == nil {
+				cur = &svgText{FontSize: 16, Fill: color.Black}
+				depth = 0
+				for _, a := range t.Attr {
+					switch a.Name.Local {
+					case "x":
+						cur.X, _ = strconv.ParseFloat(strings.Fields(a.Value + " 0")[0], 64)
+					case "y":
+						cur.Y, _ = strconv.ParseFloat(strings.Fields(a.Value + " 0")[0], 64)
+					case "font-size":
+						cur.FontSize, _ = strconv.ParseFloat(strings.TrimSuffix(a.Value, "px"), 64)
+					case "text-anchor":
+						cur.Anchor = a.Value
+					case "fill":
+						cur.Fill = parseSvgColor(a.Value, cur.Fill)
+					case "style":
+						if m := svgFontSizeRegexp.FindStringSubmatch(a.Value); m != nil {
+							cur.FontSize, _ = strconv.ParseFloat(m[1], 64)
+						}
+					}
+				}
+			} else if cur != nil {
+				depth++
+			}
+		case xml.EndElement:
+			if cur == nil {
+				continue
+			}
+			if t.Name.Local == "text" && depth == 0 {
+				cur.Content = strings.TrimSpace(cur.Content)
+				if cur.Content != "" {
+					list = append(list, *cur)
+				}
+				cur = nil
+			} else {
+				depth--
+			}
+		case xml.CharData:
+			if cur != nil {
+				cur.Content += string(t)
+			}
+		}
+	}
+	return list, nil
+}
+
+func parseSvgColor(s string, def color.Color) color.Color {
+	s = strings.TrimSpace(strings.ToLower(s))
+	switch s {
+	case "white":
+		return color.White
+	case "black":
+		return color.Black
+	case "none", "transparent":
+		return color.Transparent
+	}
+	if strings.HasPrefix(s, "#") {
+		hexStr := s[1:]
+		if len(hexStr) == 3 {
+			hexStr = string([]byte{hexStr[0], hexStr[0], hexStr[1], hexStr[1], hexStr[2], hexStr[2]})
+		}
+		if len(hexStr) == 6 {
+			if b, err := hex.DecodeString(hexStr); err == nil {
+				return color.RGBA{R: b[0], G: b[1], B: b[2], A: 0xff}
+			}
+		}
+	}
+	return def
+}
+
+func drawSvgText(img *image.RGBA, svg string, vbX, vbY, scale float64) error {
+	texts, err := parseSvgTexts(svg)
+	if err != nil || len(texts) == 0 {
+		return err
+	}
+	svgRenderFontOnce.Do(func() {
+		svgRenderFont, err = opentype.Parse(goregular.TTF)
+	})
+	if svgRenderFont == nil {
+		return errors.New("svg render font unavailable")
+	}
+	for _, t := range texts {
+		face, err := opentype.NewFace(svgRenderFont, &opentype.FaceOptions{Size: t.FontSize * scale, DPI: 72, Hinting: font.HintingFull})
+		if err != nil {
+			return err
+		}
+		d := &font.Drawer{Dst: img, Src: image.NewUniform(t.Fill), Face: face}
+		x := (t.X - vbX) * scale
+		switch t.Anchor {
+		case "middle":
+			x -= float64(d.MeasureString(t.Content).Round()) / 2
+		case "end":
+			x -= float64(d.MeasureString(t.Content).Round())
+		}
+		d.Dot = fixed.P(int(x), int((t.Y-vbY)*scale))
+		d.DrawString(t.Content)
+		face.Close()
+	}
+	return nil
+}
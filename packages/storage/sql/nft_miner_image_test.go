@@ -0,0 +1,73 @@
+package sql
+
+import (
+	"testing"
+)
+
+func TestValidateSvgTemplate(t *testing.T) {
+	tpl := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100"><text>%s %s %s %s %s %s</text></svg>`
+	if err := ValidateSvgTemplate(tpl); err != nil {
+		t.Fatalf("valid template: %v", err)
+	}
+	if err := ValidateSvgTemplate(`<svg><text>%s %s</text></svg>`); err == nil {
+		t.Fatal("template with two values should fail")
+	}
+	if err := ValidateSvgTemplate(`<svg><text>%s %s %s %s %s %s</svg>`); err == nil {
+		t.Fatal("malformed template should fail")
+	}
+	if err := ValidateSvgTemplate(`<div>%s %s %s %s %s %s</div>`); err == nil {
+		t.Fatal("non svg root should fail")
+	}
+	huge := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 100 100000"><text>%s %s %s %s %s %s</text></svg>`
+	if err := ValidateSvgTemplate(huge); err == nil {
+		t.Fatal("template with a huge view box should fail")
+	}
+}
+
+func TestSvgEscape(t *testing.T) {
+	if s := svgEscape(`<a&"b">`); s != "&lt;a&amp;&#34;b&#34;&gt;" {
+		t.Fatalf("escape = %s", s)
+	}
+}
+
+func TestRasterizeSvg(t *testing.T) {
+	svg := `<svg xmlns="http://www.w3.org/2000/svg" viewBox="0 0 200 100"><rect width="200" height="100" fill="#ff0000"/><text x="10" y="50" font-size="20" fill="#fff">#12</text></svg>`
+	img, err := rasterizeSvg(svg, 64)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if b := img.Bounds(); b.Dx() != 64 || b.Dy() != 32 {
+		t.Fatalf("bounds = %v", b)
+	}
+	//the largest size bounds both sides whatever the view box
+	for _, c := range []struct {
+		viewBox string
+		size    int
+		w, h    int
+	}{
+		{"0 0 100000 100000", 0, nftImageMaxSize, nftImageMaxSize},
+		{"0 0 100 1000", 512, 102, nftImageMaxSize},
+		{"0 0 100 100", 4096, nftImageMaxSize, nftImageMaxSize},
+	} {
+		img, err = rasterizeSvg(`<svg xmlns="http://www.w3.org/2000/svg" viewBox="`+c.viewBox+`"><rect width="10" height="10"/></svg>`, c.size)
+		if err != nil {
+			t.Fatal(err)
+		}
+		if b := img.Bounds(); b.Dx() != c.w || b.Dy() != c.h {
+			t.Errorf("view box %s size %d bounds = %v, want %dx%d", c.viewBox, c.size, b, c.w, c.h)
+		}
+	}
+}
+
+func TestParseNftImageSize(t *testing.T) {
+	for size, want := range map[string]int{"": 512, "thumb": NftImageThumbSize, "512": 512} {
+		if n, err := parseNftImageSize(size); err != nil || n != want {
+			t.Errorf("size %q = %d,%v, want %d", size, n, err, want)
+		}
+	}
+	for _, size := range []string{"100", "2048", "-1", "big"} {
+		if _, err := parseNftImageSize(size); err == nil {
+			t.Errorf("size %q accepted", size)
+		}
+	}
+}
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"encoding/hex"
 	"encoding/json"
 	"errors"
@@ -11,8 +12,10 @@ import (
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"jutkey-server/packages/logs"
 	"reflect"
 	"strconv"
+	"strings"
 	"time"
 )
 
@@ -72,13 +75,13 @@ func (p *NftMinerItems) GetByTxHash(txHash string, account string) (bool, error)
 	return isFound(GetDB(nil).Where("tx_hash = ? AND merge_status = 1 AND owner = ?", hash, account).First(p))
 }
 
-func (p *NftMinerItems) GetUserNftMinerSummary(keyid string) (NftMinerSummaryResponse, error) {
+func (p *NftMinerItems) GetUserNftMinerSummary(ctx context.Context, keyid string) (NftMinerSummaryResponse, error) {
 	var ret NftMinerSummaryResponse
 	var total int64
 	if !HasTableOrView(p.TableName()) {
 		return ret, nil
 	}
-	if err := GetDB(nil).Table(p.TableName()).Where("owner = ? AND merge_status = 1", keyid).Count(&total).Error; err != nil {
+	if err := GetDBContext(ctx).Table(p.TableName()).Where("owner = ? AND merge_status = 1", keyid).Count(&total).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
 			return ret, err
 		}
@@ -87,7 +90,7 @@ func (p *NftMinerItems) GetUserNftMinerSummary(keyid string) (NftMinerSummaryRes
 
 	var sk NftMinerStaking
 	var list []NftMinerStaking
-	if err := GetDB(nil).Table(sk.TableName()).Select("stake_amount,energy_power,start_dated,end_dated").Where("staker = ? AND staking_status = 1", keyid).Find(&list).Error; err != nil {
+	if err := GetDBContext(ctx).Table(sk.TableName()).Select("stake_amount,energy_power,start_dated,end_dated").Where("staker = ? AND staking_status = 1", keyid).Find(&list).Error; err != nil {
 		if err != gorm.ErrRecordNotFound {
 			return ret, err
 		}
@@ -96,7 +99,7 @@ func (p *NftMinerItems) GetUserNftMinerSummary(keyid string) (NftMinerSummaryRes
 	var ins SumAmount
 	var his History
 	kid := converter.StringToAddress(keyid)
-	_, err := isFound(GetDB(nil).Table(his.TableName()).Select("sum(amount)").Where("recipient_id = ? AND type = 12", kid).Take(&ins))
+	_, err := isFound(GetDBContext(ctx).Table(his.TableName()).Select("sum(amount)").Where("recipient_id = ? AND type = 12", kid).Take(&ins))
 	if err != nil {
 		return ret, err
 	}
@@ -119,12 +122,12 @@ func (p *NftMinerItems) GetUserNftMinerSummary(keyid string) (NftMinerSummaryRes
 	return ret, nil
 }
 
-func (p *NftMinerItems) GetUserNftFifteenDayOverview(day int, wallet string) (*[]NftMinerOverviewResponse, error) {
+func (p *NftMinerItems) GetUserNftFifteenDayOverview(ctx context.Context, day int, wallet string) (*[]NftMinerOverviewResponse, error) {
 	var ret []NftMinerOverviewResponse
 	var his History
-	var bk Block
 	kid := converter.StringToAddress(wallet)
-	ts, err := bk.GetSystemTime()
+	//genesis time
+	ts, _, err := GetBlockTimeEstimator().TimeOf(ctx, 1)
 	if err != nil {
 		return &ret, err
 	}
@@ -138,7 +141,7 @@ func (p *NftMinerItems) GetUserNftFifteenDayOverview(day int, wallet string) (*[
 		te := t
 		te = te.AddDate(0, 0, 1)
 
-		if amount, err := his.GetDBDayNftInComeinfo(kid, t.UnixMilli(), te.UnixMilli()); err == nil {
+		if amount, err := his.GetDBDayNftInComeinfo(ctx, kid, t.UnixMilli(), te.UnixMilli()); err == nil {
 			var dm NftMinerOverviewResponse
 			dm.Amount = amount.String()
 			dm.Time = t.Unix()
@@ -156,7 +159,7 @@ func (p *NftMinerItems) GetUserNftFifteenDayOverview(day int, wallet string) (*[
 	return &ret, nil
 }
 
-func (p *NftMinerItems) GetNftMinerKeyInfo(account string) (*CommonResult, error) {
+func (p *NftMinerItems) GetNftMinerKeyInfo(ctx context.Context, account string) (*CommonResult, error) {
 	kid := converter.StringToAddress(account)
 	if kid == 0 {
 		return nil, fmt.Errorf("account invalid:%s", account)
@@ -164,7 +167,7 @@ func (p *NftMinerItems) GetNftMinerKeyInfo(account string) (*CommonResult, error
 
 	var list []nftMinerInfo
 
-	q := GetDB(nil).Raw(`
+	q := GetDBContext(ctx).Raw(`
 SELECT v1.id,encode(v1.token_hash,'hex')token_hash,v1.energy_point,COALESCE(v2.stake_amount,'0') stake_amount,
 	COALESCE(v2.energy_power,0)energy_power,
 v2.start_dated,v2.end_dated
@@ -179,10 +182,10 @@ ORDER BY id asc
 
 	var res CommonResult
 
-	if err := GetDB(nil).Table(p.TableName()).Where("owner = ? AND merge_status = 1", account).Count(&res.Total).Error; err != nil {
+	if err := GetDBContext(ctx).Table(p.TableName()).Where("owner = ? AND merge_status = 1", account).Count(&res.Total).Error; err != nil {
 		return nil, err
 	}
-	f, err := isFound(GetDB(nil).Table(p.TableName()).Where("creator = ?", account).Take(p))
+	f, err := isFound(GetDBContext(ctx).Table(p.TableName()).Where("creator = ?", account).Take(p))
 	if err != nil {
 		return nil, err
 	}
@@ -208,13 +211,13 @@ ORDER BY id asc
 	return &res, nil
 }
 
-func (p *NftMinerItems) GetNftMinerRewardHistory(search any, page, limit int) (*GeneralResponse, error) {
+func (p *NftMinerItems) GetNftMinerRewardHistory(ctx context.Context, search any, page, limit int) (*GeneralResponse, error) {
 	var account string
 	switch reflect.TypeOf(search).String() {
 	case "string":
 		account = search.(string)
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("get Nft Miner Reward History Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("get Nft Miner Reward History Failed")
 		return nil, errors.New("request params invalid")
 	}
 	if account == "" {
@@ -225,7 +228,7 @@ func (p *NftMinerItems) GetNftMinerRewardHistory(search any, page, limit int) (*
 	if kid == 0 {
 		return nil, fmt.Errorf("account invalid:%s", account)
 	}
-	q := GetDB(nil).Raw(`
+	q := GetDBContext(ctx).Raw(`
 SELECT v2.token_hash,v1.txhash,v1.amount,v1.created_at FROM (
 	SELECT CAST(substr(comment,12,length(comment)-length('NFT Miner #')) AS numeric) nft_id,txhash,amount,created_at/1000 as created_at FROM "1_history" 
 	WHERE type = 12 AND recipient_id = ? ORDER BY id DESC OFFSET ? LIMIT ?
@@ -239,7 +242,7 @@ ORDER BY created_at DESC
 	rets := &GeneralResponse{}
 	var his History
 
-	err := GetDB(nil).Table(his.TableName()).Where("type = 12 AND recipient_id = ?", kid).Count(&rets.Total).Error
+	err := GetDBContext(ctx).Table(his.TableName()).Where("type = 12 AND recipient_id = ?", kid).Count(&rets.Total).Error
 	if err != nil {
 		return nil, err
 	}
@@ -267,7 +270,7 @@ func NftMinerTableIsExist() bool {
 	return true
 }
 
-func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*NftMinerInfoResponse, error) {
+func (p *NftMinerItems) GetNftMinerDetailBySearch(ctx context.Context, search any, wallet string) (*NftMinerInfoResponse, error) {
 	var (
 		rets NftMinerInfoResponse
 		f    bool
@@ -290,7 +293,7 @@ func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*N
 		}
 		f, err = p.GetById(minerId, wallet)
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("Get Nft Miner Detail By Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Info("Get Nft Miner Detail By Search Failed")
 		return nil, errors.New("request params invalid")
 	}
 	if err != nil {
@@ -312,18 +315,18 @@ func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*N
 	rets.Creator = p.Creator
 
 	var stak NftMinerStaking
-	err = GetDB(nil).Table(stak.TableName()).Where("token_id = ? and staker = ?", p.ID, wallet).Count(&rets.StakeCount).Error
+	err = GetDBContext(ctx).Table(stak.TableName()).Where("token_id = ? and staker = ?", p.ID, wallet).Count(&rets.StakeCount).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft info stakeCount err:", err.Error(), " nftId:", p.ID)
+			logs.WithContext(ctx).Info("get nft info stakeCount err:", err.Error(), " nftId:", p.ID)
 		}
 		return nil, err
 	}
 
 	nowTime := time.Now().Unix()
-	f, err = isFound(GetDB(nil).Where("token_id = ? AND staker = ?", p.ID, wallet).Last(&stak))
+	f, err = isFound(GetDBContext(ctx).Where("token_id = ? AND staker = ?", p.ID, wallet).Last(&stak))
 	if err != nil {
-		log.Info("get nft info stakeAmount err:", err.Error(), " nftId:", p.ID)
+		logs.WithContext(ctx).Info("get nft info stakeAmount err:", err.Error(), " nftId:", p.ID)
 		return nil, err
 	}
 	if f {
@@ -340,10 +343,10 @@ func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*N
 
 	} else {
 		var events NftMinerEvents
-		f, err = isFound(GetDB(nil).Select("id").
+		f, err = isFound(GetDBContext(ctx).Select("id").
 			Where("token_id = ? AND (event = 'Transfer' OR event = 'Synthesis')", p.ID).Last(&events))
 		if err != nil {
-			log.Info("Get Nft Miner Detail By Search events failed:", err.Error(), " nftId:", p.ID, " account:", wallet)
+			logs.WithContext(ctx).Info("Get Nft Miner Detail By Search events failed:", err.Error(), " nftId:", p.ID, " account:", wallet)
 			return nil, err
 		}
 		if f {
@@ -354,18 +357,18 @@ func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*N
 	var reward SumAmount
 	var his History
 	//kid := converter.StringToAddress(p.Owner)
-	q := GetDB(nil).Table(his.TableName()).Where("type = 12 AND comment = ? AND recipient_id = ?", fmt.Sprintf("NFT Miner #%d", p.ID), keyId)
+	q := GetDBContext(ctx).Table(his.TableName()).Where("type = 12 AND comment = ? AND recipient_id = ?", fmt.Sprintf("NFT Miner #%d", p.ID), keyId)
 	err = q.Select("sum(amount)").Take(&reward).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft miner reward info err:", err.Error(), " nftId:", p.ID)
+			logs.WithContext(ctx).Info("get nft miner reward info err:", err.Error(), " nftId:", p.ID)
 			return nil, err
 		}
 	}
 	err = q.Count(&rets.RewardCount).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft miner info reward Count err:", err.Error(), " nftId:", p.ID)
+			logs.WithContext(ctx).Info("get nft miner info reward Count err:", err.Error(), " nftId:", p.ID)
 			return nil, err
 		}
 	}
@@ -374,7 +377,7 @@ func (p *NftMinerItems) GetNftMinerDetailBySearch(search any, wallet string) (*N
 	return &rets, nil
 }
 
-func (p *NftMinerItems) GetNftMinerTxInfo(search any, page, limit int, order, wallet string) (*GeneralResponse, error) {
+func (p *NftMinerItems) GetNftMinerTxInfo(ctx context.Context, search any, page, limit int, order, wallet string) (*GeneralResponse, error) {
 	var (
 		rets  []NftMinerTxInfoResponse
 		total int64
@@ -399,32 +402,32 @@ func (p *NftMinerItems) GetNftMinerTxInfo(search any, page, limit int, order, wa
 		}
 		f, err = p.GetById(tokenId, wallet)
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Tx Info Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Tx Info Search Failed")
 		return nil, errors.New("request params invalid")
 	}
 
 	if err != nil {
-		log.Info("get nft miner txInfo err:", err.Error(), " nftId:", p.ID)
+		logs.WithContext(ctx).Info("get nft miner txInfo err:", err.Error(), " nftId:", p.ID)
 		return nil, err
 	}
 	if !f {
 		return nil, errors.New("NFT Miner Doesn't Not Exist")
 	}
 	var his []History
-	err = GetDB(nil).Table("1_history").Where("type = 12 AND comment = ? and recipient_id = ?", fmt.Sprintf("NFT Miner #%d", p.ID), keyId).Count(&total).Error
+	err = GetDBContext(ctx).Table("1_history").Where("type = 12 AND comment = ? and recipient_id = ?", fmt.Sprintf("NFT Miner #%d", p.ID), keyId).Count(&total).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft tx info total err:", err.Error(), " nftId:", p.ID)
+			logs.WithContext(ctx).Info("get nft tx info total err:", err.Error(), " nftId:", p.ID)
 			return nil, err
 		}
 	}
 
-	err = GetDB(nil).Select("id,created_at,amount,txhash").
+	err = GetDBContext(ctx).Select("id,created_at,amount,txhash").
 		Where("type = 12 AND comment = ? AND recipient_id = ?", fmt.Sprintf("NFT Miner #%d", p.ID), keyId).
 		Limit(limit).Offset((page - 1) * limit).Order(order).Find(&his).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft miner tx info nftIns err:", err.Error(), " nft miner id:", p.ID)
+			logs.WithContext(ctx).Info("get nft miner tx info nftIns err:", err.Error(), " nft miner id:", p.ID)
 			return nil, err
 		}
 	}
@@ -445,6 +448,12 @@ func (p *NftMinerItems) GetNftMinerTxInfo(search any, page, limit int, order, wa
 }
 
 func (p *NftMinerItems) ParseSvgParams() (string, error) {
+	svg, _, err := p.renderSvg()
+	return svg, err
+}
+
+// renderSvg fills the template with the escaped svg params and returns the template hash
+func (p *NftMinerItems) renderSvg() (string, string, error) {
 	var (
 		ret SvgParams
 		app AppParam
@@ -452,31 +461,41 @@ func (p *NftMinerItems) ParseSvgParams() (string, error) {
 	err := json.Unmarshal([]byte(p.Attributes), &ret)
 	if err != nil {
 		log.WithFields(log.Fields{"error": err}).Error("Parse Svg Params json unmarshal failed")
-		return "", err
+		return "", "", err
 	}
 	f, err := app.GetById(nil, p.TempId)
 	if err != nil {
 		log.WithFields(log.Fields{"error": err, "tempId": p.TempId}).Error("Parse Svg Params get app id failed")
-		return "", err
+		return "", "", err
 	}
 	if !f {
-		return "", nil
-	}
-	star := "★"
-	if p.EnergyPoint <= 20 {
-	} else if p.EnergyPoint <= 40 {
-		star = "★★"
-	} else if p.EnergyPoint <= 60 {
-		star = "★★★"
-	} else if p.EnergyPoint <= 80 {
-		star = "★★★★"
-	} else {
-		star = "★★★★★"
+		return "", "", nil
 	}
-	return fmt.Sprintf(app.Value, ret.Point, "#"+strconv.FormatInt(ret.Number, 10), formatLocation(ret.Location), smart.Date(SvgTimeFormat, MsToSeconds(ret.DateCreated)), ret.Owner, star), nil
+	tplHash, err := checkSvgTemplate(p.TempId, app.Value)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "tempId": p.TempId}).Error("Parse Svg Params template invalid")
+		return "", "", err
+	}
+	star := strings.Repeat("★", nftMinerStar(p.EnergyPoint))
+	return fmt.Sprintf(app.Value, svgEscape(ret.Point), "#"+strconv.FormatInt(ret.Number, 10), svgEscape(formatLocation(ret.Location)),
+		smart.Date(SvgTimeFormat, MsToSeconds(ret.DateCreated)), svgEscape(ret.Owner), star), tplHash, nil
+}
+
+// nftMinerStar returns the star rating of the energy point,one star per 20 points
+func nftMinerStar(energyPoint int) int {
+	if energyPoint <= 20 {
+		return 1
+	} else if energyPoint <= 40 {
+		return 2
+	} else if energyPoint <= 60 {
+		return 3
+	} else if energyPoint <= 80 {
+		return 4
+	}
+	return 5
 }
 
-//formatLocation example:NorthAmerica result:North America
+// formatLocation example:NorthAmerica result:North America
 func formatLocation(location string) string {
 	strs, indexList := StrReplaceAllString(location)
 	if strs.CapitalLetter >= 2 {
@@ -509,7 +528,7 @@ func StrReplaceAllString(s2 string) (strReplace StrReplaceStruct, indexList []St
 	return strReplace, indexList
 }
 
-func (p *NftMinerItems) NftMinerSynthesizable(account string, search any) (*[]SynthesizableResponse, error) {
+func (p *NftMinerItems) NftMinerSynthesizable(ctx context.Context, account string, search any) (*[]SynthesizableResponse, error) {
 	kid := converter.StringToAddress(account)
 	if kid == 0 {
 		return nil, fmt.Errorf("account invalid:%s", account)
@@ -531,7 +550,7 @@ func (p *NftMinerItems) NftMinerSynthesizable(account string, search any) (*[]Sy
 			return nil, errors.New("NFT doesn't not exist")
 		}
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Synthesizable Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Synthesizable Search Failed")
 		return nil, errors.New("request params invalid")
 	}
 
@@ -542,13 +561,13 @@ func (p *NftMinerItems) NftMinerSynthesizable(account string, search any) (*[]Sy
 	}
 	var rets []SynthesizableResponse
 
-	err := GetDB(nil).Table(p.TableName()).Select("id,token_hash,energy_point").
+	err := GetDBContext(ctx).Table(p.TableName()).Select("id,token_hash,energy_point").
 		Where("owner = ? AND merge_status = 1 AND id <> ?", account, nowNftId).Find(&list).Error
 	if err != nil {
 		return nil, err
 	}
 
-	f, err := getSynthesizableNftMiner(nowNftId, account)
+	f, err := getSynthesizableNftMiner(ctx, nowNftId, account)
 	if err != nil {
 		return nil, err
 	}
@@ -557,7 +576,7 @@ func (p *NftMinerItems) NftMinerSynthesizable(account string, search any) (*[]Sy
 	}
 
 	for _, v := range list {
-		f, err := getSynthesizableNftMiner(v.Id, account)
+		f, err := getSynthesizableNftMiner(ctx, v.Id, account)
 		if err != nil {
 			return nil, err
 		}
@@ -569,11 +588,11 @@ func (p *NftMinerItems) NftMinerSynthesizable(account string, search any) (*[]Sy
 	return &rets, nil
 }
 
-func getSynthesizableNftMiner(id int64, account string) (bool, error) {
+func getSynthesizableNftMiner(ctx context.Context, id int64, account string) (bool, error) {
 	var stak NftMinerStaking
-	f, err := isFound(GetDB(nil).Select("staking_status").Where("token_id = ? AND staker = ?", id, account).Last(&stak))
+	f, err := isFound(GetDBContext(ctx).Select("staking_status").Where("token_id = ? AND staker = ?", id, account).Last(&stak))
 	if err != nil {
-		log.Info("Get Nft Miner Synthesizable staking status failed:", err.Error(), " nftId:", id, " account:", account)
+		logs.WithContext(ctx).Info("Get Nft Miner Synthesizable staking status failed:", err.Error(), " nftId:", id, " account:", account)
 		return false, err
 	}
 	if f {
@@ -582,10 +601,10 @@ func getSynthesizableNftMiner(id int64, account string) (bool, error) {
 		}
 	} else {
 		var events NftMinerEvents
-		f, err = isFound(GetDB(nil).Select("id").
+		f, err = isFound(GetDBContext(ctx).Select("id").
 			Where("token_id = ? AND (event = 'Transfer' OR event = 'Synthesis')", id).Last(&events))
 		if err != nil {
-			log.Info("Get Nft Miner Synthesizable events failed:", err.Error(), " nftId:", id, " account:", account)
+			logs.WithContext(ctx).Info("Get Nft Miner Synthesizable events failed:", err.Error(), " nftId:", id, " account:", account)
 			return false, err
 		}
 		if f {
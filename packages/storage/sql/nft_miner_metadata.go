@@ -0,0 +1,161 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/smart"
+	"strconv"
+)
+
+const nftMinerMetadataName = "NFT Miner"
+
+type NftMinerAttribute struct {
+	TraitType   string `json:"trait_type"`
+	Value       any    `json:"value"`
+	DisplayType string `json:"display_type,omitempty"`
+	MaxValue    int    `json:"max_value,omitempty"`
+}
+
+type NftMinerMetadataResponse struct {
+	Name        string              `json:"name"`
+	Description string              `json:"description"`
+	Image       string              `json:"image"`
+	ImagePng    string              `json:"image_png"`
+	ExternalUrl string              `json:"external_url,omitempty"`
+	Attributes  []NftMinerAttribute `json:"attributes"`
+}
+
+type NftMinerEnergyBucket struct {
+	Star  int   `json:"star"`
+	Min   int   `json:"min"`
+	Max   int   `json:"max"`
+	Count int64 `json:"count"`
+}
+
+type NftMinerCollectionResponse struct {
+	Name               string                 `json:"name"`
+	Description        string                 `json:"description"`
+	Supply             int64                  `json:"supply"`      //all minted tokens
+	Circulating        int64                  `json:"circulating"` //tokens not consumed by synthesis
+	Merged             int64                  `json:"merged"`      //tokens created by synthesis
+	Burned             int64                  `json:"burned"`      //tokens consumed by synthesis
+	Staking            int64                  `json:"staking"`
+	Holders            int64                  `json:"holders"`
+	AvgEnergy          float64                `json:"avg_energy"`
+	EnergyDistribution []NftMinerEnergyBucket `json:"energy_distribution"`
+}
+
+// NftMinerMetadata returns the ERC-721 like metadata of the nft miner not merged yet,
+// the image url is imageUrl followed by the token id
+func NftMinerMetadata(ctx context.Context, id int64, imageUrl string) (*NftMinerMetadataResponse, error) {
+	var (
+		it  NftMinerItems
+		svg SvgParams
+		sk  NftMinerStaking
+	)
+	f, err := isFound(GetDBContext(ctx).Where("id = ? AND merge_status = 1", id).First(&it))
+	if err != nil {
+		return nil, err
+	}
+	if !f {
+		return nil, nil
+	}
+	if it.Attributes != "" {
+		if err = json.Unmarshal([]byte(it.Attributes), &svg); err != nil {
+			return nil, fmt.Errorf("nft miner %d attributes invalid:%s", id, err.Error())
+		}
+	}
+	staking, err := isFound(GetDBContext(ctx).Where("token_id = ? AND staking_status = 1", id).Order("id desc").First(&sk))
+	if err != nil {
+		return nil, err
+	}
+	state := "idle"
+	if staking {
+		state = "staking"
+	}
+
+	ret := &NftMinerMetadataResponse{
+		Name:        fmt.Sprintf("%s #%d", nftMinerMetadataName, it.ID),
+		Description: fmt.Sprintf("IBAX NFT Miner #%d with %d energy points", it.ID, it.EnergyPoint),
+		Image:       imageUrl + strconv.FormatInt(it.ID, 10),
+		ImagePng:    imageUrl + strconv.FormatInt(it.ID, 10) + "?format=" + NftImagePng,
+	}
+	ret.Attributes = []NftMinerAttribute{
+		{TraitType: "Energy Point", Value: it.EnergyPoint, DisplayType: "number", MaxValue: 100},
+		{TraitType: "Star", Value: nftMinerStar(it.EnergyPoint), DisplayType: "number", MaxValue: 5},
+		{TraitType: "Merge Count", Value: it.MergeCount, DisplayType: "number"},
+		{TraitType: "Location", Value: formatLocation(svg.Location)},
+		{TraitType: "Creator", Value: it.Creator},
+		{TraitType: "Date Created", Value: toUnixSeconds(it.DateCreated), DisplayType: "date"},
+		{TraitType: "Staking State", Value: state},
+	}
+	if staking {
+		ret.Attributes = append(ret.Attributes,
+			NftMinerAttribute{TraitType: "Stake Amount", Value: sk.StakeAmount},
+			NftMinerAttribute{TraitType: "Stake End", Value: toUnixSeconds(sk.EndDated), DisplayType: "date"},
+		)
+	}
+	if svg.DateCreated > 0 {
+		ret.Description += ", created " + smart.Date(SvgTimeFormat, MsToSeconds(svg.DateCreated))
+	}
+	return ret, nil
+}
+
+func NftMinerCollection(ctx context.Context) (*NftMinerCollectionResponse, error) {
+	var (
+		it  NftMinerItems
+		sk  NftMinerStaking
+		ret NftMinerCollectionResponse
+		agg struct {
+			Supply      int64
+			Circulating int64
+			Merged      int64
+			Holders     int64
+			AvgEnergy   float64
+		}
+		dist []struct {
+			Star  int
+			Count int64
+		}
+	)
+	err := GetDBContext(ctx).Table(it.TableName()).Select(`count(1) AS supply,
+	count(1) FILTER(WHERE merge_status = 1) AS circulating,
+	count(1) FILTER(WHERE merge_count > 0) AS merged,
+	count(DISTINCT owner) FILTER(WHERE merge_status = 1) AS holders,
+	coalesce(avg(energy_point) FILTER(WHERE merge_status = 1),0) AS avg_energy`).Take(&agg).Error
+	if err != nil {
+		return nil, err
+	}
+	err = GetDBContext(ctx).Table(sk.TableName()).Where("staking_status = 1").Select("count(DISTINCT token_id)").Take(&ret.Staking).Error
+	if err != nil {
+		return nil, err
+	}
+	//same buckets as nftMinerStar
+	err = GetDBContext(ctx).Table(it.TableName()).Where("merge_status = 1").
+		Select(`CASE WHEN energy_point <= 20 THEN 1 WHEN energy_point <= 40 THEN 2 WHEN energy_point <= 60 THEN 3
+		WHEN energy_point <= 80 THEN 4 ELSE 5 END AS star,count(1) AS count`).Group("star").Order("star asc").Find(&dist).Error
+	if err != nil {
+		return nil, err
+	}
+	ret.Name = nftMinerMetadataName
+	ret.Description = "IBAX NFT Miner collection"
+	ret.Supply = agg.Supply
+	ret.Circulating = agg.Circulating
+	ret.Merged = agg.Merged
+	ret.Burned = agg.Supply - agg.Circulating
+	ret.Holders = agg.Holders
+	ret.AvgEnergy = agg.AvgEnergy
+	counts := make(map[int]int64, len(dist))
+	for _, v := range dist {
+		counts[v.Star] = v.Count
+	}
+	for star := 1; star <= 5; star++ {
+		bucket := NftMinerEnergyBucket{Star: star, Min: (star-1)*20 + 1, Max: star * 20, Count: counts[star]}
+		if star == 1 {
+			bucket.Min = 0
+		}
+		ret.EnergyDistribution = append(ret.EnergyDistribution, bucket)
+	}
+	return &ret, nil
+}
@@ -0,0 +1,234 @@
+package sql
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/shopspring/decimal"
+	"sort"
+)
+
+const (
+	NftMinerMaxEnergy   = 100
+	NftPlannerMaxPlans  = 20
+	nftPlannerStaked    = "staked"
+	nftPlannerNotMerge  = "not_eligible"
+	nftPlannerReachable = "target_reached"
+)
+
+type NftPlannerMiner struct {
+	Id          int64  `json:"id"`
+	TokenHash   string `json:"token_hash"`
+	EnergyPoint int    `json:"energy_point"`
+	Star        int    `json:"star"`
+	Available   bool   `json:"available"`
+	Reason      string `json:"reason,omitempty"` //staked,not_eligible,target_reached
+}
+
+type NftPlannerStep struct {
+	Step         int    `json:"step"`
+	Base         int64  `json:"base"` //miner id the step starts from,0 for the result of the previous step
+	Merge        int64  `json:"merge"`
+	EnergyBefore int    `json:"energy_before"`
+	EnergyAfter  int    `json:"energy_after"`
+	StarAfter    int    `json:"star_after"`
+	RewardChange string `json:"reward_change"` //expected daily reward change of the merged miner
+}
+
+type NftPlannerPlan struct {
+	Miners      []int64          `json:"miners"`
+	EnergyPoint int              `json:"energy_point"`
+	Wasted      int              `json:"wasted"` //energy points lost over the cap
+	Steps       []NftPlannerStep `json:"steps"`
+	DayReward   string           `json:"day_reward"`
+}
+
+type NftSynthesisPlanResponse struct {
+	Target         int               `json:"target"`
+	RewardPerPoint string            `json:"reward_per_point"` //expected daily reward per energy point
+	Miners         []NftPlannerMiner `json:"miners"`
+	Plans          []NftPlannerPlan  `json:"plans"`
+}
+
+// NftMinerSynthesisPlan plans merge sequences of the wallet miners reaching the target energy,
+// each plan uses the fewest miners with the least energy over the target.
+func NftMinerSynthesisPlan(ctx context.Context, wallet string, target, maxPlans int) (*NftSynthesisPlanResponse, error) {
+	var (
+		it   NftMinerItems
+		sk   NftMinerStaking
+		list []NftMinerItems
+		ret  NftSynthesisPlanResponse
+	)
+	if converter.StringToAddress(wallet) == 0 {
+		return nil, fmt.Errorf("wallet invalid:%s", wallet)
+	}
+	if target <= 0 || target > NftMinerMaxEnergy {
+		return nil, fmt.Errorf("target energy must be between 1 and %d", NftMinerMaxEnergy)
+	}
+	if maxPlans <= 0 || maxPlans > NftPlannerMaxPlans {
+		maxPlans = NftPlannerMaxPlans
+	}
+	ret.Target = target
+
+	err := GetDBContext(ctx).Table(it.TableName()).Select("id,token_hash,energy_point").
+		Where("owner = ? AND merge_status = 1", wallet).Order("id asc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var pool []plannerItem
+	for _, v := range list {
+		m := NftPlannerMiner{
+			Id:          v.ID,
+			TokenHash:   hex.EncodeToString(v.TokenHash),
+			EnergyPoint: v.EnergyPoint,
+			Star:        nftMinerStar(v.EnergyPoint),
+		}
+		staked, err := isFound(GetDBContext(ctx).Table(sk.TableName()).Where("token_id = ? AND staker = ? AND staking_status = 1", v.ID, wallet).Take(&sk))
+		if err != nil {
+			return nil, err
+		}
+		ok, err := getSynthesizableNftMiner(ctx, v.ID, wallet)
+		if err != nil {
+			return nil, err
+		}
+		switch {
+		case staked:
+			m.Reason = nftPlannerStaked
+		case !ok:
+			m.Reason = nftPlannerNotMerge
+		case v.EnergyPoint >= target:
+			m.Reason = nftPlannerReachable
+		default:
+			m.Available = true
+			pool = append(pool, plannerItem{id: v.ID, energy: v.EnergyPoint})
+		}
+		ret.Miners = append(ret.Miners, m)
+	}
+
+	rewardPerPoint, err := nftRewardPerPoint(ctx)
+	if err != nil {
+		return nil, err
+	}
+	ret.RewardPerPoint = rewardPerPoint.StringFixed(0)
+
+	for len(ret.Plans) < maxPlans {
+		picked := planSynthesis(pool, target)
+		if len(picked) == 0 {
+			break
+		}
+		ret.Plans = append(ret.Plans, buildSynthesisPlan(picked, rewardPerPoint))
+		used := make(map[int64]bool, len(picked))
+		for _, v := range picked {
+			used[v.id] = true
+		}
+		rest := pool[:0]
+		for _, v := range pool {
+			if !used[v.id] {
+				rest = append(rest, v)
+			}
+		}
+		pool = rest
+	}
+	return &ret, nil
+}
+
+// nftRewardPerPoint estimates the daily reward of one staked energy point from the recent network reward
+func nftRewardPerPoint(ctx context.Context) (decimal.Decimal, error) {
+	dayReward, network, err := nftNetworkDayReward(ctx)
+	if err != nil {
+		return decimal.Zero, err
+	}
+	if network.EnergyPoint <= 0 {
+		return decimal.Zero, nil
+	}
+	return dayReward.Div(decimal.NewFromInt(network.EnergyPoint)), nil
+}
+
+type plannerItem struct {
+	id     int64
+	energy int
+}
+
+// planSynthesis picks at least two items whose energy sum reaches target with the least overshoot,
+// ties are broken by the fewest items. Items are expected to be below target.
+func planSynthesis(items []plannerItem, target int) []plannerItem {
+	if len(items) < 2 {
+		return nil
+	}
+	maxSum := 2 * target
+	const inf = 1 << 30
+	best := make([]int, maxSum+1) //fewest items summing exactly to the index
+	for i := range best {
+		best[i] = inf
+	}
+	best[0] = 0
+	choice := make([][]bool, len(items))
+	for i, v := range items {
+		choice[i] = make([]bool, maxSum+1)
+		for s := maxSum; s >= v.energy; s-- {
+			if best[s-v.energy] != inf && best[s-v.energy]+1 < best[s] {
+				best[s] = best[s-v.energy] + 1
+				choice[i][s] = true
+			}
+		}
+	}
+	sum := -1
+	for s := target; s <= maxSum; s++ {
+		if best[s] != inf && best[s] >= 2 {
+			sum = s
+			break
+		}
+	}
+	if sum < 0 {
+		return nil
+	}
+	var picked []plannerItem
+	for i := len(items) - 1; i >= 0 && sum > 0; i-- {
+		if choice[i][sum] {
+			picked = append(picked, items[i])
+			sum -= items[i].energy
+		}
+	}
+	sort.Slice(picked, func(i, j int) bool {
+		if picked[i].energy == picked[j].energy {
+			return picked[i].id < picked[j].id
+		}
+		return picked[i].energy > picked[j].energy
+	})
+	return picked
+}
+
+func buildSynthesisPlan(picked []plannerItem, rewardPerPoint decimal.Decimal) NftPlannerPlan {
+	plan := NftPlannerPlan{}
+	energy := picked[0].energy
+	total := energy
+	plan.Miners = append(plan.Miners, picked[0].id)
+	for i, v := range picked[1:] {
+		plan.Miners = append(plan.Miners, v.id)
+		total += v.energy
+		after := energy + v.energy
+		if after > NftMinerMaxEnergy {
+			after = NftMinerMaxEnergy
+		}
+		base := picked[0].id
+		if i > 0 {
+			base = 0
+		}
+		plan.Steps = append(plan.Steps, NftPlannerStep{
+			Step:         i + 1,
+			Base:         base,
+			Merge:        v.id,
+			EnergyBefore: energy,
+			EnergyAfter:  after,
+			StarAfter:    nftMinerStar(after),
+			RewardChange: rewardPerPoint.Mul(decimal.NewFromInt(int64(after - energy))).StringFixed(0),
+		})
+		energy = after
+	}
+	plan.EnergyPoint = energy
+	plan.Wasted = total - energy
+	plan.DayReward = rewardPerPoint.Mul(decimal.NewFromInt(int64(energy))).StringFixed(0)
+	return plan
+}
@@ -0,0 +1,31 @@
+package sql
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestPlanSynthesis(t *testing.T) {
+	items := []plannerItem{{1, 30}, {2, 45}, {3, 20}, {4, 50}, {5, 10}}
+	picked := planSynthesis(items, 60)
+	sum := 0
+	for _, v := range picked {
+		sum += v.energy
+	}
+	if sum != 60 || len(picked) != 2 {
+		t.Fatalf("picked = %+v, want two miners summing to 60", picked)
+	}
+	if planSynthesis([]plannerItem{{1, 10}, {2, 20}}, 60) != nil {
+		t.Fatal("unreachable target should return no plan")
+	}
+}
+
+func TestBuildSynthesisPlan(t *testing.T) {
+	plan := buildSynthesisPlan([]plannerItem{{1, 60}, {2, 30}, {3, 20}}, decimal.NewFromInt(10))
+	if plan.EnergyPoint != 100 || plan.Wasted != 10 || len(plan.Steps) != 2 {
+		t.Fatalf("plan = %+v", plan)
+	}
+	if plan.Steps[1].RewardChange != "100" || plan.Steps[1].Base != 0 || plan.Steps[0].Base != 1 {
+		t.Fatalf("steps = %+v", plan.Steps)
+	}
+}
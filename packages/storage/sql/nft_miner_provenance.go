@@ -0,0 +1,392 @@
+package sql
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+)
+
+const (
+	provenanceMaxDepth  = 16
+	provenanceMaxTokens = 256
+)
+
+type NftMinerProvenanceEvent struct {
+	Type         string  `json:"type"` //mint,transfer,stake,unstake,synthesis
+	TokenId      int64   `json:"token_id"`
+	Time         int64   `json:"time"`
+	From         string  `json:"from,omitempty"`
+	To           string  `json:"to,omitempty"`
+	TxHash       string  `json:"tx_hash,omitempty"`
+	Amount       string  `json:"amount,omitempty"`
+	EnergyPoint  int     `json:"energy_point,omitempty"`
+	MergedEnergy int     `json:"merged_energy,omitempty"` //energy points of the synthesis parents
+	Parents      []int64 `json:"parents,omitempty"`
+}
+
+type ProvenanceNode struct {
+	Id          string `json:"id"`
+	Kind        string `json:"kind"` //nft,account
+	Label       string `json:"label"`
+	EnergyPoint int    `json:"energy_point,omitempty"`
+	Merged      bool   `json:"merged,omitempty"`
+}
+
+type ProvenanceEdge struct {
+	From  string `json:"from"`
+	To    string `json:"to"`
+	Kind  string `json:"kind"` //mint,transfer,stake,synthesis
+	Time  int64  `json:"time"`
+	Label string `json:"label,omitempty"`
+}
+
+type NftMinerProvenanceResponse struct {
+	Id          int64                     `json:"id"`
+	Ancestors   []int64                   `json:"ancestors"`
+	Descendants []int64                   `json:"descendants"`
+	Timeline    []NftMinerProvenanceEvent `json:"timeline"`
+	Nodes       []ProvenanceNode          `json:"nodes"`
+	Edges       []ProvenanceEdge          `json:"edges"`
+}
+
+type provenanceBuilder struct {
+	data   *provenanceData
+	nodes  map[string]ProvenanceNode
+	order  []string
+	edges  []ProvenanceEdge
+	events []NftMinerProvenanceEvent
+}
+
+// provenanceData holds the rows of every token of the lineage,keyed by token id
+type provenanceData struct {
+	items     map[int64]*NftMinerItems
+	synthesis map[int64]*NftMinerEvents //Synthesis event of the tokens created by merging
+	transfers map[int64][]NftMinerEvents
+	stakes    map[int64][]NftMinerStaking
+}
+
+type synthesisEdge struct {
+	Child  int64
+	Parent int64
+}
+
+// toUnixSeconds accepts both the millisecond and second timestamps stored by the nft miner contracts
+func toUnixSeconds(t int64) int64 {
+	if t > 1e12 {
+		return MsToSeconds(t)
+	}
+	return t
+}
+
+// synthesisEdgeQuery joins a Synthesis event to the events of the merged tokens through its source,
+// the hex hash of the synthesis transaction. The source is decoded so that tx_hash is looked up by its index,
+// a source that isn't hex matches nothing instead of failing the decode
+const synthesisEdgeQuery = `SELECT ev.token_id AS child,pe.token_id AS parent FROM "1_nft_miner_events" AS ev
+	JOIN "1_nft_miner_events" AS pe ON (pe.tx_hash = CASE WHEN ev.source ~ '^([0-9a-fA-F]{2})+$' THEN decode(ev.source,'hex') END
+		AND pe.token_id <> ev.token_id)
+	WHERE ev.event = 'Synthesis'`
+
+// getSynthesisEdges walks the synthesis relation up and down from id in one recursive query
+func getSynthesisEdges(ctx context.Context, id int64) ([]synthesisEdge, error) {
+	var list []synthesisEdge
+	err := GetDBContext(ctx).Raw(`
+WITH RECURSIVE up AS (
+	SELECT child,parent,1 AS depth FROM (`+synthesisEdgeQuery+` AND ev.token_id = ?)AS s
+	UNION
+	SELECT s.child,s.parent,up.depth + 1 FROM up,LATERAL (`+synthesisEdgeQuery+` AND ev.token_id = up.parent)AS s WHERE up.depth < ?
+),down AS (
+	SELECT child,parent,1 AS depth FROM (`+synthesisEdgeQuery+` AND pe.token_id = ?)AS s
+	UNION
+	SELECT s.child,s.parent,down.depth + 1 FROM down,LATERAL (`+synthesisEdgeQuery+` AND pe.token_id = down.child)AS s WHERE down.depth < ?
+)
+SELECT DISTINCT child,parent FROM (SELECT child,parent FROM up UNION SELECT child,parent FROM down)AS e ORDER BY child,parent LIMIT ?
+`, id, provenanceMaxDepth, id, provenanceMaxDepth, provenanceMaxTokens).Find(&list).Error
+	return list, err
+}
+
+// synthesisLineage returns the ancestors of id breadth first,its descendants from the nearest and the parents of every token
+func synthesisLineage(id int64, edges []synthesisEdge) (ancestors, descendants []int64, parents map[int64][]int64) {
+	parents = make(map[int64][]int64)
+	children := make(map[int64][]int64)
+	for _, e := range edges {
+		parents[e.Child] = append(parents[e.Child], e.Parent)
+		children[e.Parent] = append(children[e.Parent], e.Child)
+	}
+	walk := func(next map[int64][]int64) []int64 {
+		visited := map[int64]bool{id: true}
+		list := []int64{}
+		queue := []int64{id}
+		for depth := 0; len(queue) > 0 && depth < provenanceMaxDepth; depth++ {
+			var level []int64
+			for _, tokenId := range queue {
+				for _, v := range next[tokenId] {
+					if !visited[v] && len(list) < provenanceMaxTokens {
+						visited[v] = true
+						list = append(list, v)
+						level = append(level, v)
+					}
+				}
+			}
+			queue = level
+		}
+		return list
+	}
+	return walk(parents), walk(children), parents
+}
+
+// getProvenanceData loads the tokens of the lineage with their transfers,stakes and synthesis events
+func getProvenanceData(ctx context.Context, ids []int64) (*provenanceData, error) {
+	var (
+		items     []NftMinerItems
+		synthesis []NftMinerEvents
+		transfers []NftMinerEvents
+		stakes    []NftMinerStaking
+		ev        NftMinerEvents
+		sk        NftMinerStaking
+	)
+	if err := GetDBContext(ctx).Where("id IN(?)", ids).Find(&items).Error; err != nil {
+		return nil, err
+	}
+	err := GetDBContext(ctx).Table(ev.TableName()).Where("token_id IN(?) AND event = 'Synthesis'", ids).Order("id asc").Find(&synthesis).Error
+	if err != nil {
+		return nil, err
+	}
+	err = GetDBContext(ctx).Table(ev.TableName()).Where("token_id IN(?) AND event = 'Transfer'", ids).Order("date_created asc,id asc").Find(&transfers).Error
+	if err != nil {
+		return nil, err
+	}
+	err = GetDBContext(ctx).Table(sk.TableName()).Where("token_id IN(?)", ids).Order("start_dated asc,id asc").Find(&stakes).Error
+	if err != nil {
+		return nil, err
+	}
+	data := &provenanceData{
+		items:     make(map[int64]*NftMinerItems, len(items)),
+		synthesis: make(map[int64]*NftMinerEvents),
+		transfers: make(map[int64][]NftMinerEvents),
+		stakes:    make(map[int64][]NftMinerStaking),
+	}
+	for i := range items {
+		data.items[items[i].ID] = &items[i]
+	}
+	for i := range synthesis {
+		if _, ok := data.synthesis[synthesis[i].TokenId]; !ok {
+			data.synthesis[synthesis[i].TokenId] = &synthesis[i]
+		}
+	}
+	for _, v := range transfers {
+		data.transfers[v.TokenId] = append(data.transfers[v.TokenId], v)
+	}
+	for _, v := range stakes {
+		data.stakes[v.TokenId] = append(data.stakes[v.TokenId], v)
+	}
+	return data, nil
+}
+
+func (b *provenanceBuilder) addNode(n ProvenanceNode) {
+	if _, ok := b.nodes[n.Id]; ok {
+		return
+	}
+	b.nodes[n.Id] = n
+	b.order = append(b.order, n.Id)
+}
+
+func tokenNodeId(id int64) string {
+	return fmt.Sprintf("nft_%d", id)
+}
+
+func accountNodeId(account string) string {
+	return "acc_" + account
+}
+
+func (b *provenanceBuilder) addAccount(account string) string {
+	if account == "" {
+		return ""
+	}
+	b.addNode(ProvenanceNode{Id: accountNodeId(account), Kind: "account", Label: account})
+	return accountNodeId(account)
+}
+
+func (b *provenanceBuilder) token(id int64) *NftMinerItems {
+	it, ok := b.data.items[id]
+	if !ok {
+		return nil
+	}
+	b.addNode(ProvenanceNode{
+		Id:          tokenNodeId(id),
+		Kind:        "nft",
+		Label:       fmt.Sprintf("NFT Miner #%d", id),
+		EnergyPoint: it.EnergyPoint,
+		Merged:      it.MergeStatus == 0,
+	})
+	return it
+}
+
+// tokenHistory adds mint,transfers and staking periods of one token
+func (b *provenanceBuilder) tokenHistory(it *NftMinerItems, parents []int64) {
+	mint := NftMinerProvenanceEvent{
+		Type:        "mint",
+		TokenId:     it.ID,
+		Time:        toUnixSeconds(it.DateCreated),
+		To:          it.Creator,
+		TxHash:      hex.EncodeToString(it.TxHash),
+		EnergyPoint: it.EnergyPoint,
+	}
+	if synthesis, ok := b.data.synthesis[it.ID]; ok {
+		mint.Type = "synthesis"
+		mint.Parents = parents
+		mint.TxHash = hex.EncodeToString(synthesis.TxHash)
+		for _, parent := range parents {
+			pt := b.token(parent)
+			if pt == nil {
+				continue
+			}
+			mint.MergedEnergy += pt.EnergyPoint
+			b.edges = append(b.edges, ProvenanceEdge{
+				From:  tokenNodeId(parent),
+				To:    tokenNodeId(it.ID),
+				Kind:  "synthesis",
+				Time:  mint.Time,
+				Label: fmt.Sprintf("+%d", pt.EnergyPoint),
+			})
+		}
+	}
+	b.events = append(b.events, mint)
+	if it.Creator != "" {
+		b.edges = append(b.edges, ProvenanceEdge{From: b.addAccount(it.Creator), To: tokenNodeId(it.ID), Kind: "mint", Time: mint.Time})
+	}
+
+	transfers := b.data.transfers[it.ID]
+	for i, v := range transfers {
+		//source is the previous owner,the receiver is the source of the next transfer or the current owner
+		to := it.Owner
+		if i+1 < len(transfers) {
+			to = transfers[i+1].Source
+		}
+		t := toUnixSeconds(v.DateCreated)
+		b.events = append(b.events, NftMinerProvenanceEvent{
+			Type:    "transfer",
+			TokenId: it.ID,
+			Time:    t,
+			From:    v.Source,
+			To:      to,
+			TxHash:  hex.EncodeToString(v.TxHash),
+		})
+		b.addAccount(v.Source)
+		b.addAccount(to)
+		b.edges = append(b.edges, ProvenanceEdge{From: accountNodeId(v.Source), To: accountNodeId(to), Kind: "transfer", Time: t, Label: fmt.Sprintf("#%d", it.ID)})
+	}
+
+	for _, v := range b.data.stakes[it.ID] {
+		b.events = append(b.events, NftMinerProvenanceEvent{
+			Type:    "stake",
+			TokenId: it.ID,
+			Time:    toUnixSeconds(v.StartDated),
+			From:    v.Staker,
+			Amount:  v.StakeAmount,
+		})
+		b.edges = append(b.edges, ProvenanceEdge{From: tokenNodeId(it.ID), To: b.addAccount(v.Staker), Kind: "stake", Time: toUnixSeconds(v.StartDated), Label: v.StakeAmount})
+		if v.StakingStatus == 2 {
+			end := v.WithdrawDate
+			if end == 0 {
+				end = v.EndDated
+			}
+			b.events = append(b.events, NftMinerProvenanceEvent{
+				Type:    "unstake",
+				TokenId: it.ID,
+				Time:    toUnixSeconds(end),
+				To:      v.Staker,
+				Amount:  v.StakeAmount,
+			})
+		}
+	}
+}
+
+func NftMinerProvenance(ctx context.Context, id int64) (*NftMinerProvenanceResponse, error) {
+	edges, err := getSynthesisEdges(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	ancestors, descendants, parents := synthesisLineage(id, edges)
+	lineage := append([]int64{id}, ancestors...)
+	lineage = append(lineage, descendants...)
+	data, err := getProvenanceData(ctx, lineage)
+	if err != nil {
+		return nil, err
+	}
+	if _, ok := data.items[id]; !ok {
+		return nil, errors.New("NFT doesn't not exist")
+	}
+	return buildProvenance(id, ancestors, descendants, parents, data), nil
+}
+
+func buildProvenance(id int64, ancestors, descendants []int64, parents map[int64][]int64, data *provenanceData) *NftMinerProvenanceResponse {
+	b := &provenanceBuilder{
+		data:  data,
+		nodes: make(map[string]ProvenanceNode),
+	}
+	rets := &NftMinerProvenanceResponse{Id: id, Ancestors: []int64{}, Descendants: []int64{}}
+	b.token(id)
+	for _, v := range ancestors {
+		if b.token(v) != nil {
+			rets.Ancestors = append(rets.Ancestors, v)
+		}
+	}
+	for _, v := range descendants {
+		if b.token(v) != nil {
+			rets.Descendants = append(rets.Descendants, v)
+		}
+	}
+
+	lineage := append([]int64{id}, rets.Ancestors...)
+	lineage = append(lineage, rets.Descendants...)
+	for _, tokenId := range lineage {
+		b.tokenHistory(data.items[tokenId], parents[tokenId])
+	}
+	sort.SliceStable(b.events, func(i, j int) bool {
+		return b.events[i].Time < b.events[j].Time
+	})
+	rets.Timeline = b.events
+	rets.Nodes = make([]ProvenanceNode, 0, len(b.order))
+	for _, v := range b.order {
+		rets.Nodes = append(rets.Nodes, b.nodes[v])
+	}
+	rets.Edges = b.edges
+	return rets
+}
+
+func dotEscape(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`).Replace(s)
+}
+
+// Dot renders the provenance graph in Graphviz DOT format
+func (p *NftMinerProvenanceResponse) Dot() string {
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("digraph \"nft_miner_%d\" {\n", p.Id))
+	sb.WriteString("\trankdir=LR;\n")
+	for _, n := range p.Nodes {
+		shape := "ellipse"
+		label := n.Label
+		if n.Kind == "nft" {
+			shape = "box"
+			label = fmt.Sprintf("%s\\n%d", dotEscape(n.Label), n.EnergyPoint)
+			if n.Merged {
+				shape = "box, style=dashed"
+			}
+		} else {
+			label = dotEscape(label)
+		}
+		sb.WriteString(fmt.Sprintf("\t\"%s\" [label=\"%s\", shape=%s];\n", dotEscape(n.Id), label, shape))
+	}
+	for _, e := range p.Edges {
+		label := e.Kind
+		if e.Label != "" {
+			label += " " + e.Label
+		}
+		sb.WriteString(fmt.Sprintf("\t\"%s\" -> \"%s\" [label=\"%s\"];\n", dotEscape(e.From), dotEscape(e.To), dotEscape(label)))
+	}
+	sb.WriteString("}\n")
+	return sb.String()
+}
@@ -0,0 +1,87 @@
+package sql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestSynthesisLineage(t *testing.T) {
+	//1+2 -> 3, 3+4 -> 5, 5+6 -> 7
+	edges := []synthesisEdge{{3, 1}, {3, 2}, {5, 3}, {5, 4}, {7, 5}, {7, 6}}
+	ancestors, descendants, parents := synthesisLineage(5, edges)
+	if !reflect.DeepEqual(ancestors, []int64{3, 4, 1, 2}) {
+		t.Errorf("ancestors %v", ancestors)
+	}
+	if !reflect.DeepEqual(descendants, []int64{7}) {
+		t.Errorf("descendants %v", descendants)
+	}
+	if !reflect.DeepEqual(parents[5], []int64{3, 4}) {
+		t.Errorf("parents of 5 %v", parents[5])
+	}
+	//a cycle in the rows doesn't loop
+	ancestors, descendants, _ = synthesisLineage(1, []synthesisEdge{{1, 2}, {2, 1}})
+	if !reflect.DeepEqual(ancestors, []int64{2}) || !reflect.DeepEqual(descendants, []int64{2}) {
+		t.Errorf("cycle ancestors %v descendants %v", ancestors, descendants)
+	}
+}
+
+func TestBuildProvenance(t *testing.T) {
+	data := &provenanceData{
+		items: map[int64]*NftMinerItems{
+			1: {ID: 1, EnergyPoint: 10, Creator: "a", Owner: "a", DateCreated: 100},
+			2: {ID: 2, EnergyPoint: 20, Creator: "b", Owner: "b", DateCreated: 200},
+			3: {ID: 3, EnergyPoint: 30, Creator: "a", Owner: "c", DateCreated: 300, MergeStatus: 1},
+		},
+		synthesis: map[int64]*NftMinerEvents{3: {TokenId: 3, Event: "Synthesis", TxHash: []byte{1}}},
+		transfers: map[int64][]NftMinerEvents{3: {{TokenId: 3, Event: "Transfer", Source: "a", DateCreated: 400}}},
+		stakes:    map[int64][]NftMinerStaking{},
+	}
+	p := buildProvenance(3, []int64{1, 2}, nil, map[int64][]int64{3: {1, 2}}, data)
+
+	var types []string
+	for _, v := range p.Timeline {
+		types = append(types, v.Type)
+	}
+	if !reflect.DeepEqual(types, []string{"mint", "mint", "synthesis", "transfer"}) {
+		t.Fatalf("timeline %v", types)
+	}
+	syn := p.Timeline[2]
+	if syn.MergedEnergy != 30 || syn.TxHash != "01" || !reflect.DeepEqual(syn.Parents, []int64{1, 2}) {
+		t.Errorf("synthesis event %+v", syn)
+	}
+	if tr := p.Timeline[3]; tr.From != "a" || tr.To != "c" {
+		t.Errorf("transfer event %+v", tr)
+	}
+
+	var synthesis int
+	for _, e := range p.Edges {
+		if e.Kind == "synthesis" {
+			synthesis++
+		}
+	}
+	if synthesis != 2 {
+		t.Errorf("%d synthesis edges, want 2", synthesis)
+	}
+
+	dot := p.Dot()
+	for _, want := range []string{
+		`digraph "nft_miner_3" {`,
+		`"nft_1" [label="NFT Miner #1\n10", shape=box, style=dashed];`,
+		`"nft_3" [label="NFT Miner #3\n30", shape=box];`,
+		`"acc_c" [label="c", shape=ellipse];`,
+		`"nft_1" -> "nft_3" [label="synthesis +10"];`,
+		`"acc_a" -> "acc_c" [label="transfer #3"];`,
+	} {
+		if !strings.Contains(dot, want) {
+			t.Errorf("dot missing %s\n%s", want, dot)
+		}
+	}
+}
+
+func TestDotEscape(t *testing.T) {
+	p := &NftMinerProvenanceResponse{Id: 1, Nodes: []ProvenanceNode{{Id: "acc_x", Kind: "account", Label: `a"b\c`}}}
+	if dot := p.Dot(); !strings.Contains(dot, `[label="a\"b\\c", shape=ellipse]`) {
+		t.Errorf("dot %s", dot)
+	}
+}
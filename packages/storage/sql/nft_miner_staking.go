@@ -1,10 +1,12 @@
 package sql
 
 import (
+	"context"
 	"encoding/json"
 	"errors"
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
+	"jutkey-server/packages/logs"
 	"reflect"
 	"time"
 )
@@ -28,7 +30,7 @@ func (p *NftMinerStaking) TableName() string {
 	return "1_nft_miner_staking"
 }
 
-func (p *NftMinerStaking) GetNftMinerStakeInfo(search any, page, limit int, order, wallet string) (*GeneralResponse, error) {
+func (p *NftMinerStaking) GetNftMinerStakeInfo(ctx context.Context, search any, page, limit int, order, wallet string) (*GeneralResponse, error) {
 	var (
 		rets  []NftMinerStakeInfoResponse
 		total int64
@@ -44,7 +46,7 @@ func (p *NftMinerStaking) GetNftMinerStakeInfo(search any, page, limit int, orde
 		var item NftMinerItems
 		f, err := item.GetByTokenHash(search.(string), wallet)
 		if err != nil {
-			log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Stake Info  Failed")
+			logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Stake Info  Failed")
 			return nil, err
 		}
 		if !f {
@@ -58,23 +60,23 @@ func (p *NftMinerStaking) GetNftMinerStakeInfo(search any, page, limit int, orde
 		}
 		nftId = tokenId
 	default:
-		log.WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Stake Info Search Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"search type": reflect.TypeOf(search).String()}).Warn("Get Nft Miner Stake Info Search Failed")
 		return nil, errors.New("request params invalid")
 	}
 
-	err := GetDB(nil).Table(p.TableName()).Where("token_id = ? AND staker = ?", nftId, wallet).Count(&total).Error
+	err := GetDBContext(ctx).Table(p.TableName()).Where("token_id = ? AND staker = ?", nftId, wallet).Count(&total).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft Miner stake info total err:", err.Error(), " nftId:", nftId)
+			logs.WithContext(ctx).Info("get nft Miner stake info total err:", err.Error(), " nftId:", nftId)
 		}
 		return nil, err
 	}
 
-	err = GetDB(nil).Raw(`SELECT id,token_id AS nft_miner_id,start_dated,end_dated,stake_amount,date_part('day',cast(to_char(to_timestamp(end_dated),'yyyy-MM-dd') as TIMESTAMP)-cast(to_char(to_timestamp(start_dated),'yyyy-MM-dd') as TIMESTAMP)) 
+	err = GetDBContext(ctx).Raw(`SELECT id,token_id AS nft_miner_id,start_dated,end_dated,stake_amount,date_part('day',cast(to_char(to_timestamp(end_dated),'yyyy-MM-dd') as TIMESTAMP)-cast(to_char(to_timestamp(start_dated),'yyyy-MM-dd') as TIMESTAMP)) 
 	AS cycle FROM "1_nft_miner_staking" WHERE token_id = ? AND staker = ?`+"ORDER BY "+order+` offset ? limit ?`, nftId, wallet, (page-1)*limit, limit).Find(&rets).Error
 	if err != nil {
 		if err != gorm.ErrRecordNotFound {
-			log.Info("get nft Miner stake info nftStaking err:", err.Error(), " nft Miner Id:", nftId)
+			logs.WithContext(ctx).Info("get nft Miner stake info nftStaking err:", err.Error(), " nft Miner Id:", nftId)
 		}
 		return nil, err
 	}
@@ -0,0 +1,282 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm/clause"
+	"time"
+)
+
+const (
+	nodePerformanceInterval = int64(3600)
+	nodePerformanceRange    = 7 * 24 //hours returned by default
+	nodePerformanceMaxRange = 90 * 24 * 3600
+)
+
+// NodePerformance is an hourly snapshot of one honor node
+type NodePerformance struct {
+	ID             int64  `gorm:"primary_key;not null"`
+	NodeId         int64  `gorm:"column:node_id;not null;uniqueIndex:idx_node_performance_hour"` //node_position
+	ConsensusMode  int32  `gorm:"column:consensus_mode;not null;uniqueIndex:idx_node_performance_hour"`
+	Hour           int64  `gorm:"column:hour;not null;uniqueIndex:idx_node_performance_hour"` //hour start,unix seconds
+	Blocks         int64  `gorm:"not null"`
+	ExpectedBlocks int64  `gorm:"column:expected_blocks;not null"`
+	MissedSlots    int64  `gorm:"column:missed_slots;not null"`
+	ReplyRate      string `gorm:"column:reply_rate;type:decimal(10,2);default:'0';not null"`
+	Vote           string `gorm:"type:decimal(40,12);default:'0';not null"`
+	Display        bool   `gorm:"not null"`
+}
+
+type NodePerformancePoint struct {
+	Time        int64  `json:"time"`
+	Blocks      int64  `json:"blocks"`
+	Expected    int64  `json:"expected"`
+	MissedSlots int64  `json:"missed_slots"`
+	ReplyRate   string `json:"reply_rate"`
+	Vote        string `json:"vote"`
+	Display     bool   `json:"display"`
+}
+
+type NodePerformanceResponse struct {
+	Id            int64                  `json:"id"`
+	ConsensusMode int32                  `json:"consensus_mode"`
+	From          int64                  `json:"from"`
+	To            int64                  `json:"to"`
+	Uptime        string                 `json:"uptime"`
+	Blocks        int64                  `json:"blocks"`
+	MissedSlots   int64                  `json:"missed_slots"`
+	VoteTrend     int                    `json:"vote_trend"` //0:unknown 1:Up 2:Down 3:Equal
+	Series        []NodePerformancePoint `json:"series"`
+}
+
+type nodeHourBlocks struct {
+	NodePosition  int64
+	ConsensusMode int32
+	Count         int64
+}
+
+func (p *NodePerformance) TableName() string {
+	return "honor_node_performance"
+}
+
+// RecordNodePerformance stores one row per honor node for the hour that has just completed,the display flag and
+// the votes are the current ones,so the hours missed while the server was stopped are not recorded afterwards
+func RecordNodePerformance() {
+	var (
+		p    NodePerformance
+		last int64
+	)
+	if !HasTableOrView(p.TableName()) {
+		return
+	}
+	if err := GetDB(nil).Table(p.TableName()).Select("coalesce(max(hour),0)").Take(&last).Error; err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("get node performance last hour failed")
+		return
+	}
+	hour, ok := nextPerformanceHour(last, time.Now().Unix())
+	if !ok {
+		return
+	}
+	nodes, err := getPerformanceNodes()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("get node performance nodes failed")
+		return
+	}
+	if len(nodes) == 0 {
+		return
+	}
+	if err = recordNodePerformanceHour(hour, nodes); err != nil {
+		log.WithFields(log.Fields{"error": err, "hour": hour}).Error("record node performance failed")
+	}
+}
+
+// nextPerformanceHour returns the start of the last completed hour when it's not recorded yet
+func nextPerformanceHour(last, now int64) (int64, bool) {
+	hour := now/nodePerformanceInterval*nodePerformanceInterval - nodePerformanceInterval
+	return hour, hour > last
+}
+
+type performanceNode struct {
+	NodeValue
+	VoteTotal decimal.Decimal
+	Display   bool
+}
+
+func getPerformanceNodes() ([]performanceNode, error) {
+	var (
+		hn   HonorNodeInfo
+		list []HonorNodeInfo
+	)
+	if !HasTableOrView(hn.TableName()) {
+		return nil, nil
+	}
+	if err := GetDB(nil).Table(hn.TableName()).Select("value,display").Find(&list).Error; err != nil {
+		return nil, err
+	}
+	var votes map[int64]decimal.Decimal
+	if NodeReady {
+		type nodeVote struct {
+			Id   int64
+			Vote decimal.Decimal
+		}
+		var vl []nodeVote
+		err := GetDB(nil).Raw(`SELECT id,round(coalesce(referendum_total,0) / 1e12,12) AS vote FROM "1_candidate_node_requests" WHERE deleted = 0`).Find(&vl).Error
+		if err != nil {
+			return nil, err
+		}
+		votes = make(map[int64]decimal.Decimal, len(vl))
+		for _, v := range vl {
+			votes[v.Id] = v.Vote
+		}
+	}
+	nodes := make([]performanceNode, 0, len(list))
+	for _, v := range list {
+		var node performanceNode
+		if err := json.Unmarshal([]byte(v.Value), &node.NodeValue); err != nil {
+			log.WithFields(log.Fields{"error": err, "value": v.Value}).Error("node performance value json unmarshal failed")
+			continue
+		}
+		node.VoteTotal = decimal.NewFromInt(node.Vote)
+		if vote, ok := votes[node.Id]; ok && node.ConsensusMode == 2 {
+			node.VoteTotal = vote
+		}
+		node.Display = v.Display
+		nodes = append(nodes, node)
+	}
+	return nodes, nil
+}
+
+func recordNodePerformanceHour(hour int64, nodes []performanceNode) error {
+	var list []nodeHourBlocks
+	err := GetDB(nil).Raw(`SELECT node_position,consensus_mode,count(1) FROM block_chain WHERE time >= ? AND time < ? GROUP BY node_position,consensus_mode`,
+		hour, hour+nodePerformanceInterval).Find(&list).Error
+	if err != nil {
+		return err
+	}
+	data := nodePerformanceRows(hour, nodes, list)
+	if len(data) == 0 {
+		return nil
+	}
+	return GetDB(nil).Clauses(clause.OnConflict{DoNothing: true}).CreateInBatches(data, 1000).Error
+}
+
+// nodePerformanceRows aggregates the blocks of an hour,packed by node_position and consensus_mode,into one row per node
+func nodePerformanceRows(hour int64, nodes []performanceNode, list []nodeHourBlocks) []NodePerformance {
+	var (
+		totals = make(map[int32]int64)
+		active = make(map[int32]int64)
+		blocks = make(map[int32]map[int64]int64)
+	)
+	for _, v := range list {
+		totals[v.ConsensusMode] += v.Count
+		if blocks[v.ConsensusMode] == nil {
+			blocks[v.ConsensusMode] = make(map[int64]int64)
+		}
+		blocks[v.ConsensusMode][v.NodePosition] = v.Count
+	}
+	for _, v := range nodes {
+		if v.Display {
+			active[v.ConsensusMode]++
+		}
+	}
+
+	data := make([]NodePerformance, 0, len(nodes))
+	for _, v := range nodes {
+		row := NodePerformance{
+			NodeId:        v.Id,
+			ConsensusMode: v.ConsensusMode,
+			Hour:          hour,
+			Blocks:        blocks[v.ConsensusMode][v.Id],
+			Vote:          v.VoteTotal.String(),
+			Display:       v.Display,
+		}
+		//block packing is round-robin between the displayed nodes of a consensus mode
+		if v.Display && active[v.ConsensusMode] > 0 {
+			row.ExpectedBlocks = totals[v.ConsensusMode] / active[v.ConsensusMode]
+		}
+		if row.ExpectedBlocks > row.Blocks {
+			row.MissedSlots = row.ExpectedBlocks - row.Blocks
+		}
+		row.ReplyRate = nodeReplyRate(row.Blocks, row.ExpectedBlocks).String()
+		data = append(data, row)
+	}
+	return data
+}
+
+func nodeReplyRate(blocks, expected int64) decimal.Decimal {
+	if expected <= 0 || blocks >= expected {
+		return decimal.NewFromInt(100)
+	}
+	return decimal.NewFromInt(blocks*100).DivRound(decimal.NewFromInt(expected), 2)
+}
+
+func GetNodePerformance(ctx context.Context, id int64, consensusMode int32, from, to int64) (*NodePerformanceResponse, error) {
+	var (
+		p    NodePerformance
+		list []NodePerformance
+		rets NodePerformanceResponse
+	)
+	if to <= 0 {
+		to = time.Now().Unix()
+	}
+	if from <= 0 {
+		from = to - nodePerformanceRange*nodePerformanceInterval
+	}
+	if from >= to {
+		return nil, errors.New("request params from must be less than to")
+	}
+	if to-from > nodePerformanceMaxRange {
+		return nil, errors.New("request params range cannot be greater than 90 days")
+	}
+	rets.Id = id
+	rets.ConsensusMode = consensusMode
+	rets.From = from
+	rets.To = to
+	rets.Series = []NodePerformancePoint{}
+	if !HasTableOrView(p.TableName()) {
+		rets.Uptime = "0"
+		return &rets, nil
+	}
+	err := GetDBContext(ctx).Where("node_id = ? AND consensus_mode = ? AND hour >= ? AND hour < ?", id, consensusMode, from, to).
+		Order("hour asc").Find(&list).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var up int64
+	for _, v := range list {
+		rets.Series = append(rets.Series, NodePerformancePoint{
+			Time:        v.Hour,
+			Blocks:      v.Blocks,
+			Expected:    v.ExpectedBlocks,
+			MissedSlots: v.MissedSlots,
+			ReplyRate:   v.ReplyRate,
+			Vote:        v.Vote,
+			Display:     v.Display,
+		})
+		rets.Blocks += v.Blocks
+		rets.MissedSlots += v.MissedSlots
+		if v.Display && (v.ExpectedBlocks == 0 || v.Blocks > 0) {
+			up++
+		}
+	}
+	if len(list) > 0 {
+		rets.Uptime = decimal.NewFromInt(up*100).DivRound(decimal.NewFromInt(int64(len(list))), 2).String()
+		first, _ := decimal.NewFromString(list[0].Vote)
+		last, _ := decimal.NewFromString(list[len(list)-1].Vote)
+		switch {
+		case last.GreaterThan(first):
+			rets.VoteTrend = 1
+		case last.LessThan(first):
+			rets.VoteTrend = 2
+		default:
+			rets.VoteTrend = 3
+		}
+	} else {
+		rets.Uptime = "0"
+	}
+	return &rets, nil
+}
@@ -0,0 +1,54 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNextPerformanceHour(t *testing.T) {
+	const now = 10*3600 + 1200
+	if hour, ok := nextPerformanceHour(0, now); !ok || hour != 9*3600 {
+		t.Errorf("first run = %d,%v, want the last completed hour only", hour, ok)
+	}
+	if hour, ok := nextPerformanceHour(2*3600, now); !ok || hour != 9*3600 {
+		t.Errorf("after a gap = %d,%v, want the last completed hour only", hour, ok)
+	}
+	if _, ok := nextPerformanceHour(9*3600, now); ok {
+		t.Error("recorded hour returned again")
+	}
+}
+
+func TestNodePerformanceRows(t *testing.T) {
+	node := func(id int64, mode int32, display bool) performanceNode {
+		return performanceNode{NodeValue: NodeValue{Id: id, ConsensusMode: mode}, VoteTotal: decimal.NewFromInt(id * 10), Display: display}
+	}
+	nodes := []performanceNode{node(1, 1, true), node(2, 1, true), node(3, 1, false), node(1, 2, true)}
+	list := []nodeHourBlocks{
+		{NodePosition: 1, ConsensusMode: 1, Count: 70},
+		{NodePosition: 2, ConsensusMode: 1, Count: 30},
+		{NodePosition: 1, ConsensusMode: 2, Count: 5},
+	}
+	rows := nodePerformanceRows(3600, nodes, list)
+	want := []struct {
+		blocks, expected, missed int64
+		rate                     string
+	}{
+		{70, 50, 0, "100"},
+		{30, 50, 20, "60"},
+		{0, 0, 0, "100"},
+		{5, 5, 0, "100"},
+	}
+	if len(rows) != len(want) {
+		t.Fatalf("%d rows, want %d", len(rows), len(want))
+	}
+	for i, w := range want {
+		r := rows[i]
+		if r.Hour != 3600 || r.NodeId != nodes[i].Id || r.Vote != nodes[i].VoteTotal.String() {
+			t.Errorf("row %d = %+v", i, r)
+		}
+		if r.Blocks != w.blocks || r.ExpectedBlocks != w.expected || r.MissedSlots != w.missed || r.ReplyRate != w.rate {
+			t.Errorf("row %d blocks %d expected %d missed %d rate %s, want %+v", i, r.Blocks, r.ExpectedBlocks, r.MissedSlots, r.ReplyRate, w)
+		}
+	}
+}
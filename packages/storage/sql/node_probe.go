@@ -0,0 +1,176 @@
+package sql
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	log "github.com/sirupsen/logrus"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const (
+	NodeProbeUnknown = iota
+	NodeProbeOnline
+	NodeProbeOffline
+	NodeProbeLagging
+)
+
+const (
+	nodeProbeTimeout   = 5 * time.Second
+	nodeProbeWorkers   = 10
+	nodeProbeLagBlocks = 10 //a node this many blocks behind the local chain is lagging
+	nodeProbePath      = "/api/v2/maxblockid"
+)
+
+var nodeProbeClient = &http.Client{Timeout: nodeProbeTimeout}
+
+type nodeProbeTarget struct {
+	ID         int64
+	ApiAddress string
+}
+
+type nodeProbeResult struct {
+	ID          int64
+	Status      int
+	Latency     int64
+	BlockHeight int64
+	Error       string
+}
+
+// ProbeHonorNodes calls the api of every known node and stores reachability, latency and block height
+func ProbeHonorNodes() {
+	var (
+		p    HonorNodeInfo
+		list []HonorNodeInfo
+		bk   Block
+	)
+	if !HasTableOrView(p.TableName()) {
+		return
+	}
+	if err := GetDB(nil).Table(p.TableName()).Select("id,value").Find(&list).Error; err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("probe honor node get list failed")
+		return
+	}
+	if len(list) == 0 {
+		return
+	}
+	if _, err := bk.GetMaxBlock(); err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("probe honor node get max block failed")
+		return
+	}
+
+	nodes := make([]nodeProbeTarget, 0, len(list))
+	for _, v := range list {
+		var value NodeValue
+		if err := json.Unmarshal([]byte(v.Value), &value); err != nil {
+			log.WithFields(log.Fields{"error": err, "value": v.Value}).Error("probe honor node value json unmarshal failed")
+			continue
+		}
+		nodes = append(nodes, nodeProbeTarget{ID: v.ID, ApiAddress: value.ApiAddress})
+	}
+	results := probeNodes(nodes, bk.ID, probeNode)
+
+	now := time.Now().Unix()
+	for _, v := range results {
+		update := map[string]any{
+			"probe_status": v.Status,
+			"latency":      v.Latency,
+			"probe_error":  v.Error,
+			"probed_at":    now,
+		}
+		if v.Status != NodeProbeOffline {
+			update["block_height"] = v.BlockHeight
+			update["last_seen"] = now
+		}
+		if err := GetDB(nil).Table(p.TableName()).Where("id = ?", v.ID).Updates(update).Error; err != nil {
+			log.WithFields(log.Fields{"error": err, "id": v.ID}).Error("probe honor node update failed")
+		}
+	}
+	p.InsertRedis()
+}
+
+// probeNodes runs probe for every node with at most nodeProbeWorkers at once
+func probeNodes(nodes []nodeProbeTarget, localHeight int64, probe func(apiAddress string, localHeight int64) nodeProbeResult) []nodeProbeResult {
+	var (
+		wg      sync.WaitGroup
+		lock    sync.Mutex
+		results = make([]nodeProbeResult, 0, len(nodes))
+		limit   = make(chan struct{}, nodeProbeWorkers)
+	)
+	for _, v := range nodes {
+		wg.Add(1)
+		limit <- struct{}{}
+		go func(v nodeProbeTarget) {
+			defer func() {
+				<-limit
+				wg.Done()
+			}()
+			rlt := probe(v.ApiAddress, localHeight)
+			rlt.ID = v.ID
+			lock.Lock()
+			results = append(results, rlt)
+			lock.Unlock()
+		}(v)
+	}
+	wg.Wait()
+	return results
+}
+
+func probeNode(apiAddress string, localHeight int64) (rlt nodeProbeResult) {
+	rlt.Status = NodeProbeOffline
+	if apiAddress == "" {
+		rlt.Error = "api address is empty"
+		return
+	}
+	url := strings.TrimRight(apiAddress, "/") + nodeProbePath
+	ctx, cancel := context.WithTimeout(context.Background(), nodeProbeTimeout)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		rlt.Error = err.Error()
+		return
+	}
+
+	start := time.Now()
+	resp, err := nodeProbeClient.Do(req)
+	if err != nil {
+		rlt.Error = err.Error()
+		return
+	}
+	defer resp.Body.Close()
+	rlt.Latency = time.Since(start).Milliseconds()
+	if resp.StatusCode != http.StatusOK {
+		rlt.Error = fmt.Sprintf("response status:%d", resp.StatusCode)
+		return
+	}
+	var body struct {
+		MaxBlockId int64 `json:"max_block_id"`
+	}
+	if err = json.NewDecoder(resp.Body).Decode(&body); err != nil {
+		rlt.Error = "response json decode failed:" + err.Error()
+		return
+	}
+	rlt.BlockHeight = body.MaxBlockId
+	if localHeight-rlt.BlockHeight > nodeProbeLagBlocks {
+		rlt.Status = NodeProbeLagging
+		rlt.Error = fmt.Sprintf("block height %d behind local %d", rlt.BlockHeight, localHeight)
+		return
+	}
+	rlt.Status = NodeProbeOnline
+	return
+}
+
+func nodeProbeStatusName(status int) string {
+	switch status {
+	case NodeProbeOnline:
+		return "online"
+	case NodeProbeOffline:
+		return "offline"
+	case NodeProbeLagging:
+		return "lagging"
+	}
+	return "unknown"
+}
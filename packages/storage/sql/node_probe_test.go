@@ -0,0 +1,109 @@
+package sql
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestProbeNode(t *testing.T) {
+	handler := func(body string, delay time.Duration) http.HandlerFunc {
+		return func(w http.ResponseWriter, r *http.Request) {
+			if r.URL.Path != nodeProbePath {
+				w.WriteHeader(http.StatusNotFound)
+				return
+			}
+			time.Sleep(delay)
+			w.Write([]byte(body))
+		}
+	}
+	client := nodeProbeClient
+	nodeProbeClient = &http.Client{Timeout: 100 * time.Millisecond}
+	defer func() { nodeProbeClient = client }()
+
+	for _, c := range []struct {
+		name   string
+		body   string
+		delay  time.Duration
+		status int
+		height int64
+		err    string
+	}{
+		{"online", `{"max_block_id":95}`, 0, NodeProbeOnline, 95, ""},
+		{"lagging", `{"max_block_id":80}`, 0, NodeProbeLagging, 80, "behind local 100"},
+		{"timeout", `{"max_block_id":100}`, 300 * time.Millisecond, NodeProbeOffline, 0, "Timeout"},
+		{"malformed", `{"max_block_id":`, 0, NodeProbeOffline, 0, "json decode failed"},
+	} {
+		t.Run(c.name, func(t *testing.T) {
+			srv := httptest.NewServer(handler(c.body, c.delay))
+			defer srv.Close()
+			//the trailing slash is trimmed
+			rlt := probeNode(srv.URL+"/", 100)
+			if rlt.Status != c.status || rlt.BlockHeight != c.height || !strings.Contains(rlt.Error, c.err) {
+				t.Errorf("result %+v", rlt)
+			}
+			if c.err == "" && rlt.Error != "" {
+				t.Errorf("error %s", rlt.Error)
+			}
+		})
+	}
+
+	//the node is down
+	srv := httptest.NewServer(handler("", 0))
+	srv.Close()
+	if rlt := probeNode(srv.URL, 100); rlt.Status != NodeProbeOffline || rlt.Error == "" {
+		t.Errorf("closed server %+v", rlt)
+	}
+	if rlt := probeNode("", 100); rlt.Status != NodeProbeOffline || rlt.Error != "api address is empty" {
+		t.Errorf("empty address %+v", rlt)
+	}
+}
+
+func TestProbeNodesWorkers(t *testing.T) {
+	var (
+		running, peak int32
+		nodes         []nodeProbeTarget
+	)
+	for i := int64(1); i <= 35; i++ {
+		nodes = append(nodes, nodeProbeTarget{ID: i})
+	}
+	results := probeNodes(nodes, 100, func(apiAddress string, localHeight int64) nodeProbeResult {
+		n := atomic.AddInt32(&running, 1)
+		for {
+			p := atomic.LoadInt32(&peak)
+			if n <= p || atomic.CompareAndSwapInt32(&peak, p, n) {
+				break
+			}
+		}
+		time.Sleep(10 * time.Millisecond)
+		atomic.AddInt32(&running, -1)
+		return nodeProbeResult{Status: NodeProbeOnline, BlockHeight: localHeight}
+	})
+	if peak > nodeProbeWorkers || peak < 2 {
+		t.Errorf("peak workers %d, limit %d", peak, nodeProbeWorkers)
+	}
+	ids := map[int64]bool{}
+	for _, r := range results {
+		ids[r.ID] = true
+	}
+	if len(results) != len(nodes) || len(ids) != len(nodes) {
+		t.Errorf("%d results for %d ids, want %d", len(results), len(ids), len(nodes))
+	}
+}
+
+func TestNodeProbeStatusName(t *testing.T) {
+	for status, want := range map[int]string{
+		NodeProbeUnknown: "unknown",
+		NodeProbeOnline:  "online",
+		NodeProbeOffline: "offline",
+		NodeProbeLagging: "lagging",
+		42:               "unknown",
+	} {
+		if got := nodeProbeStatusName(status); got != want {
+			t.Errorf("status %d name %s, want %s", status, got, want)
+		}
+	}
+}
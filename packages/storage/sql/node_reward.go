@@ -0,0 +1,203 @@
+package sql
+
+import (
+	"context"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
+	"time"
+)
+
+const (
+	nodeRewardSampleDays = 30
+	nodeRewardPrecision  = 1e12
+)
+
+type NodeRewardEstimateResponse struct {
+	Id              int64               `json:"id"`
+	Amount          string              `json:"amount"`
+	Eligible        bool                `json:"eligible"` //node earnest reached the pledge amount
+	PackedRate      string              `json:"packed_rate"`
+	NodeVote        string              `json:"node_vote"`
+	VoteShare       string              `json:"vote_share"`        //share of the node votes after adding amount
+	NodeDailyIncome string              `json:"node_daily_income"` //average fees of the blocks packed by the node per day
+	Daily           string              `json:"daily"`
+	Monthly         string              `json:"monthly"`
+	Annual          string              `json:"annual"`
+	Apr             string              `json:"apr"`
+	TokenSymbol     string              `json:"token_symbol"`
+	Realized        *NodeRewardRealized `json:"realized,omitempty"`
+}
+
+type NodeRewardRealized struct {
+	Deposited string              `json:"deposited"`
+	Withdrawn string              `json:"withdrawn"`
+	Reward    string              `json:"reward"`
+	List      []NodeRewardHistory `json:"list"`
+}
+
+type NodeRewardHistory struct {
+	Hash    string `json:"hash"`
+	Type    int64  `json:"type"` //18:earnest 19:substitute 20:referendum 21:withdraw substitute 22:withdraw referendum
+	Amount  string `json:"amount"`
+	Comment string `json:"comment"`
+	Time    int64  `json:"time"`
+}
+
+// NodeRewardEstimate projects the rewards of voting amount on node id: the fees of the blocks the node packed in the last
+// 30 days are its daily income,and the voter gets the share of amount in the node votes once amount is added
+// It's an estimate,the node may keep part of its income and its packing share changes with the other nodes
+func NodeRewardEstimate(ctx context.Context, id int64, amount decimal.Decimal, wallet string) (*NodeRewardEstimateResponse, error) {
+	var (
+		rets  NodeRewardEstimateResponse
+		zero  = decimal.Zero
+		prec  = decimal.NewFromInt(nodeRewardPrecision)
+		hundr = decimal.NewFromInt(100)
+	)
+	if !NodeReady {
+		return nil, errors.New("candidate node not ready")
+	}
+	if amount.LessThanOrEqual(zero) {
+		return nil, errors.New("request params amount invalid")
+	}
+	rets.Id = id
+	rets.Amount = amount.String()
+	rets.TokenSymbol = Tokens.Get(1)
+
+	type nodeVoteInfo struct {
+		Vote         decimal.Decimal
+		EarnestTotal decimal.Decimal
+		Packed       int64
+		MaxBlock     int64
+	}
+	var info nodeVoteInfo
+	f, err := isFound(GetDBContext(ctx).Raw(`
+SELECT round(coalesce(referendum_total,0) / 1e12,12) AS vote,coalesce(earnest_total,0) AS earnest_total,
+	(SELECT count(1) FROM block_chain WHERE node_position = cs.id AND consensus_mode = 2) AS packed,
+	(SELECT coalesce(max(id),0) FROM block_chain) AS max_block
+FROM "1_candidate_node_requests" AS cs WHERE deleted = 0 AND id = ?
+`, id).Take(&info))
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "node id": id}).Error("node reward estimate get node failed")
+		return nil, err
+	}
+	if !f {
+		return nil, fmt.Errorf("unknown node id:%d", id)
+	}
+	rets.Eligible = info.EarnestTotal.GreaterThanOrEqual(decimal.NewFromInt(PledgeAmount))
+	rets.NodeVote = info.Vote.String()
+
+	packedRate := zero
+	if info.MaxBlock > 0 {
+		packedRate = decimal.NewFromInt(info.Packed*100).DivRound(decimal.NewFromInt(info.MaxBlock), 2)
+	}
+	rets.PackedRate = packedRate.String()
+
+	//fee income of the blocks the node packed in the sample days
+	var nodeFee decimal.Decimal
+	since := time.Now().AddDate(0, 0, -nodeRewardSampleDays).Unix()
+	err = GetDBContext(ctx).Raw(`
+SELECT coalesce(sum(amount),0) FROM "1_history" WHERE ecosystem = 1 AND type IN(1,2) AND block_id IN(
+	SELECT id FROM block_chain WHERE node_position = ? AND consensus_mode = 2 AND time >= ?
+)`, id, since).Take(&nodeFee).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "node id": id}).Error("node reward estimate get node fee failed")
+		return nil, err
+	}
+	nodeDaily := nodeFee.Div(prec).Div(decimal.NewFromInt(nodeRewardSampleDays))
+	rets.NodeDailyIncome = nodeDaily.StringFixed(12)
+
+	share, daily := nodeRewardShare(nodeDaily, info.Vote, amount, rets.Eligible)
+	rets.VoteShare = share.Mul(hundr).StringFixed(2)
+	rets.Daily = daily.StringFixed(12)
+	rets.Monthly = daily.Mul(decimal.NewFromInt(30)).StringFixed(12)
+	annual := daily.Mul(decimal.NewFromInt(365))
+	rets.Annual = annual.StringFixed(12)
+	rets.Apr = annual.Mul(hundr).DivRound(amount, 2).String()
+
+	if wallet != "" {
+		rets.Realized, err = getNodeRewardRealized(ctx, id, wallet)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return &rets, nil
+}
+
+// nodeRewardShare returns the share of amount in the node votes and the daily income it's worth,nothing before the node is eligible
+func nodeRewardShare(nodeDaily, nodeVote, amount decimal.Decimal, eligible bool) (share, daily decimal.Decimal) {
+	share = amount.Div(nodeVote.Add(amount))
+	if eligible {
+		daily = nodeDaily.Mul(share)
+	}
+	return
+}
+
+// nodeRewardComments returns the history comments of staking on node id and withdrawing from it
+func nodeRewardComments(id int64) []string {
+	return []string{
+		fmt.Sprintf("Candidate Node Earnest #%d", id),
+		fmt.Sprintf("Candidate Node Substitute #%d", id),
+		fmt.Sprintf("Candidate Node Referendum #%d", id),
+		fmt.Sprintf("Candidate Node Withdraw Substitute #%d", id),
+		fmt.Sprintf("Candidate Node Withdraw Referendum #%d", id),
+	}
+}
+
+// getNodeRewardRealized lists what the wallet staked on the node (types 18-20) and withdrew from it (types 21,22).
+// The node pays the rewards of its voters back with the withdrawn stake,there's no separate reward record,
+// so the realized reward is what was withdrawn over what was staked and it's 0 while the stake is still on the node
+func getNodeRewardRealized(ctx context.Context, id int64, wallet string) (*NodeRewardRealized, error) {
+	keyId := converter.StringToAddress(wallet)
+	if keyId == 0 {
+		return nil, errors.New("request params wallet invalid")
+	}
+	var (
+		his  History
+		list []History
+	)
+	err := GetDBContext(ctx).Table(his.TableName()).Select("txhash,type,amount,comment,created_at").
+		Where("ecosystem = 1 AND type >= 18 AND type <= 22 AND (sender_id = ? OR recipient_id = ?) AND comment IN(?)", keyId, keyId, nodeRewardComments(id)).
+		Order("id desc").Find(&list).Error
+	if err != nil {
+		logs.WithContext(ctx).WithFields(log.Fields{"error": err, "wallet": wallet}).Error("node reward realized history failed")
+		return nil, err
+	}
+	return nodeRewardRealized(list), nil
+}
+
+func nodeRewardRealized(list []History) *NodeRewardRealized {
+	var (
+		rets     NodeRewardRealized
+		deposit  decimal.Decimal
+		withdraw decimal.Decimal
+		prec     = decimal.NewFromInt(nodeRewardPrecision)
+	)
+	rets.List = []NodeRewardHistory{}
+	for _, v := range list {
+		if v.Type >= 21 {
+			withdraw = withdraw.Add(v.Amount)
+		} else {
+			deposit = deposit.Add(v.Amount)
+		}
+		rets.List = append(rets.List, NodeRewardHistory{
+			Hash:    hex.EncodeToString(v.Txhash),
+			Type:    v.Type,
+			Amount:  v.Amount.Div(prec).String(),
+			Comment: v.Comment,
+			Time:    MsToSeconds(v.CreatedAt),
+		})
+	}
+	rets.Deposited = deposit.Div(prec).String()
+	rets.Withdrawn = withdraw.Div(prec).String()
+	reward := withdraw.Sub(deposit)
+	if reward.LessThan(decimal.Zero) {
+		reward = decimal.Zero
+	}
+	rets.Reward = reward.Div(prec).String()
+	return &rets
+}
@@ -0,0 +1,41 @@
+package sql
+
+import (
+	"testing"
+
+	"github.com/shopspring/decimal"
+)
+
+func TestNodeRewardShare(t *testing.T) {
+	d := decimal.RequireFromString
+	share, daily := nodeRewardShare(d("40"), d("300"), d("100"), true)
+	if !share.Equal(d("0.25")) || !daily.Equal(d("10")) {
+		t.Errorf("share %s daily %s, want 0.25 10", share, daily)
+	}
+	if _, daily = nodeRewardShare(d("40"), d("300"), d("100"), false); !daily.IsZero() {
+		t.Errorf("not eligible node daily %s, want 0", daily)
+	}
+}
+
+func TestNodeRewardRealized(t *testing.T) {
+	amount := func(n int64) decimal.Decimal {
+		return decimal.NewFromInt(n * nodeRewardPrecision)
+	}
+	list := []History{
+		{Type: 22, Amount: amount(130), Comment: "Candidate Node Withdraw Referendum #3"},
+		{Type: 20, Amount: amount(100), Comment: "Candidate Node Referendum #3"},
+		{Type: 19, Amount: amount(10), Comment: "Candidate Node Substitute #3"},
+	}
+	r := nodeRewardRealized(list)
+	if r.Deposited != "110" || r.Withdrawn != "130" || r.Reward != "20" || len(r.List) != 3 {
+		t.Errorf("realized %+v", r)
+	}
+	//stake still on the node
+	r = nodeRewardRealized(list[1:])
+	if r.Reward != "0" {
+		t.Errorf("reward %s, want 0", r.Reward)
+	}
+	if r = nodeRewardRealized(nil); r.List == nil || r.Reward != "0" {
+		t.Errorf("empty realized %+v", r)
+	}
+}
@@ -0,0 +1,317 @@
+package sql
+
+import (
+	"context"
+	"github.com/IBAX-io/go-ibax/packages/converter"
+	"github.com/shopspring/decimal"
+	log "github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+	"jutkey-server/conf"
+	"jutkey-server/packages/metrics"
+	"sync"
+	"time"
+)
+
+const (
+	DiscrepancyAccount = "account" //amount of 1_keys against the latest 1_history balance
+	DiscrepancyUtxo    = "utxo"    //unspent outputs of spent_info against the latest utxo_history balance
+
+	ReconcileSample = "sample"
+	ReconcileFull   = "full"
+
+	defaultReconcileSampleSize     = 500
+	defaultReconcileSampleInterval = 10   //minutes
+	reconcileBatch                 = 1000 //wallets compared by a query
+)
+
+var DiscrepancyKinds = []string{DiscrepancyAccount, DiscrepancyUtxo}
+
+// BalanceDiscrepancy is a wallet balance that differs from its latest history snapshot,
+// it's open while ResolvedAt is 0 and resolved when a later run finds the balances matching
+type BalanceDiscrepancy struct {
+	ID         int64           `gorm:"primary_key;not null" json:"id"`
+	Kind       string          `gorm:"not null" json:"kind"`
+	Ecosystem  int64           `gorm:"not null" json:"ecosystem"`
+	KeyId      int64           `gorm:"column:key_id;not null" json:"-"`
+	Account    string          `gorm:"-" json:"account"`
+	Expected   decimal.Decimal `gorm:"type:decimal(50);not null" json:"expected"` //recomputed balance
+	Stored     decimal.Decimal `gorm:"type:decimal(50);not null" json:"stored"`   //balance of the history snapshot
+	Block      int64           `gorm:"not null" json:"block"`                     //block of the history snapshot
+	Mode       string          `gorm:"not null" json:"mode"`                      //mode of the last run finding it
+	FoundAt    int64           `gorm:"column:found_at;not null" json:"found_at"`
+	CheckedAt  int64           `gorm:"column:checked_at;not null" json:"checked_at"`
+	ResolvedAt int64           `gorm:"column:resolved_at;not null" json:"resolved_at"`
+}
+
+func (p *BalanceDiscrepancy) TableName() string {
+	return "balance_discrepancies"
+}
+
+type walletKey struct {
+	Ecosystem int64
+	Id        int64
+}
+
+var reconcileState struct {
+	sync.Mutex
+	lastSample time.Time
+	lastFull   time.Time
+}
+
+// ReconcileBalances is called by the delay crontab,it checks every wallet each reconcile.full_interval hours
+// and a random sample of reconcile.sample_size wallets each reconcile.sample_interval minutes.
+// The balances are read from the replicas when there are any,only the discrepancies are written to the primary
+func ReconcileBalances() {
+	rc := conf.GetReconcileConf()
+	if rc == nil || !rc.Enable {
+		return
+	}
+	reconcileState.Lock()
+	defer reconcileState.Unlock()
+
+	now := time.Now()
+	if reconcileState.lastFull.IsZero() {
+		//the first full run waits for an interval,the samples start at once
+		reconcileState.lastFull = now
+	}
+	sampleInterval := rc.SampleInterval
+	if sampleInterval <= 0 {
+		sampleInterval = defaultReconcileSampleInterval
+	}
+	var mode string
+	switch {
+	case rc.FullInterval > 0 && now.Sub(reconcileState.lastFull) >= time.Duration(rc.FullInterval)*time.Hour:
+		mode = ReconcileFull
+		reconcileState.lastFull = now
+		reconcileState.lastSample = now
+	case now.Sub(reconcileState.lastSample) >= time.Duration(sampleInterval)*time.Minute:
+		mode = ReconcileSample
+		reconcileState.lastSample = now
+	default:
+		return
+	}
+	sampleSize := rc.SampleSize
+	if sampleSize <= 0 {
+		sampleSize = defaultReconcileSampleSize
+	}
+
+	checked, found, err := reconcileBalances(context.Background(), mode, sampleSize)
+	if err != nil {
+		log.WithFields(log.Fields{"error": err, "mode": mode}).Error("[reconcile] balances failed")
+		return
+	}
+	metrics.ReconcileCheckedKeys.WithLabelValues(mode).Add(float64(checked))
+	metrics.ReconcileLastRun.WithLabelValues(mode).Set(float64(time.Now().Unix()))
+	if err = updateDiscrepancyMetrics(); err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("[reconcile] count open discrepancies failed")
+	}
+	fields := log.Fields{"mode": mode, "checked": checked, "found": found, "elapsed": time.Since(now).String()}
+	if found > 0 {
+		log.WithFields(fields).Warn("[reconcile] balance discrepancies found")
+	} else {
+		log.WithFields(fields).Info("[reconcile] balances done")
+	}
+}
+
+// reconcileBalances compares the balances of the sampled wallets,or of every wallet in the full mode
+func reconcileBalances(ctx context.Context, mode string, sampleSize int) (checked, found int64, err error) {
+	//the replica may be behind the primary,the last block is the one it has
+	var utxoBlock int64
+	err = GetDBContext(ctx).Model(&UtxoHistory{}).Select("coalesce(max(block),0)").Scan(&utxoBlock).Error
+	if err != nil {
+		return 0, 0, err
+	}
+	//the last block may be partly written by the sync,the utxo balances are compared at the one before
+	if utxoBlock > 0 {
+		utxoBlock--
+	}
+
+	if mode == ReconcileSample {
+		keys, err := getReconcileSample(ctx, sampleSize)
+		if err != nil {
+			return 0, 0, err
+		}
+		found, err = reconcileKeys(ctx, mode, keys, utxoBlock)
+		return int64(len(keys)), found, err
+	}
+	after := walletKey{}
+	for {
+		keys, err := getReconcileKeysAfter(ctx, after, reconcileBatch)
+		if err != nil {
+			return checked, found, err
+		}
+		if len(keys) == 0 {
+			return checked, found, nil
+		}
+		n, err := reconcileKeys(ctx, mode, keys, utxoBlock)
+		if err != nil {
+			return checked, found, err
+		}
+		checked += int64(len(keys))
+		found += n
+		after = keys[len(keys)-1]
+	}
+}
+
+func getReconcileSample(ctx context.Context, size int) ([]walletKey, error) {
+	var (
+		k    Key
+		keys []walletKey
+	)
+	err := GetDBContext(ctx).Table(k.TableName()).Select("ecosystem,id").Order("random()").Limit(size).Find(&keys).Error
+	return keys, err
+}
+
+// getReconcileKeysAfter pages 1_keys in (ecosystem,id) order,the ecosystems start from 1
+func getReconcileKeysAfter(ctx context.Context, after walletKey, limit int) ([]walletKey, error) {
+	var (
+		k    Key
+		keys []walletKey
+	)
+	err := GetDBContext(ctx).Table(k.TableName()).Select("ecosystem,id").
+		Where("(ecosystem,id) > (?,?)", after.Ecosystem, after.Id).
+		Order("ecosystem ASC,id ASC").Limit(limit).Find(&keys).Error
+	return keys, err
+}
+
+func reconcileKeys(ctx context.Context, mode string, keys []walletKey, utxoBlock int64) (int64, error) {
+	if len(keys) == 0 {
+		return 0, nil
+	}
+	now := time.Now().Unix()
+	list, err := compareAccountBalances(ctx, keys)
+	if err != nil {
+		return 0, err
+	}
+	if err = saveDiscrepancies(GetDB(nil), DiscrepancyAccount, mode, keys, list, now); err != nil {
+		return 0, err
+	}
+	found := int64(len(list))
+	if utxoBlock <= 0 {
+		return found, nil
+	}
+	if list, err = compareUtxoBalances(ctx, keys, utxoBlock); err != nil {
+		return found, err
+	}
+	if err = saveDiscrepancies(GetDB(nil), DiscrepancyUtxo, mode, keys, list, now); err != nil {
+		return found, err
+	}
+	return found + int64(len(list)), nil
+}
+
+func walletKeyPairs(keys []walletKey) [][]any {
+	pairs := make([][]any, 0, len(keys))
+	for _, k := range keys {
+		pairs = append(pairs, []any{k.Ecosystem, k.Id})
+	}
+	return pairs
+}
+
+// compareAccountBalances returns the keys whose amount differs from their latest 1_history balance,
+// the keys without history are skipped
+func compareAccountBalances(ctx context.Context, keys []walletKey) ([]BalanceMismatch, error) {
+	var list []BalanceMismatch
+	err := GetDBContext(ctx).Raw(`
+SELECT k.ecosystem,k.id AS key_id,k.amount AS expected,h.balance AS stored,h.block FROM "1_keys" AS k
+JOIN LATERAL(
+	SELECT CASE WHEN sender_id = k.id THEN sender_balance ELSE recipient_balance END AS balance,block_id AS block
+	FROM "1_history" WHERE ecosystem = k.ecosystem AND (sender_id = k.id OR recipient_id = k.id)
+	ORDER BY id DESC LIMIT 1
+)AS h ON(true)
+WHERE (k.ecosystem,k.id) IN ? AND k.amount <> h.balance`, walletKeyPairs(keys)).Find(&list).Error
+	return list, err
+}
+
+// compareUtxoBalances returns the keys whose unspent outputs at block differ from their latest
+// utxo_history balance at block,the keys without outputs nor history are skipped
+func compareUtxoBalances(ctx context.Context, keys []walletKey, block int64) ([]BalanceMismatch, error) {
+	var list []BalanceMismatch
+	args := map[string]any{"keys": walletKeyPairs(keys), "block": block}
+	err := GetDBContext(ctx).Raw(utxoBalancesQuery(true)+` WHERE expected <> stored`, args).Find(&list).Error
+	return list, err
+}
+
+// saveDiscrepancies upserts the open discrepancies of list and resolves the open ones of the other checked keys,
+// db is the primary
+func saveDiscrepancies(db *gorm.DB, kind, mode string, keys []walletKey, list []BalanceMismatch, now int64) error {
+	mismatched := make(map[walletKey]bool, len(list))
+	if len(list) > 0 {
+		rows := make([]BalanceDiscrepancy, 0, len(list))
+		for _, m := range list {
+			mismatched[walletKey{Ecosystem: m.Ecosystem, Id: m.KeyId}] = true
+			rows = append(rows, BalanceDiscrepancy{Kind: kind, Ecosystem: m.Ecosystem, KeyId: m.KeyId, Expected: m.Expected,
+				Stored: m.Stored, Block: m.Block, Mode: mode, FoundAt: now, CheckedAt: now})
+		}
+		err := db.Clauses(clause.OnConflict{
+			Columns: []clause.Column{{Name: "kind"}, {Name: "ecosystem"}, {Name: "key_id"}},
+			//a literal predicate,the partial index isn't inferred from a bound parameter
+			TargetWhere: clause.Where{Exprs: []clause.Expression{clause.Expr{SQL: "resolved_at = 0"}}},
+			DoUpdates:   clause.AssignmentColumns([]string{"expected", "stored", "block", "mode", "checked_at"}),
+		}).Create(&rows).Error
+		if err != nil {
+			return err
+		}
+	}
+	var matched []walletKey
+	for _, k := range keys {
+		if !mismatched[k] {
+			matched = append(matched, k)
+		}
+	}
+	if len(matched) == 0 {
+		return nil
+	}
+	return db.Model(&BalanceDiscrepancy{}).
+		Where("kind = ? AND resolved_at = 0 AND (ecosystem,key_id) IN ?", kind, walletKeyPairs(matched)).
+		Update("resolved_at", now).Error
+}
+
+func updateDiscrepancyMetrics() error {
+	var list []struct {
+		Kind  string
+		Count int64
+	}
+	err := GetDB(nil).Model(&BalanceDiscrepancy{}).Select("kind,count(1) AS count").
+		Where("resolved_at = 0").Group("kind").Find(&list).Error
+	if err != nil {
+		return err
+	}
+	for _, kind := range DiscrepancyKinds {
+		metrics.BalanceDiscrepancies.WithLabelValues(kind).Set(0)
+	}
+	for _, v := range list {
+		metrics.BalanceDiscrepancies.WithLabelValues(v.Kind).Set(float64(v.Count))
+	}
+	return nil
+}
+
+// GetBalanceDiscrepancies returns the open discrepancies,or the resolved ones,of kind or of every kind
+func GetBalanceDiscrepancies(ctx context.Context, kind string, resolved bool, page, limit int) (*GeneralResponse, error) {
+	var (
+		rets GeneralResponse
+		list []BalanceDiscrepancy
+	)
+	rets.Page = page
+	rets.Limit = limit
+	q := GetDBContext(ctx).Model(&BalanceDiscrepancy{})
+	if kind != "" {
+		q = q.Where("kind = ?", kind)
+	}
+	if resolved {
+		q = q.Where("resolved_at > 0")
+	} else {
+		q = q.Where("resolved_at = 0")
+	}
+	if err := q.Count(&rets.Total).Error; err != nil {
+		return nil, err
+	}
+	if err := q.Order("id desc").Offset((page - 1) * limit).Limit(limit).Find(&list).Error; err != nil {
+		return nil, err
+	}
+	for i := range list {
+		list[i].Account = converter.AddressToString(list[i].KeyId)
+	}
+	rets.List = list
+	return &rets, nil
+}
@@ -0,0 +1,116 @@
+package sql
+
+import (
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/shopspring/decimal"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+type dryRunStatement struct {
+	sql  string
+	vars []any
+}
+
+// dryRunDB builds the statements without a database,they're appended to stmts
+func dryRunDB(t *testing.T, stmts *[]dryRunStatement) *gorm.DB {
+	db, err := gorm.Open(postgres.New(postgres.Config{DSN: "host=127.0.0.1"}),
+		&gorm.Config{DryRun: true, DisableAutomaticPing: true, SkipDefaultTransaction: true})
+	if err != nil {
+		t.Fatal(err)
+	}
+	record := func(db *gorm.DB) {
+		*stmts = append(*stmts, dryRunStatement{db.Statement.SQL.String(), db.Statement.Vars})
+	}
+	if err = db.Callback().Create().After("gorm:create").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	if err = db.Callback().Update().After("gorm:update").Register("test:record", record); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestSaveDiscrepancies(t *testing.T) {
+	var stmts []dryRunStatement
+	db := dryRunDB(t, &stmts)
+	keys := []walletKey{{1, 10}, {1, 11}, {2, 10}}
+	list := []BalanceMismatch{{Ecosystem: 1, KeyId: 11, Expected: decimal.NewFromInt(5), Stored: decimal.NewFromInt(3), Block: 7}}
+	if err := saveDiscrepancies(db, DiscrepancyUtxo, ReconcileSample, keys, list, 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 2 {
+		t.Fatalf("%d statements, want 2", len(stmts))
+	}
+
+	upsert := stmts[0]
+	for _, want := range []string{
+		`INSERT INTO "balance_discrepancies"`,
+		`ON CONFLICT ("kind","ecosystem","key_id")`,
+		`WHERE resolved_at = 0 DO UPDATE SET`,
+		`"expected"="excluded"."expected"`,
+		`"checked_at"="excluded"."checked_at"`,
+	} {
+		if !strings.Contains(upsert.sql, want) {
+			t.Errorf("upsert missing %s\n%s", want, upsert.sql)
+		}
+	}
+	//found_at and resolved_at keep the values of the open discrepancy
+	if strings.Contains(upsert.sql, `"found_at"="excluded"`) || strings.Contains(upsert.sql, `"resolved_at"="excluded"`) {
+		t.Errorf("upsert updates found_at or resolved_at\n%s", upsert.sql)
+	}
+
+	resolve := stmts[1]
+	if !strings.HasPrefix(resolve.sql, `UPDATE "balance_discrepancies" SET "resolved_at"=`) ||
+		!strings.Contains(resolve.sql, `kind = $2 AND resolved_at = 0 AND (ecosystem,key_id) IN (($3,$4),($5,$6))`) {
+		t.Errorf("resolve %s", resolve.sql)
+	}
+	//the mismatched key isn't resolved
+	if want := []any{int64(100), DiscrepancyUtxo, int64(1), int64(10), int64(2), int64(10)}; !reflect.DeepEqual(resolve.vars, want) {
+		t.Errorf("resolve vars %v, want %v", resolve.vars, want)
+	}
+}
+
+func TestSaveDiscrepanciesNone(t *testing.T) {
+	var stmts []dryRunStatement
+	db := dryRunDB(t, &stmts)
+	keys := []walletKey{{1, 10}}
+	if err := saveDiscrepancies(db, DiscrepancyAccount, ReconcileFull, keys, nil, 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || !strings.HasPrefix(stmts[0].sql, "UPDATE") {
+		t.Fatalf("statements %v, want the resolve only", stmts)
+	}
+	//every checked key mismatched,nothing to resolve
+	stmts = nil
+	list := []BalanceMismatch{{Ecosystem: 1, KeyId: 10}}
+	if err := saveDiscrepancies(db, DiscrepancyAccount, ReconcileFull, keys, list, 100); err != nil {
+		t.Fatal(err)
+	}
+	if len(stmts) != 1 || !strings.HasPrefix(stmts[0].sql, "INSERT") {
+		t.Fatalf("statements %v, want the upsert only", stmts)
+	}
+}
+
+func TestUtxoBalancesQuery(t *testing.T) {
+	byEcosystem, byKeys := utxoBalancesQuery(false), utxoBalancesQuery(true)
+	for _, q := range []string{byEcosystem, byKeys} {
+		if !strings.HasPrefix(strings.TrimSpace(q), "SELECT") {
+			t.Errorf("query doesn't start with SELECT,it isn't sent to a replica\n%s", q)
+		}
+	}
+	if strings.Contains(byEcosystem, "@keys") || strings.Count(byEcosystem, "ecosystem = @ecosystem") != 3 {
+		t.Errorf("ecosystem query\n%s", byEcosystem)
+	}
+	for _, want := range []string{"(s1.ecosystem,s1.output_key_id) IN @keys", "(ecosystem,sender_id) IN @keys", "(ecosystem,recipient_id) IN @keys"} {
+		if !strings.Contains(byKeys, want) {
+			t.Errorf("keys query missing %s", want)
+		}
+	}
+	if strings.Contains(byKeys, "@ecosystem") {
+		t.Errorf("keys query filters @ecosystem\n%s", byKeys)
+	}
+}
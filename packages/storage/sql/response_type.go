@@ -102,6 +102,10 @@ type NodeListResponse struct {
 	MyVote         float64 `json:"myVote"`
 	MyStaking      string  `json:"myStaking"`
 	Pending        bool    `json:"pending"`
+	Status         string  `json:"status"`  //unknown,online,offline,lagging
+	Latency        int64   `json:"latency"` //ms
+	BlockHeight    int64   `json:"blockHeight"`
+	LastSeen       int64   `json:"lastSeen"`
 }
 
 type NodeDetailResponse struct {
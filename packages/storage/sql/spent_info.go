@@ -1,10 +1,12 @@
 package sql
 
 import (
+	"context"
 	"errors"
 	"github.com/IBAX-io/go-ibax/packages/storage/sqldb"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
+	"jutkey-server/packages/logs"
 	"strconv"
 )
 
@@ -45,7 +47,7 @@ func (si *SpentInfo) GetAmountByKeyId(keyId int64, ecosystem int64) (decimal.Dec
 	return utxoAmount.Sum, nil
 }
 
-func GetUtxoInput(keyId int64, search []int64) (*[]UtxoInputResponse, error) {
+func GetUtxoInput(ctx context.Context, keyId int64, search []int64) (*[]UtxoInputResponse, error) {
 	var (
 		rets []UtxoInputResponse
 		si   SpentInfo
@@ -56,10 +58,10 @@ func GetUtxoInput(keyId int64, search []int64) (*[]UtxoInputResponse, error) {
 		return nil, errors.New("search max len 10")
 	}
 
-	err = GetDB(nil).Table(si.TableName()).Select("count(1) AS input,ecosystem").
+	err = GetDBContext(ctx).Table(si.TableName()).Select("count(1) AS input,ecosystem").
 		Where("input_tx_hash is NULL AND output_key_id = ? AND ecosystem IN(?)", keyId, search).Group("ecosystem").Find(&rets).Error
 	if err != nil {
-		log.WithFields(log.Fields{"err": err}).Warn("Get Utxo Input Failed")
+		logs.WithContext(ctx).WithFields(log.Fields{"err": err}).Warn("Get Utxo Input Failed")
 		return nil, err
 	}
 	for _, v1 := range search {
@@ -1,6 +1,7 @@
 package sql
 
 import (
+	"context"
 	"errors"
 	"github.com/shopspring/decimal"
 	log "github.com/sirupsen/logrus"
@@ -64,18 +65,14 @@ func GetStatisticsData() error {
 }
 
 func (m *Statistics) GetRedis() (bool, error) {
-	rd := kv.RedisParams{
-		Key:   "statistics",
-		Value: "",
-	}
-	err := rd.Get()
+	val, err := kv.Default.Get(context.Background(), StatisticsCacheKey)
 	if err != nil {
-		if err.Error() == "redis: nil" || err.Error() == "EOF" {
+		if errors.Is(err, kv.ErrNotFound) {
 			return false, nil
 		}
 		return false, err
 	}
-	err = m.Unmarshal([]byte(rd.Value))
+	err = m.Unmarshal([]byte(val))
 	if err != nil {
 		return false, err
 	}
@@ -88,16 +85,7 @@ func (m *Statistics) SetRedis() error {
 	if err != nil {
 		return err
 	}
-
-	rd := kv.RedisParams{
-		Key:   "statistics",
-		Value: string(val),
-	}
-	err = rd.Set()
-	if err != nil {
-		return err
-	}
-	return nil
+	return kv.Default.Set(context.Background(), StatisticsCacheKey, string(val))
 }
 
 func (s *Statistics) Marshal() ([]byte, error) {
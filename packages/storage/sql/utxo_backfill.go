@@ -0,0 +1,398 @@
+package sql
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"github.com/jackc/pgx/v4"
+	"github.com/jackc/pgx/v4/stdlib"
+	log "github.com/sirupsen/logrus"
+	"jutkey-server/conf"
+	"runtime"
+	"time"
+)
+
+const (
+	defaultBackfillMinBlocks   = 10000
+	defaultBackfillChunkBlocks = 500
+	defaultBackfillBatchRows   = 20000
+	backfillOutputsBatch       = 1000 //hashes of a spent_info outputs query
+)
+
+var utxoHistoryColumns = []string{"block", "hash", "sender_id", "recipient_id", "amount", "created_at",
+	"ecosystem", "type", "sub_type", "sender_balance", "recipient_balance"}
+
+type BackfillOptions struct {
+	Workers     int
+	ChunkBlocks int64
+	BatchRows   int
+	DryRun      bool //decode and compute the balances without loading the rows
+}
+
+// BackfillProgress is reported after every loaded batch
+type BackfillProgress struct {
+	FromBlock int64
+	ToBlock   int64
+	Block     int64 //last merged block
+	Rows      int64 //loaded rows
+	Elapsed   time.Duration
+}
+
+func (p BackfillProgress) BlocksPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Block-p.FromBlock+1) / p.Elapsed.Seconds()
+}
+
+func (p BackfillProgress) RowsPerSecond() float64 {
+	if p.Elapsed <= 0 {
+		return 0
+	}
+	return float64(p.Rows) / p.Elapsed.Seconds()
+}
+
+// backfillChunk is the decoded movements of the blocks [start,end)
+type backfillChunk struct {
+	index int
+	start int64
+	end   int64
+	moves []utxoMovement
+	err   error
+}
+
+// GetBackfillOptions returns the options of the backfill section
+func GetBackfillOptions() BackfillOptions {
+	var opts BackfillOptions
+	if bc := conf.GetBackfillConf(); bc != nil {
+		opts = BackfillOptions{Workers: bc.Workers, ChunkBlocks: bc.ChunkBlocks, BatchRows: bc.BatchRows}
+	}
+	return opts
+}
+
+func backfillOptions(opts BackfillOptions) BackfillOptions {
+	if opts.Workers <= 0 {
+		opts.Workers = runtime.NumCPU()
+	}
+	if opts.ChunkBlocks <= 0 {
+		opts.ChunkBlocks = defaultBackfillChunkBlocks
+	}
+	if opts.BatchRows <= 0 {
+		opts.BatchRows = defaultBackfillBatchRows
+	}
+	return opts
+}
+
+// GetUtxoHistoryLag returns the last block of utxo_history and the last utxo transaction block of tx_data
+func GetUtxoHistoryLag() (last, target int64, err error) {
+	var (
+		uh UtxoHistory
+		td TransactionData
+	)
+	if _, err = uh.GetLast(); err != nil {
+		return
+	}
+	if _, err = td.GetLastByType(formatTxDataType(true)); err != nil {
+		return
+	}
+	return uh.Block, td.Block, nil
+}
+
+// InitUtxoBackfill starts a backfill when utxo_history is at least backfill.min_blocks behind tx_data,
+// the live sync of the crontab is skipped until it is done and it's skipped itself while a reindex runs
+func InitUtxoBackfill(ctx context.Context) {
+	bc := conf.GetBackfillConf()
+	if bc == nil || !bc.Enable {
+		return
+	}
+	minBlocks := bc.MinBlocks
+	if minBlocks <= 0 {
+		minBlocks = defaultBackfillMinBlocks
+	}
+	last, target, err := GetUtxoHistoryLag()
+	if err != nil {
+		log.WithFields(log.Fields{"error": err}).Error("[utxo backfill] get utxo history lag failed")
+		return
+	}
+	if target-last < minBlocks {
+		return
+	}
+	//locked before the crontab starts the live sync
+	utxoSyncMu.Lock()
+	go func() {
+		defer utxoSyncMu.Unlock()
+		opts := GetBackfillOptions()
+		for target-last >= minBlocks {
+			var p BackfillProgress
+			locked, err := withIndexerLock(false, func() (err error) {
+				p, err = backfillUtxoHistory(ctx, opts, func(p BackfillProgress) {
+					log.WithFields(log.Fields{"block": p.Block, "to": p.ToBlock, "rows": p.Rows,
+						"blocks_per_second": int64(p.BlocksPerSecond()), "rows_per_second": int64(p.RowsPerSecond())}).Info("[utxo backfill] progress")
+				})
+				return err
+			})
+			if err != nil {
+				if !errors.Is(err, context.Canceled) {
+					log.WithFields(log.Fields{"error": err}).Error("[utxo backfill] failed,the live sync continues")
+				}
+				return
+			}
+			if !locked {
+				log.Info("[utxo backfill] a reindex is running,it rebuilds utxo_history itself")
+				return
+			}
+			log.WithFields(log.Fields{"from": p.FromBlock, "to": p.ToBlock, "rows": p.Rows, "elapsed": p.Elapsed.String()}).Info("[utxo backfill] done")
+			//tx_data may have grown meanwhile
+			if last, target, err = GetUtxoHistoryLag(); err != nil {
+				log.WithFields(log.Fields{"error": err}).Error("[utxo backfill] get utxo history lag failed")
+				return
+			}
+		}
+	}()
+}
+
+// BackfillUtxoHistory syncs utxo_history to the last utxo transaction of tx_data,the blocks are decoded by
+// parallel workers,merged in block order to compute the running balances and loaded with COPY.
+// No alerts are sent for the loaded rows.
+// It waits for a running sync or reindex,the sync of the other servers skips its rounds meanwhile
+func BackfillUtxoHistory(ctx context.Context, opts BackfillOptions, progress func(BackfillProgress)) (BackfillProgress, error) {
+	utxoSyncMu.Lock()
+	defer utxoSyncMu.Unlock()
+	var p BackfillProgress
+	_, err := withIndexerLock(true, func() (err error) {
+		p, err = backfillUtxoHistory(ctx, opts, progress)
+		return err
+	})
+	return p, err
+}
+
+func backfillUtxoHistory(ctx context.Context, opts BackfillOptions, progress func(BackfillProgress)) (BackfillProgress, error) {
+	opts = backfillOptions(opts)
+	var p BackfillProgress
+	last, target, err := GetUtxoHistoryLag()
+	if err != nil {
+		return p, err
+	}
+	//the last block may have been partly written,it's rebuilt
+	p.FromBlock, p.ToBlock, p.Block = last, target, last-1
+	if last >= target {
+		return p, nil
+	}
+	if last > 0 && !opts.DryRun {
+		if err = (&UtxoHistory{Block: last}).RollbackTransaction(); err != nil {
+			return p, err
+		}
+	}
+	return runBackfill(ctx, p, opts, backfillSteps{
+		decode: decodeBackfillChunk,
+		load:   loadBackfillBalances,
+		copy:   copyUtxoHistory,
+	}, progress)
+}
+
+// backfillSteps are the database steps of a backfill
+type backfillSteps struct {
+	decode func(start, end int64) ([]utxoMovement, error)
+	load   func(balances utxoBalances, m utxoMovement) error
+	copy   func(ctx context.Context, rows []UtxoHistory) error
+}
+
+// runBackfill decodes the blocks [p.FromBlock,p.ToBlock] in chunks by opts.Workers goroutines
+// and merges the chunks in block order
+func runBackfill(ctx context.Context, p BackfillProgress, opts BackfillOptions, steps backfillSteps, progress func(BackfillProgress)) (BackfillProgress, error) {
+	last, target := p.FromBlock, p.ToBlock
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+	chunks := make(chan backfillChunk)
+	results := make(chan backfillChunk, opts.Workers)
+	//window bounds the chunks decoded ahead of the merged one
+	window := make(chan struct{}, opts.Workers*2)
+	go func() {
+		defer close(chunks)
+		for i, st := 0, last; st <= target; i, st = i+1, st+opts.ChunkBlocks {
+			end := st + opts.ChunkBlocks
+			if end > target+1 {
+				end = target + 1
+			}
+			select {
+			case window <- struct{}{}:
+			case <-ctx.Done():
+				return
+			}
+			select {
+			case chunks <- backfillChunk{index: i, start: st, end: end}:
+			case <-ctx.Done():
+				return
+			}
+		}
+	}()
+	for i := 0; i < opts.Workers; i++ {
+		go func() {
+			for c := range chunks {
+				c.moves, c.err = steps.decode(c.start, c.end)
+				select {
+				case results <- c:
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	var (
+		start    = time.Now()
+		balances = make(utxoBalances)
+		pending  = make(map[int]backfillChunk)
+		rows     []UtxoHistory
+		next     int
+	)
+	flush := func() error {
+		if !opts.DryRun {
+			if err := steps.copy(ctx, rows); err != nil {
+				return err
+			}
+		}
+		p.Rows += int64(len(rows))
+		p.Elapsed = time.Since(start)
+		rows = rows[:0]
+		if progress != nil {
+			progress(p)
+		}
+		return nil
+	}
+	total := int((target-last)/opts.ChunkBlocks) + 1
+	for next < total {
+		var c backfillChunk
+		select {
+		case c = <-results:
+		case <-ctx.Done():
+			return p, ctx.Err()
+		}
+		if c.err != nil {
+			return p, fmt.Errorf("[utxo backfill]blocks %d-%d:%w", c.start, c.end-1, c.err)
+		}
+		pending[c.index] = c
+		//the chunks are merged in block order,the balances are carried from a chunk to the next one
+		for mc, ok := pending[next]; ok; mc, ok = pending[next] {
+			delete(pending, next)
+			next++
+			<-window
+			for _, m := range mc.moves {
+				if err := steps.load(balances, m); err != nil {
+					return p, err
+				}
+				data, err := balances.apply(m)
+				if err != nil {
+					return p, err
+				}
+				rows = append(rows, data)
+			}
+			p.Block = mc.end - 1
+			if len(rows) >= opts.BatchRows || next == total {
+				if err := flush(); err != nil {
+					return p, err
+				}
+			}
+		}
+	}
+	return p, nil
+}
+
+// loadBackfillBalances loads the balances of the keys of m met for the first time,
+// the rows of the backfill are not read back as the balances are carried in memory
+func loadBackfillBalances(balances utxoBalances, m utxoMovement) error {
+	keys := []int64{m.data.RecipientId}
+	if m.data.Type != 5 {
+		keys = append(keys, m.data.SenderId)
+	}
+	for _, key := range keys {
+		if !balances.has(m.data.Ecosystem, key) {
+			if err := balances.load(m.data.Ecosystem, key); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// decodeBackfillChunk decodes the utxo transactions of the blocks [start,end)
+func decodeBackfillChunk(start, end int64) ([]utxoMovement, error) {
+	txList, err := getSpentInfoHashList(start, end)
+	if err != nil {
+		return nil, err
+	}
+	outputs, err := getBackfillOutputs(*txList)
+	if err != nil {
+		return nil, err
+	}
+	var moves []utxoMovement
+	for _, val := range *txList {
+		m, err := decodeUtxoTx(val, func(txHash []byte) ([]SpentInfo, error) {
+			return outputs[string(txHash)], nil
+		})
+		if err != nil {
+			return nil, err
+		}
+		moves = append(moves, m...)
+	}
+	return moves, nil
+}
+
+// getBackfillOutputs returns the spent_info outputs of the transactions by hash,
+// in the order of SpentInfo.GetOutputs
+func getBackfillOutputs(txList []spentInfoTxData) (map[string][]SpentInfo, error) {
+	outputs := make(map[string][]SpentInfo)
+	for i := 0; i < len(txList); i += backfillOutputsBatch {
+		end := i + backfillOutputsBatch
+		if end > len(txList) {
+			end = len(txList)
+		}
+		hashes := make([][]byte, 0, end-i)
+		for _, val := range txList[i:end] {
+			hashes = append(hashes, val.Hash)
+		}
+		var (
+			si   SpentInfo
+			list []SpentInfo
+		)
+		err := GetDB(nil).Table(si.TableName()).Where("output_tx_hash IN ?", hashes).
+			Order("ecosystem ASC").Find(&list).Error
+		if err != nil {
+			return nil, err
+		}
+		for _, v := range list {
+			outputs[string(v.OutputTxHash)] = append(outputs[string(v.OutputTxHash)], v)
+		}
+	}
+	return outputs, nil
+}
+
+// copyUtxoHistory bulk loads the rows into utxo_history with COPY
+func copyUtxoHistory(ctx context.Context, rows []UtxoHistory) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	db, err := GetDB(nil).DB()
+	if err != nil {
+		return err
+	}
+	conn, err := db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+	return conn.Raw(func(driverConn any) error {
+		pc, ok := driverConn.(*stdlib.Conn)
+		if !ok {
+			return fmt.Errorf("copy utxo history: unsupported driver connection %T", driverConn)
+		}
+		var uh UtxoHistory
+		_, err := pc.Conn().CopyFrom(ctx, pgx.Identifier{uh.TableName()}, utxoHistoryColumns,
+			pgx.CopyFromSlice(len(rows), func(i int) ([]any, error) {
+				r := rows[i]
+				return []any{r.Block, r.Hash, r.SenderId, r.RecipientId, r.Amount, r.CreatedAt,
+					r.Ecosystem, r.Type, r.SubType, r.SenderBalance, r.RecipientBalance}, nil
+			}))
+		return err
+	})
+}
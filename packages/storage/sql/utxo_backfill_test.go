@@ -0,0 +1,102 @@
+package sql
+
+import (
+	"context"
+	"github.com/shopspring/decimal"
+	"sync"
+	"testing"
+	"time"
+)
+
+// testBackfillSteps decodes one transfer of 1 from key 10 to key 20 per chunk,the first chunk also
+// starts key 10 up with 100. The later chunks are decoded first so they're merged out of order
+func testBackfillSteps(copied *[][]UtxoHistory) backfillSteps {
+	var mu sync.Mutex
+	return backfillSteps{
+		decode: func(start, end int64) ([]utxoMovement, error) {
+			time.Sleep(time.Duration(50-start) * time.Millisecond)
+			var moves []utxoMovement
+			if start == 1 {
+				moves = append(moves, utxoMovement{
+					data:   UtxoHistory{Block: start, Ecosystem: 1, Type: 5, SenderId: 5555, RecipientId: 10},
+					amount: decimal.NewFromInt(100),
+				})
+			}
+			return append(moves, utxoMovement{
+				data:   UtxoHistory{Block: start, Ecosystem: 1, Type: 2, SenderId: 10, RecipientId: 20},
+				amount: decimal.NewFromInt(1),
+			}), nil
+		},
+		load: func(balances utxoBalances, m utxoMovement) error {
+			if balances[m.data.Ecosystem] == nil {
+				balances[m.data.Ecosystem] = map[int64]decimal.Decimal{}
+			}
+			for _, key := range []int64{m.data.SenderId, m.data.RecipientId} {
+				if !balances.has(m.data.Ecosystem, key) {
+					balances[m.data.Ecosystem][key] = decimal.Zero
+				}
+			}
+			return nil
+		},
+		copy: func(ctx context.Context, rows []UtxoHistory) error {
+			mu.Lock()
+			defer mu.Unlock()
+			*copied = append(*copied, append([]UtxoHistory(nil), rows...))
+			return nil
+		},
+	}
+}
+
+func TestRunBackfill(t *testing.T) {
+	for _, c := range []struct {
+		batchRows int
+		batches   []int
+	}{
+		{4, []int{4, 2}}, //the last rows are flushed with the last chunk
+		{100, []int{6}},
+	} {
+		var (
+			copied  [][]UtxoHistory
+			reports []BackfillProgress
+		)
+		//chunks 1-10,11-20,21-30,31-40,41-45
+		opts := BackfillOptions{Workers: 3, ChunkBlocks: 10, BatchRows: c.batchRows}
+		p, err := runBackfill(context.Background(), BackfillProgress{FromBlock: 1, ToBlock: 45}, opts,
+			testBackfillSteps(&copied), func(p BackfillProgress) { reports = append(reports, p) })
+		if err != nil {
+			t.Fatal(err)
+		}
+		if p.Block != 45 || p.Rows != 6 || len(reports) != len(c.batches) {
+			t.Errorf("batch rows %d: progress %+v after %d reports", c.batchRows, p, len(reports))
+		}
+		var rows []UtxoHistory
+		for i, b := range copied {
+			if i >= len(c.batches) || len(b) != c.batches[i] {
+				t.Fatalf("batch rows %d: batch %d of %d rows, want %v", c.batchRows, i, len(b), c.batches)
+			}
+			rows = append(rows, b...)
+		}
+		//merged in block order with the balances carried from a chunk to the next one
+		wantBlocks := []int64{1, 1, 11, 21, 31, 41}
+		wantSender := []string{"0", "99", "98", "97", "96", "95"}
+		wantRecipient := []string{"100", "1", "2", "3", "4", "5"}
+		for i, r := range rows {
+			if r.Block != wantBlocks[i] || r.SenderBalance != wantSender[i] || r.RecipientBalance != wantRecipient[i] {
+				t.Errorf("batch rows %d: row %d block %d balances %s,%s, want %d %s,%s", c.batchRows, i,
+					r.Block, r.SenderBalance, r.RecipientBalance, wantBlocks[i], wantSender[i], wantRecipient[i])
+			}
+		}
+	}
+}
+
+func TestRunBackfillDryRun(t *testing.T) {
+	var copied [][]UtxoHistory
+	opts := BackfillOptions{Workers: 2, ChunkBlocks: 10, BatchRows: 100, DryRun: true}
+	p, err := runBackfill(context.Background(), BackfillProgress{FromBlock: 1, ToBlock: 45}, opts, testBackfillSteps(&copied), nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(copied) != 0 || p.Rows != 6 {
+		t.Errorf("dry run copied %d batches,%d rows", len(copied), p.Rows)
+	}
+}
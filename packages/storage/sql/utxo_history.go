@@ -11,9 +11,14 @@ import (
 	log "github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+	"sync"
 )
 
-var initStart = true
+var (
+	initStart = true
+	//utxoSyncMu is held by the sync and the backfill of utxo_history
+	utxoSyncMu sync.Mutex
+)
 
 type UtxoHistory struct {
 	Id               int64  `gorm:"primary_key;not null"`
@@ -61,16 +66,6 @@ func (p *UtxoHistory) TableName() string {
 	return "utxo_history"
 }
 
-func (p *UtxoHistory) CreateTable() (err error) {
-	err = nil
-	if !HasTableOrView(p.TableName()) {
-		if err = GetDB(nil).Migrator().CreateTable(p); err != nil {
-			return err
-		}
-	}
-	return err
-}
-
 func (p *UtxoHistory) GetLast() (bool, error) {
 	return isFound(GetDB(nil).Last(p))
 }
@@ -79,17 +74,18 @@ func (p *UtxoHistory) RollbackTransaction() error {
 	return GetDB(nil).Where("block >= ?", p.Block).Delete(&UtxoHistory{}).Error
 }
 
-func InitSpentInfoHistory() error {
-	var p UtxoHistory
-	err := p.CreateTable()
-	if err != nil {
-		return err
+// SpentInfoHistorySync syncs utxo_history to the last utxo transaction of tx_data,
+// it returns at once when a backfill,a reindex or another sync is running
+func SpentInfoHistorySync() error {
+	if !utxoSyncMu.TryLock() {
+		return nil
 	}
-
-	return nil
+	defer utxoSyncMu.Unlock()
+	_, err := withIndexerLock(false, spentInfoHistorySync)
+	return err
 }
 
-func SpentInfoHistorySync() error {
+func spentInfoHistorySync() error {
 	var insertData []UtxoHistory
 	var (
 		si     TransactionData
@@ -135,56 +131,9 @@ func SpentInfoHistorySync() error {
 		return nil
 	}
 
-	//					map[ecosystem]map[key_id]balance
-	keysBalance := make(map[int64]map[int64]decimal.Decimal)
-
-	addInsertData := func(data UtxoHistory, amount decimal.Decimal) error {
-
-		if data.Type != 5 {
-			if _, ok := keysBalance[data.Ecosystem][data.SenderId]; !ok {
-				return fmt.Errorf("[utxo sync] ecosystem:%d, senderId:%d balance doen't not exist", data.Ecosystem, data.SenderId)
-			}
-			if _, ok := keysBalance[data.Ecosystem][data.RecipientId]; !ok {
-				return fmt.Errorf("[utxo sync] ecosystem:%d, recipientId:%d balance doen't not exist", data.Ecosystem, data.RecipientId)
-			}
-
-			if data.Type == 1 {
-				if data.SubType == 2 {
-					keysBalance[data.Ecosystem][data.SenderId] = keysBalance[data.Ecosystem][data.SenderId].Sub(amount)
-					data.SenderBalance = keysBalance[data.Ecosystem][data.SenderId].String()
-					data.RecipientBalance = keysBalance[data.Ecosystem][data.RecipientId].String()
-
-					insertData = append(insertData, data)
-					return nil
-				}
-			} else {
-				keysBalance[data.Ecosystem][data.SenderId] = keysBalance[data.Ecosystem][data.SenderId].Sub(amount)
-			}
-		} else {
-			ba := make(map[int64]decimal.Decimal)
-
-			ba[data.Ecosystem] = decimal.Zero
-			if _, ok := keysBalance[data.Ecosystem]; !ok {
-				keysBalance[data.Ecosystem] = ba
-			}
-		}
-
-		keysBalance[data.Ecosystem][data.RecipientId] = keysBalance[data.Ecosystem][data.RecipientId].Add(amount)
-		data.SenderBalance = keysBalance[data.Ecosystem][data.SenderId].String()
-		data.RecipientBalance = keysBalance[data.Ecosystem][data.RecipientId].String()
-
-		insertData = append(insertData, data)
-		return nil
-	}
-
+	keysBalance := make(utxoBalances)
+	var s1 SpentInfo
 	for _, val := range *txList {
-		var (
-			data UtxoHistory
-			s1   SpentInfo
-		)
-		data.Hash = val.Hash
-		data.Block = val.BlockId
-
 		if bkDiff != val.BlockId { //block diff update keys balance
 			bkDiff = val.BlockId
 
@@ -193,6 +142,7 @@ func SpentInfoHistorySync() error {
 				if err != nil {
 					return err
 				}
+				alertUtxoHistory(insertData)
 				insertData = nil
 			}
 
@@ -201,199 +151,22 @@ func SpentInfoHistorySync() error {
 				return fmt.Errorf("[utxo sync]get block:%d output keys failed:%s", val.BlockId, err.Error())
 			}
 			for _, k := range keys {
-				var (
-					his UtxoHistory
-				)
-				ba := make(map[int64]decimal.Decimal)
-
-				balance, err := his.GetKeyBalance(k.OutputKeyId, k.Ecosystem)
-				if err != nil {
-					return fmt.Errorf("[utxo sync]get ecosystem:%d output keys:%d balance failed:%s", k.Ecosystem, k.OutputKeyId, err.Error())
-				}
-				//fmt.Printf("[blockid]%d,ecosystem:%d,key_id:%d,balance:%s\n", val.BlockId, k.Ecosystem, k.OutputKeyId, balance.String())
-
-				ba[k.OutputKeyId] = balance
-				if _, ok := keysBalance[k.Ecosystem]; !ok {
-					keysBalance[k.Ecosystem] = ba
-				} else {
-					keysBalance[k.Ecosystem][k.OutputKeyId] = balance
+				if err = keysBalance.load(k.Ecosystem, k.OutputKeyId); err != nil {
+					return err
 				}
 			}
-
 		}
 
-		info, err := val.UnmarshalBlockTransaction()
+		moves, err := decodeUtxoTx(val, s1.GetOutputs)
 		if err != nil {
-			return fmt.Errorf("[utxo sync]unmarshal utxo transaction failed:%s", err.Error())
+			return err
 		}
-		data.CreatedAt = info.TxTime
-
-		if info.UtxoType == UtxoTx {
-			var (
-				outputList []SpentInfo
-				si         SpentInfo
-			)
-			outputList, err = si.GetOutputs(val.Hash)
-			if err != nil {
-				return fmt.Errorf("[utxo sync]get out puts failed:%s", err.Error())
-			}
-			for _, v := range outputList {
-				amount, _ := decimal.NewFromString(v.OutputValue)
-				recipientId := v.OutputKeyId
-				if info.Ecosystem == 1 {
-					if v.Ecosystem == 1 {
-						switch v.Type {
-						case 20: //fees type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = 1
-							data.Type = formatSpentInfoHistoryType(FeesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						case 21: //taxes type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = 1
-							data.Type = formatSpentInfoHistoryType(TaxesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						case 26: //utxo tx
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = 1
-							data.Type = formatSpentInfoHistoryType(UtxoTx)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-						}
-					}
-				} else {
-					if v.Ecosystem == 1 {
-						switch v.Type {
-						case 20: //fees type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = 1
-							data.Type = formatSpentInfoHistoryType(FeesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						case 21: //taxes type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = 1
-							data.Type = formatSpentInfoHistoryType(TaxesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						}
-					} else {
-						switch v.Type {
-						case 23: //combustion type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = v.Ecosystem
-							data.Type = formatSpentInfoHistoryType(CombustionType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-						case 20: //fees type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = v.Ecosystem
-							data.Type = formatSpentInfoHistoryType(FeesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						case 21: //taxes type
-
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = v.Ecosystem
-							data.Type = formatSpentInfoHistoryType(TaxesType)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-
-						case 26: //utxo tx type
-							data.Amount = amount.String()
-							data.SenderId = info.SenderId
-							data.RecipientId = recipientId
-							data.Ecosystem = v.Ecosystem
-							data.Type = formatSpentInfoHistoryType(UtxoTx)
-
-							err = addInsertData(data, amount)
-							if err != nil {
-								return err
-							}
-						}
-					}
-				}
-			}
-		} else if info.UtxoType == StartUpType {
-			var lt LogTransaction
-			f, err := lt.GetByHash(val.Hash)
-			if err != nil {
-				return err
-			}
-			if !f {
-				return fmt.Errorf("[utxo sync]get log hash doesn't exist hash:%s", hex.EncodeToString(val.Hash))
-			}
-
-			amount := decimal.New(consts.FounderAmount, int32(consts.MoneyDigits))
-			data.Type = formatSpentInfoHistoryType(info.UtxoType)
-			data.SenderId = 5555
-			data.RecipientId = lt.Address
-			data.Amount = amount.String()
-			data.Ecosystem = lt.EcosystemID
-
-			err = addInsertData(data, amount)
-			if err != nil {
-				return err
-			}
-		} else {
-			data.Type = formatSpentInfoHistoryType(info.UtxoType)
-			data.SubType = formatSpentInfoHistorySubType(info.TransferType)
-			data.SenderId = info.SenderId
-			data.RecipientId = info.RecipientId
-			data.Amount = info.Amount.String()
-			data.Ecosystem = info.Ecosystem
-
-			err = addInsertData(data, info.Amount)
+		for _, m := range moves {
+			data, err := keysBalance.apply(m)
 			if err != nil {
 				return err
 			}
+			insertData = append(insertData, data)
 		}
 	}
 	if insertData != nil {
@@ -401,10 +174,151 @@ func SpentInfoHistorySync() error {
 		if err != nil {
 			return err
 		}
+		alertUtxoHistory(insertData)
 		insertData = nil
 	}
 
-	return SpentInfoHistorySync()
+	return spentInfoHistorySync()
+}
+
+// utxoMovement is a utxo_history row without its balances,and the amount it moves
+type utxoMovement struct {
+	data   UtxoHistory
+	amount decimal.Decimal
+}
+
+// utxoBalances are the running utxo balances,map[ecosystem]map[key_id]balance
+type utxoBalances map[int64]map[int64]decimal.Decimal
+
+// load reads the balance of the key from its latest utxo_history row
+func (kb utxoBalances) load(ecosystem, keyId int64) error {
+	var his UtxoHistory
+	balance, err := his.GetKeyBalance(keyId, ecosystem)
+	if err != nil {
+		return fmt.Errorf("[utxo sync]get ecosystem:%d output keys:%d balance failed:%s", ecosystem, keyId, err.Error())
+	}
+	if _, ok := kb[ecosystem]; !ok {
+		kb[ecosystem] = make(map[int64]decimal.Decimal)
+	}
+	kb[ecosystem][keyId] = balance
+	return nil
+}
+
+func (kb utxoBalances) has(ecosystem, keyId int64) bool {
+	_, ok := kb[ecosystem][keyId]
+	return ok
+}
+
+// apply moves the amount of m and returns its row with the balances after the move
+func (kb utxoBalances) apply(m utxoMovement) (UtxoHistory, error) {
+	data, amount := m.data, m.amount
+	if data.Type != 5 {
+		if !kb.has(data.Ecosystem, data.SenderId) {
+			return data, fmt.Errorf("[utxo sync] ecosystem:%d, senderId:%d balance doen't not exist", data.Ecosystem, data.SenderId)
+		}
+		if !kb.has(data.Ecosystem, data.RecipientId) {
+			return data, fmt.Errorf("[utxo sync] ecosystem:%d, recipientId:%d balance doen't not exist", data.Ecosystem, data.RecipientId)
+		}
+
+		if data.Type == 1 {
+			if data.SubType == 2 {
+				kb[data.Ecosystem][data.SenderId] = kb[data.Ecosystem][data.SenderId].Sub(amount)
+				data.SenderBalance = kb[data.Ecosystem][data.SenderId].String()
+				data.RecipientBalance = kb[data.Ecosystem][data.RecipientId].String()
+				return data, nil
+			}
+		} else {
+			kb[data.Ecosystem][data.SenderId] = kb[data.Ecosystem][data.SenderId].Sub(amount)
+		}
+	} else if _, ok := kb[data.Ecosystem]; !ok {
+		kb[data.Ecosystem] = make(map[int64]decimal.Decimal)
+	}
+
+	kb[data.Ecosystem][data.RecipientId] = kb[data.Ecosystem][data.RecipientId].Add(amount)
+	data.SenderBalance = kb[data.Ecosystem][data.SenderId].String()
+	data.RecipientBalance = kb[data.Ecosystem][data.RecipientId].String()
+	return data, nil
+}
+
+// utxoOutputType returns the utxo_history type of a spent_info output of a transaction of txEcosystem,
+// the outputs of other types are not recorded
+func utxoOutputType(txEcosystem int64, v SpentInfo) string {
+	if txEcosystem == 1 && v.Ecosystem != 1 {
+		return ""
+	}
+	switch v.Type {
+	case 20:
+		return FeesType
+	case 21:
+		return TaxesType
+	case 23:
+		if v.Ecosystem != 1 {
+			return CombustionType
+		}
+	case 26:
+		if txEcosystem == 1 || v.Ecosystem != 1 {
+			return UtxoTx
+		}
+	}
+	return ""
+}
+
+// decodeUtxoTx returns the movements of a utxo transaction in the order they are applied,
+// outputs returns the spent_info outputs of the UTXO_Tx transactions
+func decodeUtxoTx(val spentInfoTxData, outputs func(txHash []byte) ([]SpentInfo, error)) ([]utxoMovement, error) {
+	var moves []utxoMovement
+	info, err := val.UnmarshalBlockTransaction()
+	if err != nil {
+		return nil, fmt.Errorf("[utxo sync]unmarshal utxo transaction failed:%s", err.Error())
+	}
+	data := UtxoHistory{Hash: val.Hash, Block: val.BlockId, CreatedAt: info.TxTime}
+
+	switch info.UtxoType {
+	case UtxoTx:
+		outputList, err := outputs(val.Hash)
+		if err != nil {
+			return nil, fmt.Errorf("[utxo sync]get out puts failed:%s", err.Error())
+		}
+		for _, v := range outputList {
+			utxoType := utxoOutputType(info.Ecosystem, v)
+			if utxoType == "" {
+				continue
+			}
+			amount, _ := decimal.NewFromString(v.OutputValue)
+			data.Amount = amount.String()
+			data.SenderId = info.SenderId
+			data.RecipientId = v.OutputKeyId
+			data.Ecosystem = v.Ecosystem
+			data.Type = formatSpentInfoHistoryType(utxoType)
+			moves = append(moves, utxoMovement{data: data, amount: amount})
+		}
+	case StartUpType:
+		var lt LogTransaction
+		f, err := lt.GetByHash(val.Hash)
+		if err != nil {
+			return nil, err
+		}
+		if !f {
+			return nil, fmt.Errorf("[utxo sync]get log hash doesn't exist hash:%s", hex.EncodeToString(val.Hash))
+		}
+
+		amount := decimal.New(consts.FounderAmount, int32(consts.MoneyDigits))
+		data.Type = formatSpentInfoHistoryType(info.UtxoType)
+		data.SenderId = 5555
+		data.RecipientId = lt.Address
+		data.Amount = amount.String()
+		data.Ecosystem = lt.EcosystemID
+		moves = append(moves, utxoMovement{data: data, amount: amount})
+	default:
+		data.Type = formatSpentInfoHistoryType(info.UtxoType)
+		data.SubType = formatSpentInfoHistorySubType(info.TransferType)
+		data.SenderId = info.SenderId
+		data.RecipientId = info.RecipientId
+		data.Amount = info.Amount.String()
+		data.Ecosystem = info.Ecosystem
+		moves = append(moves, utxoMovement{data: data, amount: info.Amount})
+	}
+	return moves, nil
 }
 
 func createUtxoTxBatches(dbTx *gorm.DB, data *[]UtxoHistory) error {
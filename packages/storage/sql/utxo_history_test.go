@@ -0,0 +1,75 @@
+package sql
+
+import (
+	"github.com/shopspring/decimal"
+	"testing"
+)
+
+func TestUtxoOutputType(t *testing.T) {
+	cases := []struct {
+		txEcosystem int64
+		ecosystem   int64
+		typ         int64
+		want        string
+	}{
+		{1, 1, 20, FeesType},
+		{1, 1, 21, TaxesType},
+		{1, 1, 26, UtxoTx},
+		{1, 1, 23, ""},
+		{1, 2, 20, ""},
+		{2, 1, 20, FeesType},
+		{2, 1, 21, TaxesType},
+		{2, 1, 23, ""},
+		{2, 1, 26, ""},
+		{2, 2, 20, FeesType},
+		{2, 2, 21, TaxesType},
+		{2, 2, 23, CombustionType},
+		{2, 2, 26, UtxoTx},
+		{2, 2, 24, ""},
+	}
+	for _, c := range cases {
+		got := utxoOutputType(c.txEcosystem, SpentInfo{Ecosystem: c.ecosystem, Type: c.typ})
+		if got != c.want {
+			t.Errorf("tx ecosystem %d output ecosystem %d type %d: got %q want %q", c.txEcosystem, c.ecosystem, c.typ, got, c.want)
+		}
+	}
+}
+
+func TestUtxoBalancesApply(t *testing.T) {
+	kb := utxoBalances{}
+	move := func(typ, subType int, sender, recipient int64, amount int64) utxoMovement {
+		return utxoMovement{
+			data:   UtxoHistory{Ecosystem: 1, Type: typ, SubType: subType, SenderId: sender, RecipientId: recipient},
+			amount: decimal.NewFromInt(amount),
+		}
+	}
+	steps := []struct {
+		m         utxoMovement
+		sender    string
+		recipient string
+	}{
+		{move(5, 0, 5555, 10, 100), "0", "100"}, //start up
+		{move(1, 1, 10, 10, 20), "120", "120"},  //account to utxo
+		{move(2, 0, 10, 20, 30), "90", "30"},    //utxo tx
+		{move(3, 0, 10, 30, 1), "89", "1"},      //fees
+		{move(1, 2, 20, 20, 10), "20", "20"},    //utxo to account
+	}
+	kb[1] = map[int64]decimal.Decimal{}
+	for i, s := range steps {
+		for _, key := range []int64{s.m.data.SenderId, s.m.data.RecipientId} {
+			if s.m.data.Type != 5 && !kb.has(1, key) {
+				kb[1][key] = decimal.Zero
+			}
+		}
+		data, err := kb.apply(s.m)
+		if err != nil {
+			t.Fatalf("step %d: %v", i, err)
+		}
+		if data.SenderBalance != s.sender || data.RecipientBalance != s.recipient {
+			t.Errorf("step %d: balances %s,%s want %s,%s", i, data.SenderBalance, data.RecipientBalance, s.sender, s.recipient)
+		}
+	}
+	if _, err := kb.apply(move(2, 0, 40, 10, 1)); err == nil {
+		t.Error("unknown sender applied")
+	}
+}
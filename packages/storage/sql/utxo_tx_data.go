@@ -27,22 +27,7 @@ func (p *TransactionData) TableName() string {
 	return "tx_data"
 }
 
-func (p *TransactionData) CreateTable() (err error) {
-	err = nil
-	if !HasTableOrView(p.TableName()) {
-		if err = GetDB(nil).Migrator().CreateTable(p); err != nil {
-			return err
-		}
-	}
-	return err
-}
-
 func InitTransactionData() error {
-	var p TransactionData
-	err := p.CreateTable()
-	if err != nil {
-		return err
-	}
 	go TxDataSyncSignalReceive()
 
 	return nil
@@ -82,7 +67,8 @@ func TxDataSyncSignalReceive() {
 	for {
 		select {
 		case <-getTransactionData:
-			if err := transactionDataSync(); err != nil {
+			//skipped while a reindex rebuilds tx_data
+			if _, err := withIndexerLock(false, transactionDataSync); err != nil {
 				log.WithFields(log.Fields{"error": err}).Error("Transaction Data Sync Failed")
 			}
 		}
@@ -155,6 +141,9 @@ begin:
 	if err != nil {
 		return err
 	}
+	if len(*bkList) > 0 {
+		alertAccountHistory(tr.Block, (*bkList)[len(*bkList)-1].ID)
+	}
 
 	return transactionDataSync()
 }
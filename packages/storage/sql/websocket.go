@@ -4,7 +4,9 @@ import (
 	"context"
 	"encoding/json"
 	"errors"
+	"go.opentelemetry.io/otel/attribute"
 	"jutkey-server/conf"
+	"jutkey-server/packages/tracing"
 	"time"
 )
 
@@ -53,7 +55,15 @@ func sendChannelDashboardData(channel string, data WebsocketDataTitle) error {
 }
 
 func writeChannelByte(channel string, data []byte) error {
+	ce := conf.GetCentrifugoConn()
+	if ce == nil || !ce.Enable {
+		return nil
+	}
 	ctx, cancel := context.WithTimeout(context.Background(), centrifugoTimeout)
 	defer cancel()
-	return conf.GetCentrifugoConn().Conn().Publish(ctx, channel, data)
+	ctx, span := tracing.Start(ctx, "centrifugo.publish", attribute.String("messaging.destination", channel),
+		attribute.Int("messaging.message_payload_size_bytes", len(data)))
+	err := ce.Conn().Publish(ctx, channel, data)
+	tracing.End(span, err)
+	return err
 }
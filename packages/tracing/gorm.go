@@ -0,0 +1,111 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"gorm.io/gorm"
+	"regexp"
+	"strings"
+)
+
+const (
+	gormSpanKey   = "tracing:span"
+	maxSqlLength  = 2048
+	gormDbSystem  = "postgresql"
+	gormSpanName  = "gorm."
+	gormCallbacks = "tracing"
+)
+
+var (
+	sqlString = regexp.MustCompile(`'(?:[^']|'')*'`)
+	sqlNumber = regexp.MustCompile(`([^\w$."])-?\d+(?:\.\d+)?\b`)
+	sqlSpace  = regexp.MustCompile(`\s+`)
+)
+
+type gormSpan struct {
+	span   trace.Span
+	parent context.Context
+}
+
+// GormPlugin creates a client span for every query run under a span,the statement is recorded without its values.
+// The queries without a parent,like the sync ones,aren't traced so they don't become root traces
+type GormPlugin struct{}
+
+func (p *GormPlugin) Name() string {
+	return gormCallbacks
+}
+
+func (p *GormPlugin) Initialize(db *gorm.DB) error {
+	cb := db.Callback()
+	var err error
+	register := func(op string, before, after func(name string, fn func(*gorm.DB)) error) {
+		if err != nil {
+			return
+		}
+		if err = before(gormCallbacks+":before_"+op, p.before(op)); err != nil {
+			return
+		}
+		err = after(gormCallbacks+":after_"+op, p.after)
+	}
+	register("create", cb.Create().Before("gorm:create").Register, cb.Create().After("gorm:create").Register)
+	register("query", cb.Query().Before("gorm:query").Register, cb.Query().After("gorm:query").Register)
+	register("update", cb.Update().Before("gorm:update").Register, cb.Update().After("gorm:update").Register)
+	register("delete", cb.Delete().Before("gorm:delete").Register, cb.Delete().After("gorm:delete").Register)
+	register("row", cb.Row().Before("gorm:row").Register, cb.Row().After("gorm:row").Register)
+	register("raw", cb.Raw().Before("gorm:raw").Register, cb.Raw().After("gorm:raw").Register)
+	return err
+}
+
+func (p *GormPlugin) before(op string) func(*gorm.DB) {
+	return func(db *gorm.DB) {
+		parent := db.Statement.Context
+		if !hasParent(parent) {
+			return
+		}
+		ctx, span := tracer.Start(parent, gormSpanName+op, trace.WithSpanKind(trace.SpanKindClient))
+		db.InstanceSet(gormSpanKey, gormSpan{span: span, parent: parent})
+		db.Statement.Context = ctx
+	}
+}
+
+func (p *GormPlugin) after(db *gorm.DB) {
+	v, ok := db.InstanceGet(gormSpanKey)
+	if !ok {
+		return
+	}
+	gs := v.(gormSpan)
+	span := gs.span
+	db.Statement.Context = gs.parent
+	attrs := []attribute.KeyValue{
+		semconv.DBSystemKey.String(gormDbSystem),
+		semconv.DBStatementKey.String(SanitizeSql(db.Statement.SQL.String())),
+		attribute.Int64("db.rows_affected", db.RowsAffected),
+	}
+	if db.Statement.Table != "" {
+		attrs = append(attrs, semconv.DBSQLTableKey.String(db.Statement.Table))
+	}
+	span.SetAttributes(attrs...)
+	err := db.Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		err = nil
+	}
+	End(span, err)
+}
+
+func hasParent(ctx context.Context) bool {
+	return ctx != nil && trace.SpanContextFromContext(ctx).IsValid()
+}
+
+// SanitizeSql replaces the string and number literals with ?,the bound values are never part of the statement
+func SanitizeSql(sql string) string {
+	sql = sqlString.ReplaceAllString(sql, "?")
+	sql = sqlNumber.ReplaceAllString(sql, "${1}?")
+	sql = strings.TrimSpace(sqlSpace.ReplaceAllString(sql, " "))
+	if len(sql) > maxSqlLength {
+		sql = sql[:maxSqlLength] + "..."
+	}
+	return sql
+}
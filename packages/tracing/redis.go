@@ -0,0 +1,56 @@
+package tracing
+
+import (
+	"context"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/attribute"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+)
+
+// RedisHook creates a client span for every command run under a span,the keys and values are not recorded
+type RedisHook struct{}
+
+var _ redis.Hook = RedisHook{}
+
+func (RedisHook) BeforeProcess(ctx context.Context, cmd redis.Cmder) (context.Context, error) {
+	if !hasParent(ctx) {
+		return ctx, nil
+	}
+	ctx, _ = tracer.Start(ctx, "redis."+cmd.Name(), trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, semconv.DBOperationKey.String(cmd.Name())))
+	return ctx, nil
+}
+
+func (RedisHook) AfterProcess(ctx context.Context, cmd redis.Cmder) error {
+	endRedisSpan(ctx, cmd.Err())
+	return nil
+}
+
+func (RedisHook) BeforeProcessPipeline(ctx context.Context, cmds []redis.Cmder) (context.Context, error) {
+	if !hasParent(ctx) {
+		return ctx, nil
+	}
+	ctx, _ = tracer.Start(ctx, "redis.pipeline", trace.WithSpanKind(trace.SpanKindClient),
+		trace.WithAttributes(semconv.DBSystemRedis, attribute.Int("db.redis.num_cmd", len(cmds))))
+	return ctx, nil
+}
+
+func (RedisHook) AfterProcessPipeline(ctx context.Context, cmds []redis.Cmder) error {
+	var err error
+	for _, cmd := range cmds {
+		if cmd.Err() != nil && cmd.Err() != redis.Nil {
+			err = cmd.Err()
+			break
+		}
+	}
+	endRedisSpan(ctx, err)
+	return nil
+}
+
+func endRedisSpan(ctx context.Context, err error) {
+	if err == redis.Nil {
+		err = nil
+	}
+	End(trace.SpanFromContext(ctx), err)
+}
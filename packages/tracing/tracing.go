@@ -0,0 +1,143 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.12.0"
+	"go.opentelemetry.io/otel/trace"
+	"io"
+	"jutkey-server/conf"
+	"jutkey-server/packages/consts"
+	"os"
+	"path/filepath"
+)
+
+const (
+	instrumentationName = "jutkey-server"
+	defaultServiceName  = "jutkey-server"
+)
+
+var (
+	tracer   = otel.Tracer(instrumentationName)
+	provider *sdktrace.TracerProvider
+)
+
+// Enabled reports whether the spans are exported
+func Enabled() bool {
+	return provider != nil
+}
+
+func ServiceName() string {
+	if t := conf.GetTracingConf(); t != nil && t.ServiceName != "" {
+		return t.ServiceName
+	}
+	return defaultServiceName
+}
+
+// InitTracing sets the global tracer provider and instruments the database and redis connections,
+// nothing is done when tracing is disabled
+func InitTracing() error {
+	cfg := conf.GetTracingConf()
+	if cfg == nil || !cfg.Enable {
+		return nil
+	}
+	exporter, err := newExporter(cfg.Exporter, cfg.Protocol, cfg.Endpoint, cfg.File, cfg.Insecure)
+	if err != nil {
+		return err
+	}
+	res, err := resource.Merge(resource.Default(), resource.NewWithAttributes(semconv.SchemaURL,
+		semconv.ServiceNameKey.String(ServiceName()),
+		semconv.ServiceVersionKey.String(consts.Version()),
+	))
+	if err != nil {
+		return err
+	}
+	sampler := sdktrace.AlwaysSample()
+	if cfg.SampleRatio > 0 && cfg.SampleRatio < 1 {
+		sampler = sdktrace.TraceIDRatioBased(cfg.SampleRatio)
+	}
+	provider = sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sampler)),
+	)
+	otel.SetTracerProvider(provider)
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(propagation.TraceContext{}, propagation.Baggage{}))
+
+	if db := conf.GetDbConn().Conn(); db != nil {
+		if err = db.Use(&GormPlugin{}); err != nil {
+			return err
+		}
+	}
+	if r := conf.GetRedisDbConn(); r != nil && r.Conn() != nil {
+		r.Conn().AddHook(RedisHook{})
+	}
+	return nil
+}
+
+func newExporter(name, protocol, endpoint, file string, insecure bool) (sdktrace.SpanExporter, error) {
+	ctx := context.Background()
+	switch name {
+	case "otlp":
+		if protocol == "http" {
+			opts := []otlptracehttp.Option{otlptracehttp.WithEndpoint(endpoint)}
+			if insecure {
+				opts = append(opts, otlptracehttp.WithInsecure())
+			}
+			return otlptrace.New(ctx, otlptracehttp.NewClient(opts...))
+		}
+		opts := []otlptracegrpc.Option{otlptracegrpc.WithEndpoint(endpoint)}
+		if insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		return otlptrace.New(ctx, otlptracegrpc.NewClient(opts...))
+	case "stdout":
+		return stdouttrace.New(stdouttrace.WithWriter(os.Stdout))
+	case "file":
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(conf.GetEnvConf().ConfigPath, file)
+		}
+		f, err := os.OpenFile(file, os.O_WRONLY|os.O_APPEND|os.O_CREATE, 0644)
+		if err != nil {
+			return nil, err
+		}
+		return stdouttrace.New(stdouttrace.WithWriter(f))
+	}
+	return nil, fmt.Errorf("tracing exporter not supported:%s", name)
+}
+
+// Shutdown flushes the pending spans
+func Shutdown(ctx context.Context) error {
+	if provider == nil {
+		return nil
+	}
+	return provider.Shutdown(ctx)
+}
+
+// Start starts a span,it's a no-op span when tracing is disabled
+func Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if ctx == nil {
+		ctx = context.Background()
+	}
+	return tracer.Start(ctx, name, trace.WithAttributes(attrs...))
+}
+
+// End records err on the span and ends it
+func End(span trace.Span, err error) {
+	if err != nil && !errors.Is(err, io.EOF) {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
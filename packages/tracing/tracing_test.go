@@ -0,0 +1,75 @@
+package tracing
+
+import (
+	"context"
+	"errors"
+	"github.com/go-redis/redis/v8"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"testing"
+)
+
+func TestSanitizeSql(t *testing.T) {
+	cases := map[string]string{
+		`SELECT * FROM "1_history" WHERE ecosystem = 1 AND comment = 'it''s me'`: `SELECT * FROM "1_history" WHERE ecosystem = ? AND comment = ?`,
+		"SELECT v1.block\n\tFROM utxo_history WHERE id = $1 LIMIT 10":            `SELECT v1.block FROM utxo_history WHERE id = $1 LIMIT ?`,
+		`SELECT amount FROM t WHERE amount > -2.5`:                               `SELECT amount FROM t WHERE amount > ?`,
+	}
+	for sql, want := range cases {
+		if got := SanitizeSql(sql); got != want {
+			t.Errorf("SanitizeSql(%q) = %q,want %q", sql, got, want)
+		}
+	}
+}
+
+func TestStartEnd(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer tp.Shutdown(context.Background())
+	old := tracer
+	tracer = tp.Tracer(instrumentationName)
+	defer func() { tracer = old }()
+
+	ctx, parent := Start(context.Background(), "parent")
+	_, child := Start(ctx, "child")
+	End(child, errors.New("query canceled"))
+	End(parent, nil)
+
+	spans := recorder.Ended()
+	if len(spans) != 2 {
+		t.Fatalf("ended spans %d", len(spans))
+	}
+	if spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Error("child span is not under the parent")
+	}
+	if spans[0].Status().Code != codes.Error || spans[1].Status().Code != codes.Unset {
+		t.Errorf("status %v %v", spans[0].Status(), spans[1].Status())
+	}
+}
+
+func TestRedisHookParent(t *testing.T) {
+	recorder := tracetest.NewSpanRecorder()
+	tp := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+	defer tp.Shutdown(context.Background())
+	old := tracer
+	tracer = tp.Tracer(instrumentationName)
+	defer func() { tracer = old }()
+
+	var hook RedisHook
+	cmd := redis.NewStringCmd(context.Background(), "get", "k")
+	ctx, _ := hook.BeforeProcess(context.Background(), cmd)
+	hook.AfterProcess(ctx, cmd)
+	if n := len(recorder.Ended()); n != 0 {
+		t.Fatalf("command without a parent traced,%d spans", n)
+	}
+
+	parent, span := Start(context.Background(), "crontab.task")
+	ctx, _ = hook.BeforeProcess(parent, cmd)
+	hook.AfterProcess(ctx, cmd)
+	End(span, nil)
+	spans := recorder.Ended()
+	if len(spans) != 2 || spans[0].Name() != "redis.get" || spans[0].Parent().SpanID() != spans[1].SpanContext().SpanID() {
+		t.Errorf("spans %v", spans)
+	}
+}